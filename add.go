@@ -5,10 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
-	"slices"
-	"sort"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/rivo/tview"
@@ -21,6 +18,8 @@ type AddTransactionRequest struct {
 	Description string
 	Month       string
 	Year        string
+	Date        string // YYYY-MM-DD, takes precedence over month and year when set
+	Tags        string // comma separated, e.g. "vacation-2025, reimbursable"
 }
 
 // creates a TUI form with required fiields to add a new transaction
@@ -108,81 +107,31 @@ func formAddTransaction(currentTableType, selectedMonth, selectedYear string) er
 		descriptionField.SetLabel(fmt.Sprintf("Description (%d/%d)", len(text), DescriptionMaxCharLength))
 	})
 
-	var monthAndYear string
-	periodDropdown := styleDropdown(tview.NewDropDown().
-		SetLabel("Month/Year"))
-	{
-		opts, err := getMonthsWithTransactions()
-		if err != nil {
-			showErrorModal(fmt.Sprintf("unable to get months with transactions: err:\n\n%s", err), form)
-			log.Printf("unable to get months with transactions: err:\n\n%s", err)
-			return err
-		}
-
-		// make sure all 12 months of the current year are in the list
-		now := time.Now()
-		currentYear := strconv.Itoa(now.Year())
-		for m := 1; m <= 12; m++ {
-			monthStr := fmt.Sprintf("%s %s", strings.ToLower(time.Month(m).String()), currentYear)
-			if !slices.Contains(opts, monthStr) {
-				opts = append(opts, monthStr)
-			}
-		}
+	// the date decides which month the transaction is added to, prefill it with a date in the month we came from
+	dateField := styleInputField(tview.NewInputField().
+		SetLabel("Date (YYYY-MM-DD)").
+		SetText(defaultTransactionDate(selectedMonth, selectedYear).Format(TransactionDateFormat)))
 
-		// sort by year (ascending), then month (ascending)
-		sort.Slice(opts, func(i, j int) bool {
-			partsI := strings.SplitN(opts[i], " ", 2)
-			partsJ := strings.SplitN(opts[j], " ", 2)
-			if len(partsI) != 2 || len(partsJ) != 2 {
-				return opts[i] < opts[j]
-			}
-			yearI, _ := strconv.Atoi(partsI[1])
-			yearJ, _ := strconv.Atoi(partsJ[1])
-			if yearI != yearJ {
-				return yearI < yearJ
-			}
-			return monthOrder[strings.ToLower(partsI[0])] < monthOrder[strings.ToLower(partsJ[0])]
-		})
-
-		periodDropdown.SetOptions(opts, func(selectedOption string, index int) {
-			monthAndYear = selectedOption
-		})
-
-		//pre-select the month that we came from when we initiated formAddTransaction()
-		prefillIndex := 0
-		for i, period := range opts {
-			if period == fmt.Sprintf("%s %s", selectedMonth, selectedYear) {
-				prefillIndex = i
-				monthAndYear = period
-				break
-			}
-		}
-
-		periodDropdown.SetCurrentOption(prefillIndex)
-
-		// j/k navigation inside dropdown
-		periodDropdown.SetInputCapture(vimMotions)
-	}
+	tagsField := newTagsInputField(nil)
 
 	form = styleForm(tview.NewForm().
 		AddFormItem(typeDropdown).
 		AddFormItem(amountField).
 		AddFormItem(categoryDropdown).
 		AddFormItem(descriptionField).
-		AddFormItem(periodDropdown).
+		AddFormItem(dateField).
+		AddFormItem(tagsField).
 		AddButton("Add", func() {
 			amount := amountField.GetText()
 			description := descriptionField.GetText()
 
-			// parse the selected month and year
-			parts := strings.SplitN(monthAndYear, " ", 2)
-			if len(parts) != 2 {
-				showErrorModal(fmt.Sprintf("invalid period format: %s", monthAndYear), form)
-				log.Printf("invalid period format: %s", monthAndYear)
+			date, err := parseTransactionDate(dateField.GetText())
+			if err != nil {
+				showErrorModal(err.Error(), form)
+				log.Printf("invalid transaction date: %s", err)
 				return
 			}
-			month := parts[0]
-			year := parts[1]
+			month, year := periodOfDate(date)
 
 			var addReq = AddTransactionRequest{
 				Type:        transactionType,
@@ -191,25 +140,38 @@ func formAddTransaction(currentTableType, selectedMonth, selectedYear string) er
 				Description: description,
 				Month:       month,
 				Year:        year,
+				Date:        date.Format(TransactionDateFormat),
+				Tags:        tagsField.GetText(),
 			}
 
-			if err := handleAddTransaction(addReq); err != nil {
-				showErrorModal(fmt.Sprintf("failed to add transaction:\n\n%s", err), form)
-				log.Printf("failed to add transaction:\n\n%s", err)
-				return
+			save := func() {
+				if err := handleAddTransaction(addReq); err != nil {
+					showErrorModal(fmt.Sprintf("failed to add transaction:\n\n%s", err), form)
+					log.Printf("failed to add transaction:\n\n%s", err)
+					return
+				}
+
+				_, err = gridVisualizeTransactions(month, year, transactionType, true) // go back to list of transactions for the same month and table type
+				if err != nil {
+					showErrorModal("failed to return back to transactions list from add form", form)
+					log.Printf("failed to return back to transactions list from add form")
+				}
 			}
 
-			_, err := gridVisualizeTransactions(month, year, transactionType, true) // go back to list of transactions for the same month and table type
-			if err != nil {
-				showErrorModal("failed to return back to transactions list from add form", form)
-				log.Printf("failed to return back to transactions list from add form")
+			// an expense that goes over a budget is only added once confirmed, an invalid amount is left to the add handler to report
+			if warning := expenseBudgetWarning(addReq); warning != "" {
+				confirmOverBudget(warning, form, save)
+				return
 			}
+			save()
 		}).
 		AddButton("Clear", func() {
 			typeDropdown.SetCurrentOption(0)
 			amountField.SetText("")
 			categoryDropdown.SetCurrentOption(0)
 			descriptionField.SetText("")
+			dateField.SetText(defaultTransactionDate(selectedMonth, selectedYear).Format(TransactionDateFormat))
+			tagsField.SetText("")
 			transactionType = "expense"
 		}).
 		AddButton("Cancel", func() {
@@ -234,7 +196,7 @@ func formAddTransaction(currentTableType, selectedMonth, selectedYear string) er
 	centeredModal := styleFlex(tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(nil, 0, 1, false).
-		AddItem(modal, 19, 1, true). // enough to fit all the fields of the form on the screen
+		AddItem(modal, 21, 1, true). // enough to fit all the fields of the form on the screen
 		AddItem(nil, 0, 1, false))
 
 	pages.AddPage("add-transaction", centeredModal, true, true)
@@ -244,70 +206,81 @@ func formAddTransaction(currentTableType, selectedMonth, selectedYear string) er
 
 // handles adding a new transaction to storage
 func handleAddTransaction(req AddTransactionRequest) error {
-	txType, err := normalizeTransactionType(req.Type)
+	_, err := addTransaction(req)
+	return err
+}
+
+// adds a new transaction to storage and returns the id that was assigned to it
+func addTransaction(req AddTransactionRequest) (string, error) {
+	txType, newTransaction, err := newTransactionFromRequest(req)
 	if err != nil {
-		return fmt.Errorf("transaction type error: %w", err)
+		return "", err
 	}
 
-	txAmount, err := strconv.ParseFloat(req.Amount, 64)
-	if err != nil {
-		return fmt.Errorf("\ninvalid amount: %w\n", err)
+	if saveTransactionErr := InsertTransaction(txType, newTransaction); saveTransactionErr != nil {
+		return "", fmt.Errorf("Error saving transaction: %w", saveTransactionErr)
 	}
 
-	updatedCategory := req.Category
-	if _, ok := allowedTransactionCategories[txType][updatedCategory]; !ok {
-		return fmt.Errorf("invalid transaction category: %s", updatedCategory)
+	return newTransaction.Id, nil
+}
+
+// checks a request to add a transaction and turns it into a transaction with a new unique id, without storing it yet
+func newTransactionFromRequest(req AddTransactionRequest) (string, Transaction, error) {
+	txType, err := normalizeTransactionType(req.Type)
+	if err != nil {
+		return "", Transaction{}, fmt.Errorf("transaction type error: %w", err)
 	}
 
-	transactions, loadFileErr := LoadTransactions()
-	if loadFileErr != nil {
-		return fmt.Errorf("unable to load transactions file: %w", loadFileErr)
+	txAmount, err := strconv.ParseFloat(req.Amount, 64)
+	if err != nil {
+		return "", Transaction{}, fmt.Errorf("\ninvalid amount: %w\n", err)
 	}
 
-	var transactionId string
-	if transactionId, err = generateTransactionId(); err != nil {
-		return fmt.Errorf("unable to generate transaction id: %w", err)
+	// the date decides the month and year, without one the transaction is dated within the requested month
+	var txDate time.Time
+	if req.Date != "" {
+		if txDate, err = parseTransactionDate(req.Date); err != nil {
+			return "", Transaction{}, err
+		}
+		req.Month, req.Year = periodOfDate(txDate)
+	} else {
+		if _, err := firstOfMonth(req.Month, req.Year); err != nil {
+			return "", Transaction{}, fmt.Errorf("invalid transaction period: %w", err)
+		}
+		txDate = defaultTransactionDate(req.Month, req.Year)
 	}
 
-	// make sure nested structure exists
-	if _, ok := transactions[req.Year]; !ok {
-		transactions[req.Year] = make(map[string]map[string][]Transaction)
+	if !isAllowedCategory(txType, req.Category) {
+		return "", Transaction{}, fmt.Errorf("invalid transaction category: %s", req.Category)
 	}
 
-	if _, ok := transactions[req.Year][req.Month]; !ok {
-		transactions[req.Year][req.Month] = make(map[string][]Transaction)
+	txTags, err := parseTags(req.Tags)
+	if err != nil {
+		return "", Transaction{}, fmt.Errorf("invalid transaction tags: %w", err)
 	}
 
-	if _, ok := transactions[req.Year][req.Month][txType]; !ok {
-		transactions[req.Year][req.Month][txType] = []Transaction{}
+	var transactionId string
+	if transactionId, err = generateTransactionId(); err != nil {
+		return "", Transaction{}, fmt.Errorf("unable to generate transaction id: %w", err)
 	}
 
 	// make sure only unique IDs are used
 	for {
-		var duplicateIdFound bool
-		for txType := range transactions[req.Year][req.Month] {
-			for _, t := range transactions[req.Year][req.Month][txType] {
-				if transactionId == t.Id {
-					duplicateIdFound = true
-					break
-				}
-			}
-			if duplicateIdFound {
-				break // id is already in use
-			}
+		idInUse, err := TransactionIdExists(transactionId)
+		if err != nil {
+			return "", Transaction{}, err
 		}
-
-		if !duplicateIdFound {
+		if !idInUse {
 			break // id is unique
 		}
 
 		if transactionId, err = generateTransactionId(); err != nil {
-			return fmt.Errorf("unable to generate transaction id: %w", err)
+			return "", Transaction{}, fmt.Errorf("unable to generate transaction id: %w", err)
 		}
 	}
 
 	if len(transactionId) > TransactionIDLength {
-		return fmt.Errorf("transcation id should have a maximum of %v chars, current id %s with length of %v", TransactionIDLength, transactionId, len(transactionId))
+		return "", Transaction{}, fmt.Errorf("transcation id should have a maximum of %v chars, current id %s with length of %v", TransactionIDLength, transactionId, len(transactionId))
 	}
 
 	newTransaction := Transaction{
@@ -315,14 +288,11 @@ func handleAddTransaction(req AddTransactionRequest) error {
 		Amount:      txAmount,
 		Category:    req.Category,
 		Description: req.Description,
+		Date:        txDate,
+		Tags:        txTags,
 	}
 
-	transactions[req.Year][req.Month][txType] = append(transactions[req.Year][req.Month][txType], newTransaction)
-	if saveTransactionErr := SaveTransactions(transactions); saveTransactionErr != nil {
-		return fmt.Errorf("Error saving transaction: %w", saveTransactionErr)
-	}
-
-	return nil
+	return txType, newTransaction, nil
 }
 
 // creates a randomly generated transaction id that will be assined on each new transaction
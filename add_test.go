@@ -148,3 +148,41 @@ func TestHandleAddTransaction(t *testing.T) {
 		})
 	}
 }
+
+func TestAddTransactionWithDate(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	// the date decides the month and year, even if the request says otherwise
+	id, err := addTransaction(AddTransactionRequest{
+		Type: "expense", Amount: "12.50", Category: "food", Description: "lunch",
+		Month: "january", Year: "2024", Date: "2024-03-15",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error adding dated transaction, got %v", err)
+	}
+
+	transactions, err := loadTransactionsFromTestStorage()
+	if err != nil {
+		t.Fatalf("Failed to load transactions: %v", err)
+	}
+	list := transactions["2024"]["march"]["expense"]
+	if len(list) != 1 || list[0].Id != id || list[0].Date.Format(TransactionDateFormat) != "2024-03-15" {
+		t.Errorf("Expected transaction on 2024-03-15 in march, got %+v", transactions)
+	}
+
+	// without a date a past month gets its first day
+	if _, err := addTransaction(AddTransactionRequest{Type: "income", Amount: "100", Category: "salary", Month: "june", Year: "2023"}); err != nil {
+		t.Fatalf("Expected no error adding undated transaction, got %v", err)
+	}
+	transactions, _ = loadTransactionsFromTestStorage()
+	if list := transactions["2023"]["june"]["income"]; len(list) != 1 || list[0].Date.Format(TransactionDateFormat) != "2023-06-01" {
+		t.Errorf("Expected transaction on 2023-06-01, got %+v", list)
+	}
+
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "1", Category: "food", Date: "15.03.2024"}); err == nil {
+		t.Errorf("Expected error for invalid date format")
+	}
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "1", Category: "food", Month: "smarch", Year: "2024"}); err == nil {
+		t.Errorf("Expected error for invalid month")
+	}
+}
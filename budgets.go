@@ -0,0 +1,596 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// a monthly spending limit of an expense category, a budget on a parent category also covers its subcategories
+type Budget struct {
+	Category  string
+	Limit     float64
+	Carryover bool      // unspent amounts are added to the limit of the next month
+	Since     time.Time // first of the month the budget started in, nothing is carried over from before it
+}
+
+// how a budget stands in a month
+type BudgetStatus struct {
+	Budget
+	Carried float64 // unspent amount carried over from the previous months
+	Spent   float64
+}
+
+// the limit of the month together with what was carried over
+func (s BudgetStatus) Available() float64 {
+	return s.Limit + s.Carried
+}
+
+// share of the available amount that is spent, over 1 once the budget is exceeded
+func (s BudgetStatus) Progress() float64 {
+	if s.Available() <= 0 {
+		return 0
+	}
+	return s.Spent / s.Available()
+}
+
+const budgetSinceFormat = "2006-01"
+
+// width of the progress bars in the budget panel
+const budgetBarWidth = 20
+
+var ErrBudgetNotFound = errors.New("budget not found")
+
+// helper to check if a budget covers a category, either its own or the one of its parent
+func budgetCovers(budgetCategory, category string) bool {
+	return category == budgetCategory || (!isSubcategory(budgetCategory) && parentCategory(category) == budgetCategory)
+}
+
+// lists every budget sorted by category, budgets are only stored in the sqlite database
+func listBudgets() ([]Budget, error) {
+	if !categoriesInDb() {
+		return nil, nil
+	}
+
+	rows, err := db.Query(`SELECT category, amount, carryover, since FROM budgets ORDER BY category COLLATE NOCASE`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load budgets: %w", err)
+	}
+	defer rows.Close()
+
+	var budgets []Budget
+	for rows.Next() {
+		var b Budget
+		var since string
+		if err := rows.Scan(&b.Category, &b.Limit, &b.Carryover, &since); err != nil {
+			return nil, fmt.Errorf("failed to read budget: %w", err)
+		}
+		if b.Since, err = time.Parse(budgetSinceFormat, since); err != nil {
+			return nil, fmt.Errorf("invalid start of budget %s: %w", b.Category, err)
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+// sets the monthly limit of an expense category, a new budget starts in the current month
+func setBudget(category, amount string, carryover bool) error {
+	limit, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+	if err != nil {
+		return fmt.Errorf("invalid budget amount: %w", err)
+	}
+	if limit <= 0 {
+		return fmt.Errorf("budget amount has to be more than 0")
+	}
+	if !isAllowedCategory("expense", category) {
+		return fmt.Errorf("invalid budget category: %s", category)
+	}
+
+	return runDbChange(func(sqlTx *sql.Tx) error {
+		now := time.Now()
+		since := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).Format(budgetSinceFormat)
+		_, err := sqlTx.Exec(`
+			INSERT INTO budgets (category, amount, carryover, since) VALUES (?, ?, ?, ?)
+			ON CONFLICT (category) DO UPDATE SET amount = excluded.amount, carryover = excluded.carryover
+		`, category, limit, carryover, since)
+		if err != nil {
+			return fmt.Errorf("failed to save budget of %s: %w", category, err)
+		}
+		log.Printf("set budget of %s to %.2f", category, limit)
+		return nil
+	})
+}
+
+// removes the budget of a category
+func deleteBudget(category string) error {
+	return runDbChange(func(sqlTx *sql.Tx) error {
+		result, err := sqlTx.Exec(`DELETE FROM budgets WHERE category = ?`, category)
+		if err != nil {
+			return fmt.Errorf("failed to delete budget of %s: %w", category, err)
+		}
+		if removed, _ := result.RowsAffected(); removed == 0 {
+			return fmt.Errorf("%w: %s", ErrBudgetNotFound, category)
+		}
+		return nil
+	})
+}
+
+// helper to keep the budget of an expense category when the category is renamed
+func renameBudget(sqlTx *sql.Tx, txType, oldName, newName string) error {
+	if txType != "expense" {
+		return nil
+	}
+	if _, err := sqlTx.Exec(`UPDATE budgets SET category = ? WHERE category = ?`, newName, oldName); err != nil {
+		return fmt.Errorf("failed to rename budget of %s: %w", oldName, err)
+	}
+	return nil
+}
+
+// helper to keep the budget of a category that is merged into another one, the budget moves over unless the other category has its own
+func mergeBudget(sqlTx *sql.Tx, from, into string) error {
+	_, err := sqlTx.Exec(`UPDATE budgets SET category = ? WHERE category = ? AND NOT EXISTS (SELECT 1 FROM budgets WHERE category = ?)`, into, from, into)
+	if err != nil {
+		return fmt.Errorf("failed to move budget of %s to %s: %w", from, into, err)
+	}
+	result, err := sqlTx.Exec(`DELETE FROM budgets WHERE category = ?`, from)
+	if err != nil {
+		return fmt.Errorf("failed to remove budget of %s: %w", from, err)
+	}
+	if dropped, _ := result.RowsAffected(); dropped > 0 {
+		log.Printf("dropped budget of %s, %s keeps its own budget", from, into)
+	}
+	return nil
+}
+
+// describes the budget that is dropped when a category is merged into one that already has a budget, empty when nothing is lost
+func mergeBudgetWarning(txType, from, into string) (string, error) {
+	if txType != "expense" {
+		return "", nil
+	}
+	budgets, err := listBudgets()
+	if err != nil {
+		return "", err
+	}
+
+	var fromBudget, intoBudget *Budget
+	for i := range budgets {
+		switch budgets[i].Category {
+		case from:
+			fromBudget = &budgets[i]
+		case into:
+			intoBudget = &budgets[i]
+		}
+	}
+	if fromBudget == nil || intoBudget == nil {
+		return "", nil
+	}
+	return fmt.Sprintf("%s keeps its budget of €%.2f, the budget of €%.2f of %s is removed", into, intoBudget.Limit, fromBudget.Limit, from), nil
+}
+
+// asks before merging a category whose budget would be removed
+func confirmMergeDropsBudget(warning string, focus tview.Primitive, confirm func()) {
+	closePrompt := func() {
+		pages.RemovePage("mergeBudgetPrompt")
+		tui.SetFocus(focus)
+	}
+
+	modal := styleModal(tview.NewModal().
+		SetText(fmt.Sprintf("Both categories have a budget\n\n%s", warning)).
+		AddButtons([]string{"Merge anyway", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			closePrompt()
+			if label == "Merge anyway" {
+				confirm()
+			}
+		}))
+
+	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closePrompt()
+			return nil
+		}
+		return event
+	})
+
+	pages.AddPage("mergeBudgetPrompt", modal, true, true)
+	tui.SetFocus(modal)
+}
+
+// works out how every budget stands in a month, carried over amounts are worked out from the start of each budget with its current limit
+func calculateBudgetStatus(month, year string) ([]BudgetStatus, error) {
+	budgets, err := listBudgets()
+	if err != nil || len(budgets) == 0 {
+		return nil, err
+	}
+
+	monthStart, err := firstOfMonth(month, year)
+	if err != nil {
+		return nil, fmt.Errorf("invalid budget period: %w", err)
+	}
+
+	// only budgets that carry over need the months before this one
+	from := monthStart
+	for _, b := range budgets {
+		if b.Carryover && b.Since.Before(from) {
+			from = b.Since
+		}
+	}
+
+	transactions, err := LoadTransactionsInRange(from, monthStart.AddDate(0, 1, -1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load transactions: %w", err)
+	}
+
+	// expenses of each month per category
+	spentPerMonth := make(map[time.Time]map[string]float64)
+	for _, months := range transactions {
+		for _, types := range months {
+			for _, tx := range types["expense"] {
+				m := time.Date(tx.Date.Year(), tx.Date.Month(), 1, 0, 0, 0, 0, time.UTC)
+				if spentPerMonth[m] == nil {
+					spentPerMonth[m] = make(map[string]float64)
+				}
+				spentPerMonth[m][tx.Category] += tx.Amount
+			}
+		}
+	}
+	spent := func(budgetCategory string, m time.Time) float64 {
+		var total float64
+		for category, amount := range spentPerMonth[m] {
+			if budgetCovers(budgetCategory, category) {
+				total += amount
+			}
+		}
+		return total
+	}
+
+	var statuses []BudgetStatus
+	for _, b := range budgets {
+		s := BudgetStatus{Budget: b, Spent: spent(b.Category, monthStart)}
+		if b.Carryover {
+			for m := b.Since; m.Before(monthStart); m = m.AddDate(0, 1, 0) {
+				s.Carried = math.Max(0, b.Limit+s.Carried-spent(b.Category, m))
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// describes the budgets a new expense would exceed, empty when it stays within all of them
+func budgetWarning(category string, amount float64, date time.Time) (string, error) {
+	month, year := periodOfDate(date)
+	statuses, err := calculateBudgetStatus(month, year)
+	if err != nil {
+		return "", err
+	}
+
+	var warnings []string
+	for _, s := range statuses {
+		if !budgetCovers(s.Category, category) || s.Spent+amount <= s.Available() {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s would be €%.2f over its €%.2f budget for %s %s", s.Category, s.Spent+amount-s.Available(), s.Available(), capitalize(month), year))
+	}
+	return strings.Join(warnings, "\n"), nil
+}
+
+// helper to get the budget warning of a new transaction, only expenses count towards budgets
+// a failed check is only logged, the budgets should never keep a transaction from being added
+func expenseBudgetWarning(req AddTransactionRequest) string {
+	if req.Type != "expense" {
+		return ""
+	}
+	amount, err := strconv.ParseFloat(req.Amount, 64)
+	if err != nil {
+		return ""
+	}
+	date, err := parseTransactionDate(req.Date)
+	if err != nil {
+		if date, err = firstOfMonth(req.Month, req.Year); err != nil {
+			return ""
+		}
+	}
+
+	warning, err := budgetWarning(req.Category, amount, date)
+	if err != nil {
+		log.Printf("failed to check budgets: %s", err)
+		return ""
+	}
+	return warning
+}
+
+// asks before adding an expense that goes over a budget
+func confirmOverBudget(warning string, focus tview.Primitive, confirm func()) {
+	closePrompt := func() {
+		pages.RemovePage("overBudgetPrompt")
+		tui.SetFocus(focus)
+	}
+
+	modal := styleModal(tview.NewModal().
+		SetText(fmt.Sprintf("Over budget\n\n%s", warning)).
+		AddButtons([]string{"Add anyway", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			closePrompt()
+			if label == "Add anyway" {
+				confirm()
+			}
+		}))
+
+	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closePrompt()
+			return nil
+		}
+		return event
+	})
+
+	pages.AddPage("overBudgetPrompt", modal, true, true)
+	tui.SetFocus(modal)
+}
+
+// helper to draw how much of a budget is spent as a bar - green while within it, yellow when close and red once it is exceeded
+func budgetBar(s BudgetStatus) string {
+	color := Green
+	switch progress := s.Progress(); {
+	case progress > 1:
+		color = Red
+	case progress >= 0.8:
+		color = Yellow
+	}
+
+	filled := int(math.Round(math.Min(s.Progress(), 1) * budgetBarWidth))
+	return color + strings.Repeat("█", filled) + Reset + strings.Repeat("░", budgetBarWidth-filled)
+}
+
+// creates the panel of the main grid with spent vs limit for each budget, nil when there are no budgets
+func createBudgetPanel(month, year string) (*tview.TextView, int, error) {
+	if month == "" || year == "" {
+		return nil, 0, nil
+	}
+	statuses, err := calculateBudgetStatus(month, year)
+	if err != nil || len(statuses) == 0 {
+		return nil, 0, err
+	}
+
+	var b strings.Builder
+	for i, s := range statuses {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(fmt.Sprintf("%-*s %s €%.2f / €%.2f", CategoryNameMaxLength, s.Category, budgetBar(s), s.Spent, s.Available()))
+		if s.Carried > 0 {
+			b.WriteString(fmt.Sprintf(" (€%.2f carried over)", s.Carried))
+		}
+	}
+
+	panel := styleTextView(tview.NewTextView().
+		SetDynamicColors(true).
+		SetWordWrap(false).
+		SetText(b.String()))
+	// a few budgets fit without scrolling, the rest of the panel stays reserved for the tables
+	return panel, min(len(statuses), 6), nil
+}
+
+func generateBudgetsFooter() string {
+	return Green + "a" + Reset + ": add  " +
+		Yellow + "e" + Reset + ": edit  " +
+		Red + "d" + Reset + ": delete  " +
+		Yellow + "ESC" + Reset + "/" + Yellow + "q" + Reset + ": back"
+}
+
+// creates a TUI window to add, edit and delete the monthly budgets, with how each of them stands in the selected month
+func showBudgets(selectedMonth, selectedYear, focusTableType string) error {
+	if err := requireWritableSQLiteDb(); err != nil {
+		return err
+	}
+	if selectedMonth == "" || selectedYear == "" {
+		now := time.Now()
+		selectedMonth, selectedYear = periodOfDate(now)
+	}
+
+	table := styleTable(tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0))
+	table.SetTitle(fmt.Sprintf("Budgets - %s %s", capitalize(selectedMonth), selectedYear)).
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true)
+
+	// fills the table again after a change and keeps the selection on the changed budget where possible
+	refresh := func(selectCategory string) error {
+		statuses, err := calculateBudgetStatus(selectedMonth, selectedYear)
+		if err != nil {
+			return err
+		}
+
+		table.Clear()
+		for c, h := range []string{"Category", "Limit", "Carry over", "Spent", "Available", "Progress"} {
+			table.SetCell(0, c, tview.NewTableCell(h).SetSelectable(false))
+		}
+		if len(statuses) == 0 {
+			table.SetCell(1, 0, tview.NewTableCell("no budgets yet"))
+			return nil
+		}
+
+		for i, s := range statuses {
+			row := i + 1
+			carryover := "no"
+			if s.Carryover {
+				carryover = fmt.Sprintf("yes, since %s", s.Since.Format(budgetSinceFormat))
+			}
+			table.SetCell(row, 0, tview.NewTableCell(s.Category).SetReference(s.Budget))
+			table.SetCell(row, 1, tview.NewTableCell(fmt.Sprintf("€%.2f", s.Limit)).SetAlign(tview.AlignRight))
+			table.SetCell(row, 2, tview.NewTableCell(carryover))
+			table.SetCell(row, 3, tview.NewTableCell(fmt.Sprintf("€%.2f", s.Spent)).SetAlign(tview.AlignRight))
+			table.SetCell(row, 4, tview.NewTableCell(fmt.Sprintf("€%.2f", s.Available())).SetAlign(tview.AlignRight))
+			table.SetCell(row, 5, tview.NewTableCell(budgetBar(s)))
+			if s.Category == selectCategory {
+				table.Select(row, 0)
+			}
+		}
+		if r, _ := table.GetSelection(); r < 1 {
+			table.Select(1, 0)
+		}
+		return nil
+	}
+	if err := refresh(""); err != nil {
+		return err
+	}
+
+	// helper to get the budget on the selected row
+	selected := func() (Budget, bool) {
+		r, _ := table.GetSelection()
+		b, ok := table.GetCell(r, 0).GetReference().(Budget)
+		return b, ok
+	}
+
+	backToTransactions := func() {
+		pages.RemovePage("budgets")
+		gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType, true)
+	}
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if ev := exitShortcuts(event); ev == nil {
+			backToTransactions()
+			return nil
+		}
+		if event.Key() != tcell.KeyRune {
+			return vimMotions(event)
+		}
+
+		switch event.Rune() {
+		case 'a':
+			formBudget(nil, table, refresh)
+			return nil
+		case 'e':
+			if b, ok := selected(); ok {
+				formBudget(&b, table, refresh)
+			}
+			return nil
+		case 'd':
+			if b, ok := selected(); ok {
+				if err := deleteBudget(b.Category); err != nil {
+					showErrorModal(fmt.Sprintf("failed to delete budget:\n\n%s", err), table)
+					log.Printf("failed to delete budget: %s", err)
+					return nil
+				}
+				if err := refresh(""); err != nil {
+					showErrorModal(fmt.Sprintf("failed to list budgets:\n\n%s", err), table)
+				}
+			}
+			return nil
+		}
+		return vimMotions(event)
+	})
+
+	// navigation help
+	frame := tview.NewFrame(table).
+		AddText(generateBudgetsFooter(), false, tview.AlignCenter, theme.FieldTextColor)
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).    // left spacer
+		AddItem(frame, 110, 1, true). // same width as the categories
+		AddItem(nil, 0, 1, false))    // right spacer
+
+	pages.AddPage("budgets", modal, true, true)
+	tui.SetFocus(table)
+	return nil
+}
+
+// creates a TUI form to add a budget, or to change the limit and carry over of an existing one
+func formBudget(existing *Budget, focus tview.Primitive, refresh func(selectCategory string) error) {
+	categories, err := listOfAllowedCategories("expense")
+	if err != nil {
+		showErrorModal(fmt.Sprintf("failed to list categories:\n\n%s", err), focus)
+		return
+	}
+
+	category := categories[0]
+	title := "Add Budget"
+	var amount string
+	var carryover bool
+	if existing != nil {
+		category, carryover = existing.Category, existing.Carryover
+		amount = strconv.FormatFloat(existing.Limit, 'f', 2, 64)
+		title = fmt.Sprintf("Edit Budget of %s", existing.Category)
+	}
+
+	// selecting an option sets category, so an edit has to preselect the category of the budget
+	categoryDropdown := styleDropdown(tview.NewDropDown().
+		SetLabel("Category").
+		SetOptions(categories, func(selectedOption string, _ int) {
+			category = selectedOption
+		}))
+	categoryDropdown.SetCurrentOption(max(slices.Index(categories, category), 0))
+	categoryDropdown.SetInputCapture(vimMotions)
+
+	amountField := styleInputField(tview.NewInputField().
+		SetLabel("Monthly limit").
+		SetText(amount))
+	carryoverCheckbox := tview.NewCheckbox().
+		SetLabel("Carry over unspent amounts").
+		SetChecked(carryover)
+
+	closeForm := func() {
+		pages.RemovePage("budgetForm")
+		tui.SetFocus(focus)
+	}
+
+	var form *tview.Form
+	form = styleForm(tview.NewForm())
+	if existing == nil {
+		form.AddFormItem(categoryDropdown)
+	}
+	form.AddFormItem(amountField).
+		AddFormItem(carryoverCheckbox).
+		AddButton("Save", func() {
+			if err := setBudget(category, amountField.GetText(), carryoverCheckbox.IsChecked()); err != nil {
+				showErrorModal(fmt.Sprintf("failed to save budget:\n\n%s", err), form)
+				log.Printf("failed to save budget: %s", err)
+				return
+			}
+
+			closeForm()
+			if err := refresh(category); err != nil {
+				showErrorModal(fmt.Sprintf("failed to list budgets:\n\n%s", err), focus)
+			}
+		}).
+		AddButton("Cancel", closeForm)
+
+	form.SetButtonsAlign(tview.AlignCenter)
+	form.SetBorder(true).SetTitle(title).SetTitleAlign(tview.AlignCenter)
+
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeForm()
+			return nil
+		}
+		return event
+	})
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).  // left spacer
+		AddItem(form, 60, 1, true). // wide enough for the checkbox label
+		AddItem(nil, 0, 1, false))  // right spacer
+
+	// vertical centering
+	centeredModal := styleFlex(tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).   // top spacer
+		AddItem(modal, 11, 1, true). // enough to fit the fields and the buttons
+		AddItem(nil, 0, 1, false))   // bottom spacer
+
+	pages.AddPage("budgetForm", centeredModal, true, true)
+	tui.SetFocus(form)
+}
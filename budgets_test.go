@@ -0,0 +1,226 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+// helper to start a budget in an earlier month, new budgets always start in the current one
+func setTestBudgetSince(t *testing.T, category, since string) {
+	t.Helper()
+	if _, err := db.Exec(`UPDATE budgets SET since = ? WHERE category = ?`, since, category); err != nil {
+		t.Fatalf("Failed to move start of budget: %v", err)
+	}
+}
+
+func TestSetBudget(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	if err := setBudget("food", "300", false); err != nil {
+		t.Fatalf("Expected no error setting a budget, got %v", err)
+	}
+	setTestBudgetSince(t, "food", "2025-01")
+	// changing the limit keeps the month the budget started in
+	if err := setBudget("food", "350.50", true); err != nil {
+		t.Fatalf("Expected no error changing a budget, got %v", err)
+	}
+
+	budgets, err := listBudgets()
+	if err != nil || len(budgets) != 1 {
+		t.Fatalf("Expected one budget, got %+v (err %v)", budgets, err)
+	}
+	if b := budgets[0]; b.Limit != 350.50 || !b.Carryover || b.Since.Format(budgetSinceFormat) != "2025-01" {
+		t.Errorf("Unexpected budget %+v", b)
+	}
+
+	for _, amount := range []string{"", "abc", "0", "-10"} {
+		if err := setBudget("food", amount, false); err == nil {
+			t.Errorf("Expected amount %q to be rejected", amount)
+		}
+	}
+	if err := setBudget("salary", "100", false); err == nil {
+		t.Errorf("Expected budgets to only be set on expense categories")
+	}
+
+	if err := deleteBudget("food"); err != nil {
+		t.Fatalf("Expected no error deleting a budget, got %v", err)
+	}
+	if err := deleteBudget("food"); !errors.Is(err, ErrBudgetNotFound) {
+		t.Errorf("Expected ErrBudgetNotFound, got %v", err)
+	}
+}
+
+func TestEditBudgetForm(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	setupTestTui(t)
+
+	categories, err := listOfAllowedCategories("expense")
+	if err != nil || len(categories) < 2 {
+		t.Fatalf("Expected expense categories, got %v (err %v)", categories, err)
+	}
+	first, edited := categories[0], categories[len(categories)-1]
+	for category, amount := range map[string]string{first: "100", edited: "200"} {
+		if err := setBudget(category, amount, false); err != nil {
+			t.Fatalf("Failed to set budget: %v", err)
+		}
+	}
+
+	// the category dropdown is hidden when editing, the budget that was opened has to be the one that changes
+	formBudget(&Budget{Category: edited, Limit: 200}, tview.NewBox(), func(string) error { return nil })
+	form := frontTestForm(t)
+	form.GetFormItemByLabel("Monthly limit").(*tview.InputField).SetText("250")
+	pressTestFormButton(t, form, "Save")
+
+	budgets, err := listBudgets()
+	if err != nil {
+		t.Fatalf("Failed to list budgets: %v", err)
+	}
+	limits := map[string]float64{}
+	for _, b := range budgets {
+		limits[b.Category] = b.Limit
+	}
+	if limits[edited] != 250 || limits[first] != 100 {
+		t.Errorf("Expected only the budget of %s to change, got %v", edited, limits)
+	}
+}
+
+func TestCalculateBudgetStatus(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	if err := addCategory("expense", "food/groceries", ""); err != nil {
+		t.Fatalf("Failed to add subcategory: %v", err)
+	}
+	for _, req := range []AddTransactionRequest{
+		{Type: "expense", Amount: "200", Category: "food", Date: "2025-01-10"},
+		{Type: "expense", Amount: "450", Category: "food/groceries", Date: "2025-02-10"},
+		{Type: "expense", Amount: "120", Category: "food/groceries", Date: "2025-03-03"},
+		{Type: "expense", Amount: "50", Category: "food", Date: "2025-03-20"},
+		{Type: "expense", Amount: "90", Category: "bills", Date: "2025-03-05"},
+	} {
+		if _, err := addTransaction(req); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	if err := setBudget("food", "300", true); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+	if err := setBudget("food/groceries", "100", false); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+	setTestBudgetSince(t, "food", "2025-01")
+
+	statuses, err := calculateBudgetStatus("march", "2025")
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("Expected the status of both budgets, got %+v (err %v)", statuses, err)
+	}
+
+	// january leaves 100 unspent, february uses it up and overspends - overspending isn't carried over
+	food := statuses[0]
+	if food.Spent != 170 || food.Carried != 0 || food.Available() != 300 {
+		t.Errorf("Expected the subcategories to count towards the food budget without carry over, got %+v", food)
+	}
+	if groceries := statuses[1]; groceries.Spent != 120 || groceries.Progress() <= 1 {
+		t.Errorf("Expected the groceries budget to be exceeded, got %+v", groceries)
+	}
+
+	february, _ := calculateBudgetStatus("february", "2025")
+	if february[0].Carried != 100 || february[0].Available() != 400 {
+		t.Errorf("Expected the unspent january amount to be carried over, got %+v", february[0])
+	}
+}
+
+func TestBudgetWarning(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	if err := setBudget("food", "100", false); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+	addTestExpense(t, "food") // 10 on 2025-03-14
+
+	req := AddTransactionRequest{Type: "expense", Amount: "90", Category: "food", Date: "2025-03-20"}
+	if warning := expenseBudgetWarning(req); warning != "" {
+		t.Errorf("Expected no warning for an expense that fits the budget, got %q", warning)
+	}
+
+	req.Amount = "95"
+	if warning := expenseBudgetWarning(req); !strings.Contains(warning, "food would be €5.00 over its €100.00 budget for March 2025") {
+		t.Errorf("Expected an over budget warning, got %q", warning)
+	}
+
+	// income and other categories don't count towards the budget
+	if warning := expenseBudgetWarning(AddTransactionRequest{Type: "income", Amount: "500", Category: "food", Date: "2025-03-20"}); warning != "" {
+		t.Errorf("Expected no warning for income, got %q", warning)
+	}
+	if warning := expenseBudgetWarning(AddTransactionRequest{Type: "expense", Amount: "500", Category: "bills", Date: "2025-03-20"}); warning != "" {
+		t.Errorf("Expected no warning for a category without a budget, got %q", warning)
+	}
+
+	out, err := runTestCliCommand(t, cliAddSetup, "--amount", "95", "--category", "food", "--date", "2025-03-21")
+	if err != nil || !strings.Contains(out, "warning: food would be") {
+		t.Errorf("Expected the command line to warn about the budget, got %q (err %v)", out, err)
+	}
+}
+
+func TestBudgetFollowsCategory(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	if err := addCategory("expense", "food/groceries", ""); err != nil {
+		t.Fatalf("Failed to add subcategory: %v", err)
+	}
+	if err := setBudget("food/groceries", "100", false); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+	if err := setBudget("bills", "100", false); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+
+	if err := editCategory("expense", "food", "meals", ""); err != nil {
+		t.Fatalf("Failed to rename category: %v", err)
+	}
+	if err := mergeCategory("expense", "bills", "housing"); err != nil {
+		t.Fatalf("Failed to merge category: %v", err)
+	}
+
+	budgets, _ := listBudgets()
+	if len(budgets) != 2 || budgets[0].Category != "housing" || budgets[1].Category != "meals/groceries" {
+		t.Errorf("Expected the budgets to follow the renamed and the merged category, got %+v", budgets)
+	}
+
+	// a category that already has a budget keeps it, merging asks first because the other one is removed
+	if err := setBudget("taxes", "50", false); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+	warning, err := mergeBudgetWarning("expense", "housing", "taxes")
+	if err != nil || !strings.Contains(warning, "budget of €100.00 of housing is removed") {
+		t.Errorf("Expected a warning about the removed budget, got %q (err %v)", warning, err)
+	}
+	if warning, _ := mergeBudgetWarning("expense", "taxes", "travel"); warning != "" {
+		t.Errorf("Expected no warning when only one category has a budget, got %q", warning)
+	}
+	if err := mergeCategory("expense", "housing", "taxes"); err != nil {
+		t.Fatalf("Failed to merge category: %v", err)
+	}
+	budgets, _ = listBudgets()
+	if len(budgets) != 2 || budgets[1].Category != "taxes" || budgets[1].Limit != 50 {
+		t.Errorf("Expected taxes to keep its own budget, got %+v", budgets)
+	}
+}
+
+func TestBudgetBar(t *testing.T) {
+	for _, tc := range []struct {
+		spent, limit float64
+		color        string
+		filled       int
+	}{
+		{0, 100, Green, 0},
+		{50, 100, Green, 10},
+		{85, 100, Yellow, 17},
+		{150, 100, Red, 20},
+	} {
+		bar := budgetBar(BudgetStatus{Budget: Budget{Limit: tc.limit}, Spent: tc.spent})
+		if !strings.HasPrefix(bar, tc.color) || strings.Count(bar, "█") != tc.filled || strings.Count(bar, "░") != budgetBarWidth-tc.filled {
+			t.Errorf("Unexpected bar for %.0f of %.0f: %q", tc.spent, tc.limit, bar)
+		}
+	}
+}
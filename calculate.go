@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"sort"
 )
 
 type PnLResult struct {
@@ -17,7 +18,7 @@ type PnLResult struct {
 func calculateMonthPnL(month, year string) (PnLResult, error) {
 	var pnl PnLResult
 
-	transactions, loadFileErr := LoadTransactions()
+	transactions, loadFileErr := LoadTransactionsForPeriod(year, month, "")
 	if loadFileErr != nil {
 		return pnl, fmt.Errorf("unable to load transactions file: %w", loadFileErr)
 	}
@@ -122,3 +123,106 @@ func calculateYearMonthlyPnL(year string) (map[string]PnLResult, error) {
 
 	return monthlyPnL, nil
 }
+
+// calculates the p&l of only the transactions with a tag, for a month or for the whole year if no month is given
+func calculateTagPnL(tag, month, year string) (PnLResult, error) {
+	var pnl PnLResult
+
+	transactions, err := LoadTransactionsForPeriod(year, month, "")
+	if err != nil {
+		return pnl, fmt.Errorf("unable to load transactions file: %w", err)
+	}
+
+	for m := range transactions[year] {
+		for txType, txList := range transactions[year][m] {
+			for _, tx := range txList {
+				if !hasTag(tx, tag) {
+					continue
+				}
+				switch txType {
+				case "income":
+					pnl.incomeTotal += tx.Amount
+				case "expense":
+					pnl.expenseTotal += tx.Amount
+				case "investment":
+					pnl.investmentTotal += tx.Amount
+				}
+			}
+		}
+	}
+
+	// same savings as for a whole month or year, investments don't count towards them
+	pnl.pnlAmount = pnl.incomeTotal - pnl.expenseTotal
+	if pnl.incomeTotal != 0 {
+		pnl.pnlPercent = (pnl.pnlAmount / pnl.incomeTotal) * 100
+	}
+	return pnl, nil
+}
+
+// the total of a category, a top level category includes the totals of its subcategories
+type CategoryTotal struct {
+	Name     string
+	Amount   float64
+	Children []CategoryTotal // the subcategories of a parent, transactions booked on the parent itself are listed under the parent's name
+}
+
+// adds up the transactions of one type per category and rolls subcategories up into their parent category
+func rollUpCategoryTotals(transactions []Transaction) []CategoryTotal {
+	parents := make(map[string]*CategoryTotal)
+	children := make(map[string]map[string]float64)
+
+	for _, tx := range transactions {
+		parent := parentCategory(tx.Category)
+		if parents[parent] == nil {
+			parents[parent] = &CategoryTotal{Name: parent}
+			children[parent] = make(map[string]float64)
+		}
+		parents[parent].Amount += tx.Amount
+		children[parent][tx.Category] += tx.Amount
+	}
+
+	var totals []CategoryTotal
+	for name, total := range parents {
+		// a parent without subcategories has nothing to drill down into
+		if _, bookedOnParent := children[name][name]; len(children[name]) > 1 || !bookedOnParent {
+			for child, amount := range children[name] {
+				total.Children = append(total.Children, CategoryTotal{Name: child, Amount: amount})
+			}
+			sortCategoryTotals(total.Children)
+		}
+		totals = append(totals, *total)
+	}
+	sortCategoryTotals(totals)
+	return totals
+}
+
+// helper to sort category totals with the largest first, equal totals by name
+func sortCategoryTotals(totals []CategoryTotal) {
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Amount != totals[j].Amount {
+			return totals[i].Amount > totals[j].Amount
+		}
+		return totals[i].Name < totals[j].Name
+	})
+}
+
+// calculates the category totals of each transaction type for a month, or for the whole year if no month is given
+func calculateCategoryTotals(month, year string) (map[string][]CategoryTotal, error) {
+	transactions, err := LoadTransactionsForPeriod(year, month, "")
+	if err != nil {
+		return nil, fmt.Errorf("unable to load transactions file: %w", err)
+	}
+
+	byType := make(map[string][]Transaction)
+	for m := range transactions[year] {
+		for txType, txList := range transactions[year][m] {
+			byType[txType] = append(byType[txType], txList...)
+		}
+	}
+
+	totals := make(map[string][]CategoryTotal)
+	for txType, txList := range byType {
+		totals[txType] = rollUpCategoryTotals(txList)
+	}
+	return totals, nil
+}
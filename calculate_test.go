@@ -256,3 +256,66 @@ func TestCalculateYearPnL(t *testing.T) {
 		})
 	}
 }
+
+func TestRollUpCategoryTotals(t *testing.T) {
+	totals := rollUpCategoryTotals([]Transaction{
+		{Amount: 50, Category: "food/groceries"},
+		{Amount: 30, Category: "food/restaurants"},
+		{Amount: 20, Category: "food"},
+		{Amount: 10, Category: "food/groceries"},
+		{Amount: 200, Category: "rent"},
+	})
+
+	if len(totals) != 2 || totals[0].Name != "rent" || totals[1].Name != "food" {
+		t.Fatalf("Expected rent and food as the parent categories, largest first, got %+v", totals)
+	}
+	if len(totals[0].Children) != 0 {
+		t.Errorf("Expected no drill-down for a category without subcategories, got %+v", totals[0].Children)
+	}
+
+	food := totals[1]
+	if food.Amount != 110 {
+		t.Errorf("Expected the subcategories to roll up into food, got %.2f", food.Amount)
+	}
+	expected := []CategoryTotal{{Name: "food/groceries", Amount: 60}, {Name: "food/restaurants", Amount: 30}, {Name: "food", Amount: 20}}
+	if len(food.Children) != len(expected) {
+		t.Fatalf("Expected %d children, got %+v", len(expected), food.Children)
+	}
+	for i, child := range expected {
+		if food.Children[i].Name != child.Name || food.Children[i].Amount != child.Amount {
+			t.Errorf("Expected child %d to be %+v, got %+v", i, child, food.Children[i])
+		}
+	}
+}
+
+func TestCalculateCategoryTotals(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	if err := addCategory("expense", "food/groceries", ""); err != nil {
+		t.Fatalf("Failed to add subcategory: %v", err)
+	}
+	for _, req := range []AddTransactionRequest{
+		{Type: "expense", Amount: "40", Category: "food/groceries", Date: "2025-03-14"},
+		{Type: "expense", Amount: "15", Category: "food", Date: "2025-04-02"},
+		{Type: "income", Amount: "1000", Category: "salary", Date: "2025-03-01"},
+	} {
+		if _, err := addTransaction(req); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	month, err := calculateCategoryTotals("march", "2025")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(month["expense"]) != 1 || month["expense"][0].Amount != 40 || month["income"][0].Amount != 1000 {
+		t.Errorf("Expected only the march transactions, got %+v", month)
+	}
+
+	year, err := calculateCategoryTotals("", "2025")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if food := year["expense"][0]; food.Name != "food" || food.Amount != 55 || len(food.Children) != 2 {
+		t.Errorf("Expected the year to roll up into food with a drill-down, got %+v", food)
+	}
+}
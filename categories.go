@@ -0,0 +1,741 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// a transaction category, categories belong to a transaction type so e.g. insurance can be an expense and an investment
+type Category struct {
+	Type        string
+	Name        string
+	Description string
+	Archived    bool // existing transactions keep an archived category but new ones can't use it
+}
+
+const CategoryNameMaxLength = 30
+
+// subcategories are named after their parent, e.g. food/groceries, and only go one level deep
+const CategorySeparator = "/"
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrCategoryExists   = errors.New("category already exists")
+)
+
+// helper to get the parent of a subcategory, a top level category is its own parent
+func parentCategory(name string) string {
+	parent, _, _ := strings.Cut(name, CategorySeparator)
+	return parent
+}
+
+// helper to check if a category is the subcategory of another one
+func isSubcategory(name string) bool {
+	return strings.Contains(name, CategorySeparator)
+}
+
+// helper to check if categories are managed in the database, the json storage types only know the default categories
+func categoriesInDb() bool {
+	return globalConfig != nil && globalConfig.StorageType == StorageSQLite && db != nil
+}
+
+// lists the categories of a transaction type sorted by name, archived ones are only included when asked for
+func listCategories(txType string, includeArchived bool) ([]Category, error) {
+	txType, err := normalizeTransactionType(txType)
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []Category
+	if !categoriesInDb() {
+		for name, description := range defaultTransactionCategories[txType] {
+			categories = append(categories, Category{Type: txType, Name: name, Description: description})
+		}
+		sort.Slice(categories, func(i, j int) bool {
+			return strings.ToLower(categories[i].Name) < strings.ToLower(categories[j].Name)
+		})
+		return categories, nil
+	}
+
+	query := `SELECT name, description, archived FROM categories WHERE type = ?`
+	if !includeArchived {
+		query += ` AND archived = 0`
+	}
+	rows, err := db.Query(query+` ORDER BY name COLLATE NOCASE`, txType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s categories: %w", txType, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c := Category{Type: txType}
+		if err := rows.Scan(&c.Name, &c.Description, &c.Archived); err != nil {
+			return nil, fmt.Errorf("failed to read %s category: %w", txType, err)
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// looks up a single category, archived ones included
+func getCategory(txType, name string) (Category, error) {
+	categories, err := listCategories(txType, true)
+	if err != nil {
+		return Category{}, err
+	}
+	for _, c := range categories {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return Category{}, fmt.Errorf("%w: %s %s", ErrCategoryNotFound, txType, name)
+}
+
+// helper to check if new transactions can use a category, archived categories are only kept by the transactions that have them
+func isAllowedCategory(txType, category string) bool {
+	c, err := getCategory(txType, category)
+	return err == nil && !c.Archived
+}
+
+// counts the transactions of each category of a transaction type
+func categoryUsage(txType string) (map[string]int, error) {
+	usage := make(map[string]int)
+	if !categoriesInDb() {
+		return usage, nil
+	}
+
+	rows, err := db.Query(`SELECT category, COUNT(*) FROM transactions WHERE type = ? GROUP BY category`, txType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count transactions per category: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var count int
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, fmt.Errorf("failed to read category usage: %w", err)
+		}
+		usage[name] = count
+	}
+	return usage, rows.Err()
+}
+
+// helper to clean up a category name, names are compared case insensitively so "Gym" and "gym" can't both exist
+func validateCategoryName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("category name cannot be empty")
+	}
+	if isSubcategory(name) {
+		parts := strings.Split(name, CategorySeparator)
+		if len(parts) > 2 {
+			return "", fmt.Errorf("subcategories can only be one level deep, e.g. food%sgroceries", CategorySeparator)
+		}
+		parent, child := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if parent == "" || child == "" {
+			return "", fmt.Errorf("a subcategory needs both a parent and a name, e.g. food%sgroceries", CategorySeparator)
+		}
+		name = parent + CategorySeparator + child
+	}
+	if utf8.RuneCountInString(name) > CategoryNameMaxLength {
+		return "", fmt.Errorf("category name can have at most %d characters", CategoryNameMaxLength)
+	}
+	return name, nil
+}
+
+// helper to check if another category of the transaction type already has the name, except the one named except
+func categoryNameTaken(sqlTx *sql.Tx, txType, name, except string) (bool, error) {
+	var count int
+	err := sqlTx.QueryRow(`SELECT COUNT(*) FROM categories WHERE type = ? AND name = ? COLLATE NOCASE AND name != ?`, txType, name, except).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check category name: %w", err)
+	}
+	return count > 0, nil
+}
+
+// helper to make sure a category exists before it is changed
+func requireCategory(sqlTx *sql.Tx, txType, name string) error {
+	var count int
+	if err := sqlTx.QueryRow(`SELECT COUNT(*) FROM categories WHERE type = ? AND name = ?`, txType, name).Scan(&count); err != nil {
+		return fmt.Errorf("failed to look up category: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: %s %s", ErrCategoryNotFound, txType, name)
+	}
+	return nil
+}
+
+// helper to list the subcategories of a parent category
+func subcategoryNames(sqlTx *sql.Tx, txType, parent string) ([]string, error) {
+	prefix := parent + CategorySeparator
+	rows, err := sqlTx.Query(`SELECT name FROM categories WHERE type = ? AND substr(name, 1, ?) = ?`, txType, utf8.RuneCountInString(prefix), prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up subcategories of %s: %w", parent, err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read subcategory of %s: %w", parent, err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// helper to make sure the parent of a subcategory exists and can take new subcategories
+func requireParentCategory(sqlTx *sql.Tx, txType, name string) error {
+	if !isSubcategory(name) {
+		return nil
+	}
+	parent := parentCategory(name)
+	if err := requireCategory(sqlTx, txType, parent); err != nil {
+		return fmt.Errorf("%w, add the parent category first", err)
+	}
+	return nil
+}
+
+// helper to rename the category of the transactions that use it
+func renameCategoryOfTransactions(sqlTx *sql.Tx, txType, oldName, newName string) (int64, error) {
+	result, err := sqlTx.Exec(`UPDATE transactions SET category = ? WHERE type = ? AND category = ?`, newName, txType, oldName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rename category of transactions: %w", err)
+	}
+	renamed, _ := result.RowsAffected()
+	return renamed, nil
+}
+
+// adds a new category that transactions of the type can use right away
+func addCategory(txType, name, description string) error {
+	txType, err := normalizeTransactionType(txType)
+	if err != nil {
+		return err
+	}
+	if name, err = validateCategoryName(name); err != nil {
+		return err
+	}
+
+	return runDbChange(func(sqlTx *sql.Tx) error {
+		taken, err := categoryNameTaken(sqlTx, txType, name, "")
+		if err != nil {
+			return err
+		}
+		if taken {
+			return fmt.Errorf("%w: %s %s", ErrCategoryExists, txType, name)
+		}
+		if err := requireParentCategory(sqlTx, txType, name); err != nil {
+			return err
+		}
+
+		_, err = sqlTx.Exec(`INSERT INTO categories (type, name, description) VALUES (?, ?, ?)`, txType, name, strings.TrimSpace(description))
+		if err != nil {
+			return fmt.Errorf("failed to add category %s: %w", name, err)
+		}
+		log.Printf("added %s category %s", txType, name)
+		return nil
+	})
+}
+
+// renames a category and sets its description, the transactions that use the old name are renamed with it
+// renaming a parent category also renames its subcategories, e.g. food/groceries becomes meals/groceries
+func editCategory(txType, oldName, newName, description string) error {
+	txType, err := normalizeTransactionType(txType)
+	if err != nil {
+		return err
+	}
+	if newName, err = validateCategoryName(newName); err != nil {
+		return err
+	}
+
+	return runDbChange(func(sqlTx *sql.Tx) error {
+		if err := requireCategory(sqlTx, txType, oldName); err != nil {
+			return err
+		}
+		taken, err := categoryNameTaken(sqlTx, txType, newName, oldName)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return fmt.Errorf("%w: %s %s, merge the categories instead", ErrCategoryExists, txType, newName)
+		}
+
+		var subcategories []string
+		if !isSubcategory(oldName) {
+			if subcategories, err = subcategoryNames(sqlTx, txType, oldName); err != nil {
+				return err
+			}
+		}
+		if isSubcategory(newName) {
+			if len(subcategories) > 0 {
+				return fmt.Errorf("category %s has subcategories and can't become a subcategory itself", oldName)
+			}
+			if parentCategory(newName) == oldName {
+				return fmt.Errorf("category %s can't become a subcategory of itself", oldName)
+			}
+			if err := requireParentCategory(sqlTx, txType, newName); err != nil {
+				return err
+			}
+		}
+
+		_, err = sqlTx.Exec(`UPDATE categories SET name = ?, description = ? WHERE type = ? AND name = ?`, newName, strings.TrimSpace(description), txType, oldName)
+		if err != nil {
+			return fmt.Errorf("failed to update category %s: %w", oldName, err)
+		}
+		if newName == oldName {
+			return nil
+		}
+
+		renamed, err := renameCategoryOfTransactions(sqlTx, txType, oldName, newName)
+		if err != nil {
+			return err
+		}
+		if err := renameBudget(sqlTx, txType, oldName, newName); err != nil {
+			return err
+		}
+		if err := renameRecurringRulesCategory(sqlTx, txType, oldName, newName); err != nil {
+			return err
+		}
+
+		for _, oldChild := range subcategories {
+			newChild := newName + strings.TrimPrefix(oldChild, oldName)
+			taken, err := categoryNameTaken(sqlTx, txType, newChild, oldChild)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return fmt.Errorf("%w: %s %s, merge the categories instead", ErrCategoryExists, txType, newChild)
+			}
+			if _, err := sqlTx.Exec(`UPDATE categories SET name = ? WHERE type = ? AND name = ?`, newChild, txType, oldChild); err != nil {
+				return fmt.Errorf("failed to rename subcategory %s: %w", oldChild, err)
+			}
+			renamedChildren, err := renameCategoryOfTransactions(sqlTx, txType, oldChild, newChild)
+			if err != nil {
+				return err
+			}
+			if err := renameBudget(sqlTx, txType, oldChild, newChild); err != nil {
+				return err
+			}
+			if err := renameRecurringRulesCategory(sqlTx, txType, oldChild, newChild); err != nil {
+				return err
+			}
+			renamed += renamedChildren
+		}
+
+		log.Printf("renamed %s category %s to %s on %d transactions", txType, oldName, newName, renamed)
+		return nil
+	})
+}
+
+// moves every transaction of a category into another category of the same type and removes the emptied category
+func mergeCategory(txType, from, into string) error {
+	txType, err := normalizeTransactionType(txType)
+	if err != nil {
+		return err
+	}
+	if from == into {
+		return fmt.Errorf("can't merge category %s into itself", from)
+	}
+
+	return runDbChange(func(sqlTx *sql.Tx) error {
+		if err := requireCategory(sqlTx, txType, from); err != nil {
+			return err
+		}
+		if err := requireCategory(sqlTx, txType, into); err != nil {
+			return err
+		}
+		if !isSubcategory(from) {
+			subcategories, err := subcategoryNames(sqlTx, txType, from)
+			if err != nil {
+				return err
+			}
+			if len(subcategories) > 0 {
+				return fmt.Errorf("category %s still has subcategories, merge them first", from)
+			}
+		}
+
+		result, err := sqlTx.Exec(`UPDATE transactions SET category = ? WHERE type = ? AND category = ?`, into, txType, from)
+		if err != nil {
+			return fmt.Errorf("failed to move transactions to category %s: %w", into, err)
+		}
+		if _, err := sqlTx.Exec(`DELETE FROM categories WHERE type = ? AND name = ?`, txType, from); err != nil {
+			return fmt.Errorf("failed to remove category %s: %w", from, err)
+		}
+		// the spending of the merged category now counts towards the budget of the category it was merged into
+		if txType == "expense" {
+			if err := mergeBudget(sqlTx, from, into); err != nil {
+				return err
+			}
+		}
+		// recurring transactions keep posting, into the category the old one was merged into
+		if err := renameRecurringRulesCategory(sqlTx, txType, from, into); err != nil {
+			return err
+		}
+		moved, _ := result.RowsAffected()
+		log.Printf("merged %s category %s into %s, moved %d transactions", txType, from, into, moved)
+		return nil
+	})
+}
+
+// archives a category or brings it back, archived categories are no longer offered for new transactions
+// a parent category takes its subcategories with it, and a subcategory can't be brought back while its parent is archived
+func setCategoryArchived(txType, name string, archived bool) error {
+	txType, err := normalizeTransactionType(txType)
+	if err != nil {
+		return err
+	}
+
+	return runDbChange(func(sqlTx *sql.Tx) error {
+		if err := requireCategory(sqlTx, txType, name); err != nil {
+			return err
+		}
+		if !archived && isSubcategory(name) {
+			var parentArchived bool
+			if err := sqlTx.QueryRow(`SELECT archived FROM categories WHERE type = ? AND name = ?`, txType, parentCategory(name)).Scan(&parentArchived); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("failed to look up parent category: %w", err)
+			}
+			if parentArchived {
+				return fmt.Errorf("parent category %s is archived, unarchive it first", parentCategory(name))
+			}
+		}
+
+		names := []string{name}
+		if !isSubcategory(name) {
+			subcategories, err := subcategoryNames(sqlTx, txType, name)
+			if err != nil {
+				return err
+			}
+			names = append(names, subcategories...)
+		}
+		for _, n := range names {
+			if _, err := sqlTx.Exec(`UPDATE categories SET archived = ? WHERE type = ? AND name = ?`, archived, txType, n); err != nil {
+				return fmt.Errorf("failed to archive category %s: %w", n, err)
+			}
+		}
+		return nil
+	})
+}
+
+func generateCategoriesFooter() string {
+	return Green + "a" + Reset + ": add  " +
+		Yellow + "e" + Reset + ": edit  " +
+		Yellow + "m" + Reset + ": merge  " +
+		Red + "x" + Reset + ": archive/unarchive  " +
+		Yellow + "ESC" + Reset + "/" + Yellow + "q" + Reset + ": back"
+}
+
+// creates a TUI window to add, edit, merge and archive the categories of every transaction type
+func showCategories(selectedMonth, selectedYear, focusTableType string) error {
+	if err := requireWritableSQLiteDb(); err != nil {
+		return err
+	}
+
+	table := styleTable(tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0))
+	table.SetTitle("Categories").
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true)
+
+	// fills the table again after a change and keeps the selection on the changed category where possible
+	refresh := func(selectType, selectName string) error {
+		table.Clear()
+		for c, h := range []string{"Type", "Category", "Transactions", "Status", "Description"} {
+			table.SetCell(0, c, tview.NewTableCell(h).SetSelectable(false))
+		}
+
+		row := 1
+		for _, txType := range []string{"expense", "income", "investment"} {
+			categories, err := listCategories(txType, true)
+			if err != nil {
+				return err
+			}
+			usage, err := categoryUsage(txType)
+			if err != nil {
+				return err
+			}
+			for _, c := range categories {
+				status := "active"
+				if c.Archived {
+					status = "archived"
+				}
+				table.SetCell(row, 0, tview.NewTableCell(c.Type).SetReference(c))
+				table.SetCell(row, 1, tview.NewTableCell(c.Name))
+				table.SetCell(row, 2, tview.NewTableCell(fmt.Sprintf("%d", usage[c.Name])).SetAlign(tview.AlignRight))
+				table.SetCell(row, 3, tview.NewTableCell(status))
+				table.SetCell(row, 4, tview.NewTableCell(c.Description))
+				if c.Type == selectType && c.Name == selectName {
+					table.Select(row, 0)
+				}
+				row++
+			}
+		}
+		if r, _ := table.GetSelection(); r < 1 && row > 1 {
+			table.Select(1, 0)
+		}
+		return nil
+	}
+	if err := refresh("", ""); err != nil {
+		return err
+	}
+
+	// helper to get the category on the selected row
+	selected := func() (Category, bool) {
+		r, _ := table.GetSelection()
+		c, ok := table.GetCell(r, 0).GetReference().(Category)
+		return c, ok
+	}
+
+	backToSettings := func() {
+		pages.RemovePage("categories")
+		if err := showSettings(selectedMonth, selectedYear, focusTableType); err != nil {
+			gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType, true)
+		}
+	}
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if ev := exitShortcuts(event); ev == nil {
+			backToSettings()
+			return nil
+		}
+		if event.Key() != tcell.KeyRune {
+			return vimMotions(event)
+		}
+
+		switch event.Rune() {
+		case 'a':
+			formCategory(nil, table, refresh)
+			return nil
+		case 'e':
+			if c, ok := selected(); ok {
+				formCategory(&c, table, refresh)
+			}
+			return nil
+		case 'm':
+			if c, ok := selected(); ok {
+				formMergeCategory(c, table, refresh)
+			}
+			return nil
+		case 'x':
+			if c, ok := selected(); ok {
+				if err := setCategoryArchived(c.Type, c.Name, !c.Archived); err != nil {
+					showErrorModal(fmt.Sprintf("failed to archive category:\n\n%s", err), table)
+					log.Printf("failed to archive category: %s", err)
+					return nil
+				}
+				if err := refresh(c.Type, c.Name); err != nil {
+					showErrorModal(fmt.Sprintf("failed to list categories:\n\n%s", err), table)
+				}
+			}
+			return nil
+		}
+		return vimMotions(event)
+	})
+
+	// navigation help
+	frame := tview.NewFrame(table).
+		AddText(generateCategoriesFooter(), false, tview.AlignCenter, theme.FieldTextColor)
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).    // left spacer
+		AddItem(frame, 110, 1, true). // wide enough for the descriptions of the default categories
+		AddItem(nil, 0, 1, false))    // right spacer
+
+	pages.AddPage("categories", modal, true, true)
+	tui.SetFocus(table)
+	return nil
+}
+
+// creates a TUI form to add a category, or to rename an existing one and change its description
+func formCategory(existing *Category, focus tview.Primitive, refresh func(selectType, selectName string) error) {
+	txType := "expense"
+	title := "Add Category - name it parent" + CategorySeparator + "name for a subcategory"
+	var name, description string
+	if existing != nil {
+		txType, name, description = existing.Type, existing.Name, existing.Description
+		title = fmt.Sprintf("Edit %s Category - renaming updates its transactions", capitalize(txType))
+	}
+
+	// selecting an option sets txType, so an edit has to preselect the type of the category
+	types := []string{"expense", "income", "investment"}
+	typeDropdown := styleDropdown(tview.NewDropDown().
+		SetLabel("Transaction Type").
+		SetOptions(types, func(selectedOption string, _ int) {
+			txType = selectedOption
+		}))
+	typeDropdown.SetCurrentOption(max(slices.Index(types, txType), 0))
+	typeDropdown.SetInputCapture(vimMotions)
+
+	nameField := styleInputField(tview.NewInputField().
+		SetLabel("Name").
+		SetText(name).
+		SetAcceptanceFunc(func(text string, _ rune) bool {
+			return utf8.RuneCountInString(text) <= CategoryNameMaxLength
+		}))
+	descriptionField := styleInputField(tview.NewInputField().
+		SetLabel("Description").
+		SetText(description))
+
+	closeForm := func() {
+		pages.RemovePage("categoryForm")
+		tui.SetFocus(focus)
+	}
+
+	var form *tview.Form
+	form = styleForm(tview.NewForm())
+	if existing == nil {
+		form.AddFormItem(typeDropdown)
+	}
+	form.AddFormItem(nameField).
+		AddFormItem(descriptionField).
+		AddButton("Save", func() {
+			var err error
+			if existing == nil {
+				err = addCategory(txType, nameField.GetText(), descriptionField.GetText())
+			} else {
+				err = editCategory(existing.Type, existing.Name, nameField.GetText(), descriptionField.GetText())
+			}
+			if err != nil {
+				showErrorModal(fmt.Sprintf("failed to save category:\n\n%s", err), form)
+				log.Printf("failed to save category: %s", err)
+				return
+			}
+
+			closeForm()
+			if err := refresh(txType, strings.TrimSpace(nameField.GetText())); err != nil {
+				showErrorModal(fmt.Sprintf("failed to list categories:\n\n%s", err), focus)
+			}
+		}).
+		AddButton("Cancel", closeForm)
+
+	form.SetButtonsAlign(tview.AlignCenter)
+	form.SetBorder(true).SetTitle(title).SetTitleAlign(tview.AlignCenter)
+
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeForm()
+			return nil
+		}
+		return event
+	})
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).  // left spacer
+		AddItem(form, 70, 1, true). // wide enough for a description
+		AddItem(nil, 0, 1, false))  // right spacer
+
+	// vertical centering
+	centeredModal := styleFlex(tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).   // top spacer
+		AddItem(modal, 11, 1, true). // enough to fit the fields and the buttons
+		AddItem(nil, 0, 1, false))   // bottom spacer
+
+	pages.AddPage("categoryForm", centeredModal, true, true)
+	tui.SetFocus(form)
+}
+
+// creates a TUI form to move every transaction of a category into another category of the same type
+func formMergeCategory(from Category, focus tview.Primitive, refresh func(selectType, selectName string) error) {
+	categories, err := listCategories(from.Type, true)
+	if err != nil {
+		showErrorModal(fmt.Sprintf("failed to list categories:\n\n%s", err), focus)
+		return
+	}
+	var targets []string
+	for _, c := range categories {
+		if c.Name != from.Name {
+			targets = append(targets, c.Name)
+		}
+	}
+	if len(targets) == 0 {
+		showErrorModal(fmt.Sprintf("there is no other %s category to merge %s into", from.Type, from.Name), focus)
+		return
+	}
+
+	into := targets[0]
+	targetDropdown := styleDropdown(tview.NewDropDown().
+		SetLabel(fmt.Sprintf("Merge %s into", from.Name)).
+		SetOptions(targets, func(selectedOption string, _ int) {
+			into = selectedOption
+		}))
+	targetDropdown.SetCurrentOption(0)
+	targetDropdown.SetInputCapture(vimMotions)
+
+	closeForm := func() {
+		pages.RemovePage("mergeCategory")
+		tui.SetFocus(focus)
+	}
+
+	var form *tview.Form
+	form = styleForm(tview.NewForm().
+		AddFormItem(targetDropdown).
+		AddButton("Merge", func() {
+			merge := func() {
+				if err := mergeCategory(from.Type, from.Name, into); err != nil {
+					showErrorModal(fmt.Sprintf("failed to merge categories:\n\n%s", err), form)
+					log.Printf("failed to merge categories: %s", err)
+					return
+				}
+
+				closeForm()
+				if err := refresh(from.Type, into); err != nil {
+					showErrorModal(fmt.Sprintf("failed to list categories:\n\n%s", err), focus)
+				}
+			}
+
+			// only one of the budgets can be kept, so dropping the other one has to be confirmed
+			warning, err := mergeBudgetWarning(from.Type, from.Name, into)
+			if err != nil {
+				log.Printf("failed to check budgets of merged categories: %s", err)
+			}
+			if warning != "" {
+				confirmMergeDropsBudget(warning, form, merge)
+				return
+			}
+			merge()
+		}).
+		AddButton("Cancel", closeForm))
+
+	form.SetButtonsAlign(tview.AlignCenter)
+	form.SetBorder(true).
+		SetTitle(fmt.Sprintf("Merge %s Category - %s is removed", capitalize(from.Type), from.Name)).
+		SetTitleAlign(tview.AlignCenter)
+
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeForm()
+			return nil
+		}
+		return event
+	})
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).  // left spacer
+		AddItem(form, 70, 1, true). // same width as the category form
+		AddItem(nil, 0, 1, false))  // right spacer
+
+	// vertical centering
+	centeredModal := styleFlex(tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).  // top spacer
+		AddItem(modal, 7, 1, true). // enough to fit the dropdown and the buttons
+		AddItem(nil, 0, 1, false))  // bottom spacer
+
+	pages.AddPage("mergeCategory", centeredModal, true, true)
+	tui.SetFocus(form)
+}
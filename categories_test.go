@@ -0,0 +1,299 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// helper to add an expense in a category and return its id
+func addTestExpense(t *testing.T, category string) string {
+	t.Helper()
+	id, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: category, Date: "2025-03-14"})
+	if err != nil {
+		t.Fatalf("Failed to add %s expense: %v", category, err)
+	}
+	return id
+}
+
+func TestCategoriesSeededFromDefaults(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	food, err := getCategory("expense", "food")
+	if err != nil || food.Description != defaultTransactionCategories["expense"]["food"] || food.Archived {
+		t.Errorf("Expected the default food category with its description, got %+v (err %v)", food, err)
+	}
+
+	// categories that existing transactions use are kept even if they aren't defaults
+	if _, err := db.Exec(`DROP TABLE categories; DELETE FROM schema_version WHERE version >= 6`); err != nil {
+		t.Fatalf("Failed to reset categories: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO transactions (id, amount, type, category, year, month, date) VALUES ('a1b2c3d4', 50, 'expense', 'gym', 2025, 'march', '2025-03-01')`); err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+	if err := runMigrations(nil); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if !isAllowedCategory("expense", "gym") || !isAllowedCategory("expense", "food") {
+		t.Errorf("Expected the defaults and the categories in use after the migration")
+	}
+}
+
+func TestAddCategory(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "gym", Date: "2025-03-14"}); err == nil {
+		t.Errorf("Expected an unknown category to be rejected")
+	}
+
+	if err := addCategory("expense", " gym ", "memberships and classes"); err != nil {
+		t.Fatalf("Expected no error adding a category, got %v", err)
+	}
+	addTestExpense(t, "gym")
+
+	opts, _ := listOfAllowedCategories("expense")
+	if !slices.Contains(opts, "gym") {
+		t.Errorf("Expected the new category to be offered in the forms, got %v", opts)
+	}
+	if isAllowedCategory("income", "gym") {
+		t.Errorf("Expected the category to only exist for expenses")
+	}
+
+	if err := addCategory("expense", "Gym", ""); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("Expected ErrCategoryExists for a name that only differs in case, got %v", err)
+	}
+	if err := addCategory("expense", "  ", ""); err == nil {
+		t.Errorf("Expected an empty name to be rejected")
+	}
+	if err := addCategory("expense", "a very long category name that goes on", ""); err == nil {
+		t.Errorf("Expected a name over %d characters to be rejected", CategoryNameMaxLength)
+	}
+}
+
+// helper to give the forms a TUI to open on, it is never run
+func setupTestTui(t *testing.T) {
+	t.Helper()
+	originalTui, originalPages := tui, pages
+	t.Cleanup(func() { tui, pages = originalTui, originalPages })
+	tui = tview.NewApplication()
+	pages = tview.NewPages()
+	tui.SetRoot(pages, true)
+}
+
+// helper to find the form on the page in front, the forms are centered with nested flexes
+func frontTestForm(t *testing.T) *tview.Form {
+	t.Helper()
+	var find func(p tview.Primitive) *tview.Form
+	find = func(p tview.Primitive) *tview.Form {
+		switch item := p.(type) {
+		case *tview.Form:
+			return item
+		case *tview.Flex:
+			for i := 0; i < item.GetItemCount(); i++ {
+				if form := find(item.GetItem(i)); form != nil {
+					return form
+				}
+			}
+		}
+		return nil
+	}
+	_, front := pages.GetFrontPage()
+	form := find(front)
+	if form == nil {
+		t.Fatalf("Expected a form on the page in front")
+	}
+	return form
+}
+
+// helper to press a button of a form like a user would
+func pressTestFormButton(t *testing.T, form *tview.Form, label string) {
+	t.Helper()
+	i := form.GetButtonIndex(label)
+	if i < 0 {
+		t.Fatalf("Expected a %s button", label)
+	}
+	form.GetButton(i).InputHandler()(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), func(tview.Primitive) {})
+}
+
+func TestEditCategoryForm(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	setupTestTui(t)
+	incomeId, err := addTransaction(AddTransactionRequest{Type: "income", Amount: "10", Category: "transfers", Date: "2025-03-14"})
+	if err != nil {
+		t.Fatalf("Failed to add income: %v", err)
+	}
+	expenseId := addTestExpense(t, "transfers")
+
+	// transfers is an income and an expense category, editing the income one must leave the expense one alone
+	existing, err := getCategory("income", "transfers")
+	if err != nil {
+		t.Fatalf("Failed to get category: %v", err)
+	}
+	formCategory(&existing, tview.NewBox(), func(string, string) error { return nil })
+	form := frontTestForm(t)
+	form.GetFormItemByLabel("Name").(*tview.InputField).SetText("reimbursements")
+	pressTestFormButton(t, form, "Save")
+
+	if income, _ := GetTransaction("income", incomeId); income.Category != "reimbursements" {
+		t.Errorf("Expected the income category to be renamed, got %s", income.Category)
+	}
+	if expense, _ := GetTransaction("expense", expenseId); expense.Category != "transfers" {
+		t.Errorf("Expected the expense category to be left alone, got %s", expense.Category)
+	}
+}
+
+func TestEditCategoryRenamesTransactions(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	id := addTestExpense(t, "transfers")
+	incomeId, err := addTransaction(AddTransactionRequest{Type: "income", Amount: "10", Category: "transfers", Date: "2025-03-14"})
+	if err != nil {
+		t.Fatalf("Failed to add income: %v", err)
+	}
+
+	if err := editCategory("expense", "transfers", "payouts", "money sent to others"); err != nil {
+		t.Fatalf("Expected no error renaming, got %v", err)
+	}
+
+	tx, _ := GetTransaction("expense", id)
+	if tx.Category != "payouts" {
+		t.Errorf("Expected the transaction to be renamed with its category, got %s", tx.Category)
+	}
+	if income, _ := GetTransaction("income", incomeId); income.Category != "transfers" {
+		t.Errorf("Expected the income category of the same name to be left alone, got %s", income.Category)
+	}
+	if c, _ := getCategory("expense", "payouts"); c.Description != "money sent to others" {
+		t.Errorf("Expected the description to be updated, got %q", c.Description)
+	}
+	if isAllowedCategory("expense", "transfers") {
+		t.Errorf("Expected the old name to be gone")
+	}
+
+	if err := editCategory("expense", "payouts", "food", ""); !errors.Is(err, ErrCategoryExists) {
+		t.Errorf("Expected renaming onto another category to be rejected, got %v", err)
+	}
+	if err := editCategory("expense", "missing", "other", ""); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("Expected ErrCategoryNotFound, got %v", err)
+	}
+}
+
+func TestMergeCategory(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	id := addTestExpense(t, "cash")
+
+	if err := mergeCategory("expense", "cash", "food"); err != nil {
+		t.Fatalf("Expected no error merging, got %v", err)
+	}
+	if tx, _ := GetTransaction("expense", id); tx.Category != "food" {
+		t.Errorf("Expected the transaction to move to food, got %s", tx.Category)
+	}
+	if _, err := getCategory("expense", "cash"); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("Expected the merged category to be removed, got %v", err)
+	}
+
+	if err := mergeCategory("expense", "food", "food"); err == nil {
+		t.Errorf("Expected merging a category into itself to be rejected")
+	}
+	if usage, _ := categoryUsage("expense"); usage["food"] != 1 {
+		t.Errorf("Expected one food transaction, got %v", usage)
+	}
+}
+
+func TestArchiveCategory(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	petsId := addTestExpense(t, "pets")
+	foodId := addTestExpense(t, "food")
+
+	if err := setCategoryArchived("expense", "pets", true); err != nil {
+		t.Fatalf("Expected no error archiving, got %v", err)
+	}
+
+	opts, _ := listOfAllowedCategories("expense")
+	if slices.Contains(opts, "pets") || isAllowedCategory("expense", "pets") {
+		t.Errorf("Expected an archived category to not be offered for new transactions")
+	}
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "pets", Date: "2025-03-14"}); err == nil {
+		t.Errorf("Expected a new transaction in an archived category to be rejected")
+	}
+
+	// transactions that have the category keep it, others can't move into it
+	if err := handleUpdateTransaction(UpdateTransactionRequest{Type: "expense", Id: petsId, Amount: "20", Category: "pets"}); err != nil {
+		t.Errorf("Expected a transaction to keep its archived category, got %v", err)
+	}
+	if err := handleUpdateTransaction(UpdateTransactionRequest{Type: "expense", Id: foodId, Amount: "20", Category: "pets"}); err == nil {
+		t.Errorf("Expected moving a transaction into an archived category to be rejected")
+	}
+
+	if err := setCategoryArchived("expense", "pets", false); err != nil || !isAllowedCategory("expense", "pets") {
+		t.Errorf("Expected the category to be usable again after unarchiving (err %v)", err)
+	}
+}
+
+func TestCategoriesWithoutDb(t *testing.T) {
+	setupTestStorage(t, StorageMemory)
+
+	categories, err := listCategories("investment", true)
+	if err != nil || len(categories) != len(defaultTransactionCategories["investment"]) {
+		t.Errorf("Expected the default categories without a database, got %d (err %v)", len(categories), err)
+	}
+	if err := addCategory("expense", "gym", ""); err == nil {
+		t.Errorf("Expected categories to only be editable with sqlite storage")
+	}
+}
+
+func TestSubcategories(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	if err := addCategory("expense", "gym/classes", ""); !errors.Is(err, ErrCategoryNotFound) {
+		t.Errorf("Expected a subcategory without its parent to be rejected, got %v", err)
+	}
+	if err := addCategory("expense", "food/drinks/coffee", ""); err == nil {
+		t.Errorf("Expected subcategories more than one level deep to be rejected")
+	}
+	if err := addCategory("expense", "food/ ", ""); err == nil {
+		t.Errorf("Expected a subcategory without a name to be rejected")
+	}
+	if err := addCategory("expense", " food / groceries ", "supermarkets"); err != nil {
+		t.Fatalf("Expected no error adding a subcategory, got %v", err)
+	}
+
+	opts, _ := listOfAllowedCategories("expense")
+	if i := slices.Index(opts, "food/groceries"); i < 0 || opts[i-1] != "food" {
+		t.Errorf("Expected the subcategory to be offered right after its parent, got %v", opts)
+	}
+	id := addTestExpense(t, "food/groceries")
+
+	if err := mergeCategory("expense", "food", "bills"); err == nil {
+		t.Errorf("Expected merging a parent that still has subcategories to be rejected")
+	}
+	if err := editCategory("expense", "food", "bills/food", ""); err == nil {
+		t.Errorf("Expected a parent with subcategories to not become a subcategory")
+	}
+
+	// renaming the parent renames its subcategories and their transactions
+	if err := editCategory("expense", "food", "meals", ""); err != nil {
+		t.Fatalf("Expected no error renaming the parent, got %v", err)
+	}
+	if tx, _ := GetTransaction("expense", id); tx.Category != "meals/groceries" {
+		t.Errorf("Expected the transaction to follow its renamed parent, got %s", tx.Category)
+	}
+	if c, err := getCategory("expense", "meals/groceries"); err != nil || c.Description != "supermarkets" {
+		t.Errorf("Expected the subcategory to be renamed with its description, got %+v (err %v)", c, err)
+	}
+
+	// archiving the parent archives its subcategories
+	if err := setCategoryArchived("expense", "meals", true); err != nil {
+		t.Fatalf("Expected no error archiving, got %v", err)
+	}
+	if isAllowedCategory("expense", "meals/groceries") {
+		t.Errorf("Expected the subcategory to be archived with its parent")
+	}
+	if err := setCategoryArchived("expense", "meals/groceries", false); err == nil {
+		t.Errorf("Expected a subcategory to stay archived while its parent is")
+	}
+	if err := setCategoryArchived("expense", "meals", false); err != nil || !isAllowedCategory("expense", "meals/groceries") {
+		t.Errorf("Expected the subcategory to be usable again with its parent (err %v)", err)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// helper to get the label of a subcategory in a drill-down, transactions booked on the parent itself are shown separately
+func subcategoryLabel(parent, name string) string {
+	if name == parent {
+		return "(no subcategory)"
+	}
+	return strings.TrimPrefix(name, parent+CategorySeparator)
+}
+
+// helper to add up the totals of all categories
+func sumCategoryTotals(totals []CategoryTotal) float64 {
+	var sum float64
+	for _, t := range totals {
+		sum += t.Amount
+	}
+	return sum
+}
+
+// helper to write the parent category totals of a transaction type as indented lines, used in the year results
+func formatCategoryTotals(totals []CategoryTotal) string {
+	var b strings.Builder
+	for _, t := range totals {
+		b.WriteString(fmt.Sprintf("  %s: €%.2f\n", t.Name, t.Amount))
+	}
+	return b.String()
+}
+
+func generateCategoryTotalsFooter() string {
+	return Green + "ENTER" + Reset + ": expand/collapse subcategories  " +
+		Green + "j/k" + Reset + " or " + Green + "↑/↓" + Reset + ": navigate  " +
+		Yellow + "ESC" + Reset + "/" + Yellow + "q" + Reset + ": back"
+}
+
+// creates a TUI window with the totals per category of a month or year, subcategories are rolled up into their parent
+// and selecting a parent drills down into its subcategories
+func showCategoryTotals(month, year string, back func()) error {
+	totals, err := calculateCategoryTotals(month, year)
+	if err != nil {
+		return fmt.Errorf("unable to calculate category totals: %w", err)
+	}
+
+	period := year
+	if month != "" {
+		period = fmt.Sprintf("%s %s", capitalize(month), year)
+	}
+
+	root := tview.NewTreeNode(period).SetSelectable(false)
+	for _, txType := range []string{"income", "expense", "investment"} {
+		typeNode := tview.NewTreeNode(fmt.Sprintf("%s: €%.2f", capitalize(txType), sumCategoryTotals(totals[txType]))).
+			SetSelectable(false).
+			SetColor(theme.TitleColor)
+		root.AddChild(typeNode)
+
+		for _, parent := range totals[txType] {
+			label := fmt.Sprintf("%s: €%.2f", parent.Name, parent.Amount)
+			parentNode := tview.NewTreeNode(label).
+				SetReference(label).
+				SetColor(theme.FieldTextColor).
+				SetExpanded(false)
+			if len(parent.Children) > 0 {
+				parentNode.SetText("+ " + label)
+			}
+			for _, child := range parent.Children {
+				parentNode.AddChild(tview.NewTreeNode(fmt.Sprintf("%s: €%.2f", subcategoryLabel(parent.Name, child.Name), child.Amount)).
+					SetColor(theme.LabelColor))
+			}
+			typeNode.AddChild(parentNode)
+		}
+	}
+
+	tree := styleTreeView(tview.NewTreeView().
+		SetRoot(root).
+		SetTopLevel(1))
+	tree.SetBorder(true).
+		SetTitle(fmt.Sprintf("Totals by Category - %s", period)).
+		SetTitleAlign(tview.AlignCenter)
+
+	// select the first category so the tree can be navigated right away
+	for _, typeNode := range root.GetChildren() {
+		if children := typeNode.GetChildren(); len(children) > 0 {
+			tree.SetCurrentNode(children[0])
+			break
+		}
+	}
+
+	// drill down into the subcategories of a parent
+	tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		label, _ := node.GetReference().(string)
+		if len(node.GetChildren()) == 0 {
+			return
+		}
+		node.SetExpanded(!node.IsExpanded())
+		if node.IsExpanded() {
+			node.SetText("- " + label)
+		} else {
+			node.SetText("+ " + label)
+		}
+	})
+
+	closeTotals := func() {
+		pages.RemovePage("categoryTotals")
+		back()
+	}
+
+	tree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if ev := exitShortcuts(event); ev == nil {
+			closeTotals()
+			return nil // key event consumed
+		}
+		// handle j/k events to navigate up or down
+		return vimMotions(event)
+	})
+
+	// navigation help
+	frame := tview.NewFrame(tree).
+		AddText(generateCategoryTotalsFooter(), false, tview.AlignCenter, theme.FieldTextColor)
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).   // left spacer
+		AddItem(frame, 70, 1, true). // wide enough for a category and its total
+		AddItem(nil, 0, 1, false))   // right spacer
+
+	pages.AddPage("categoryTotals", modal, true, true)
+	tui.SetFocus(tree)
+	return nil
+}
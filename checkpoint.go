@@ -0,0 +1,143 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// re-encrypts the open database into EncryptedDBFile in the background during a session, so a crash or a lost ssh session
+// doesn't lose the changes made since login - a checkpoint is written after every n mutations and every interval
+type checkpointer struct {
+	config   *Config
+	every    int           // mutations between checkpoints, 0 disables the mutation trigger
+	interval time.Duration // time between checkpoints, 0 disables the timer
+
+	mu      sync.Mutex
+	pending int // mutations since the last checkpoint
+
+	trigger chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+var (
+	checkpointerMu     sync.Mutex
+	activeCheckpointer *checkpointer
+)
+
+// starts background checkpoints for the current session, does nothing if they are disabled or already running
+func startCheckpoints(config *Config) {
+	if config.StorageType != StorageSQLite || readOnlySession || (config.CheckpointEvery <= 0 && config.CheckpointInterval <= 0) {
+		return
+	}
+
+	checkpointerMu.Lock()
+	defer checkpointerMu.Unlock()
+	if activeCheckpointer != nil {
+		return
+	}
+
+	c := &checkpointer{
+		config:   config,
+		every:    config.CheckpointEvery,
+		interval: config.CheckpointInterval,
+		trigger:  make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	activeCheckpointer = c
+	go c.run()
+}
+
+// stops background checkpoints and waits for a running checkpoint to finish, the final encryption on exit happens afterwards
+// reports if checkpoints were running, so a caller that only pauses them knows whether to start them again
+func stopCheckpoints() bool {
+	checkpointerMu.Lock()
+	c := activeCheckpointer
+	activeCheckpointer = nil
+	checkpointerMu.Unlock()
+
+	if c == nil {
+		return false
+	}
+	close(c.stop)
+	<-c.done
+	return true
+}
+
+// counts a change to the database, called by the storage functions after every successful mutation
+func recordDbMutation() {
+	checkpointerMu.Lock()
+	c := activeCheckpointer
+	checkpointerMu.Unlock()
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.pending++
+	due := c.every > 0 && c.pending >= c.every
+	c.mu.Unlock()
+
+	if due {
+		// never block the ui goroutine, a checkpoint that is already queued covers this mutation as well
+		select {
+		case c.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *checkpointer) run() {
+	defer close(c.done)
+
+	var tick <-chan time.Time
+	if c.interval > 0 {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-tick:
+			c.checkpoint()
+		case <-c.trigger:
+			c.checkpoint()
+		}
+	}
+}
+
+// writes a checkpoint if anything changed since the last one, a failed checkpoint keeps its mutations pending for the next attempt
+func (c *checkpointer) checkpoint() {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = 0
+	c.mu.Unlock()
+
+	if pending == 0 {
+		return
+	}
+
+	if err := checkpointDb(); err != nil {
+		log.Printf("checkpoint failed: %s", err)
+		c.mu.Lock()
+		c.pending += pending
+		c.mu.Unlock()
+		return
+	}
+	log.Printf("checkpoint written after %d changes", pending)
+}
+
+// encrypts a consistent snapshot of the open database into the encrypted database file
+// serializing goes through the connection pool, so it waits for statements of the ui goroutine instead of racing them
+func checkpointDb() error {
+	image, err := serializeDb()
+	if err != nil {
+		return err
+	}
+	return encryptDatabaseImage(image)
+}
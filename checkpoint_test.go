@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// helper to open an in-memory session database with checkpoints enabled
+func setupCheckpointSession(t *testing.T, every int, interval time.Duration) *Config {
+	t.Helper()
+	setUserPassword("testpassword")
+
+	dir := t.TempDir()
+	config := &Config{
+		StorageType:        StorageSQLite,
+		UnencryptedDbFile:  filepath.Join(dir, "transactions.db"),
+		EncryptedDBFile:    filepath.Join(dir, "transactions.enc"),
+		SaltFile:           filepath.Join(dir, "transactions.salt"),
+		InMemoryDb:         true,
+		CheckpointEvery:    every,
+		CheckpointInterval: interval,
+	}
+	original, originalDb := globalConfig, db
+	SetGlobalConfig(config)
+	t.Cleanup(func() {
+		stopCheckpoints()
+		closeDb()
+		clearUserPassword()
+		SetGlobalConfig(original)
+		db = originalDb
+	})
+
+	if err := initSessionDb(config, nil); err != nil {
+		t.Fatalf("Failed to open session db: %v", err)
+	}
+	startCheckpoints(config)
+	return config
+}
+
+// helper to wait until the encrypted database contains the expected number of transactions
+func waitForCheckpoint(t *testing.T, expected int) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if image, err := decryptDatabaseImage(); err == nil && image != nil {
+			if count := countTransactionsInImage(t, image); count == expected {
+				return
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Expected a checkpoint with %d transactions", expected)
+}
+
+// helper to count the transactions of a decrypted database image without touching the session db
+func countTransactionsInImage(t *testing.T, image []byte) int {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "checkpoint.db")
+	if err := os.WriteFile(path, image, 0600); err != nil {
+		t.Fatalf("Failed to write checkpoint copy: %v", err)
+	}
+
+	sessionDb := db
+	defer func() { db = sessionDb }()
+	db = nil
+	if err := initDb(path); err != nil {
+		t.Fatalf("Failed to open checkpoint copy: %v", err)
+	}
+	defer closeDb()
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transactions`).Scan(&count); err != nil {
+		t.Fatalf("Failed to count transactions in checkpoint: %v", err)
+	}
+	return count
+}
+
+func TestCheckpointAfterMutations(t *testing.T) {
+	config := setupCheckpointSession(t, 2, 0)
+
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "food", Date: "2025-03-14"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := os.Stat(config.EncryptedDBFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no checkpoint before %d changes", config.CheckpointEvery)
+	}
+
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "12", Category: "food", Date: "2025-03-15"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	waitForCheckpoint(t, 2)
+
+	// nothing but the encrypted database and the salt is written
+	entries, _ := os.ReadDir(filepath.Dir(config.EncryptedDBFile))
+	for _, e := range entries {
+		if e.Name() != "transactions.enc" && e.Name() != "transactions.salt" {
+			t.Errorf("Unexpected file %s after checkpoint", e.Name())
+		}
+	}
+}
+
+func TestCheckpointOnInterval(t *testing.T) {
+	setupCheckpointSession(t, 0, 20*time.Millisecond)
+
+	if _, err := addTransaction(AddTransactionRequest{Type: "income", Amount: "1000", Category: "salary", Date: "2025-03-01"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	waitForCheckpoint(t, 1)
+}
+
+func TestStopCheckpoints(t *testing.T) {
+	// stopping without a running checkpointer is a no-op
+	stopCheckpoints()
+
+	config := setupCheckpointSession(t, 1, 0)
+	stopCheckpoints()
+
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "food", Date: "2025-03-14"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := os.Stat(config.EncryptedDBFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no checkpoint after checkpoints were stopped")
+	}
+}
+
+func TestWriteFileAtomically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.enc")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if err := writeFileAtomically(path, []byte("new"), 0600); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "new" {
+		t.Errorf("Expected new contents, got %q", data)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected 0600 permissions, got %v", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Expected temporary files to be cleaned up, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// a headless subcommand - setup registers the command specific flags and returns the function that runs the command once flags are parsed and the db is unlocked
+type cliCommand struct {
+	description string
+	setup       func(fs *flag.FlagSet) func(out io.Writer) error
+}
+
+var cliCommands = map[string]cliCommand{
+	"add":            {"add a new transaction", cliAddSetup},
+	"list":           {"list transactions, optionally filtered by month, year and type", cliListSetup},
+	"update":         {"update an existing transaction by id", cliUpdateSetup},
+	"delete":         {"delete an existing transaction by id", cliDeleteSetup},
+	"import":         {"import transactions from a bank statement, shows a preview unless --commit is passed", cliImportSetup},
+	"import-review":  {"list imported transactions flagged for review because their category couldn't be mapped", cliImportReviewSetup},
+	"import-profile": {"save, list or delete named csv column mapping profiles", cliImportProfileSetup},
+	"report":         {"print monthly and yearly income, expenses, investments and savings as json or csv", cliReportSetup},
+	"export":         {"export transactions as a ledger, hledger or beancount journal", cliExportSetup},
+	"import-legacy":  {"copy transactions from a transactions.json in the old json layout, shows a preview unless --commit is passed", cliImportLegacySetup},
+	"info":           {"show the storage backend, number of transactions, their date range and the schema version", cliInfoSetup},
+}
+
+// non-interactive source for the password - either read from stdin or from an already open file descriptor
+type passwordSource struct {
+	fromStdin bool
+	fd        int
+}
+
+// registers the shared password flags on a subcommand
+func registerPasswordFlags(fs *flag.FlagSet) *passwordSource {
+	src := &passwordSource{fd: -1}
+	fs.BoolVar(&src.fromStdin, "password-stdin", false, "read the password from the first line of stdin")
+	fs.IntVar(&src.fd, "password-fd", -1, "read the password from the first line of an open file descriptor")
+	return src
+}
+
+// reports if a password source was passed on the command line, it is optional when a keyfile is used
+func (src *passwordSource) provided() bool {
+	return src.fromStdin || src.fd >= 0
+}
+
+// reads the password from whichever source was provided on the command line
+func (src *passwordSource) read(stdin io.Reader) (string, error) {
+	var r io.Reader
+	switch {
+	case src.fromStdin && src.fd >= 0:
+		return "", fmt.Errorf("use only one of --password-stdin or --password-fd")
+	case src.fromStdin:
+		r = stdin
+	case src.fd >= 0:
+		f := os.NewFile(uintptr(src.fd), "password-fd")
+		if f == nil {
+			return "", fmt.Errorf("invalid password file descriptor %d", src.fd)
+		}
+		defer f.Close()
+		r = f
+	default:
+		return "", fmt.Errorf("a password is required, provide it with --password-stdin or --password-fd")
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("password cannot be empty")
+	}
+	return password, nil
+}
+
+// entry point for headless mode, returns the exit code for the process
+func runCli(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		printCliUsage(stdout)
+		return 0
+	}
+
+	cmd, ok := cliCommands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+		printCliUsage(stderr)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	pwSrc := registerPasswordFlags(fs)
+	readOnly := fs.Bool("read-only", false, "open the database read-only without taking the lock, e.g. while the TUI is running")
+	keyFile := fs.String("keyfile", globalConfig.KeyFile, "path of the keyfile for databases that are unlocked with a keyfile, alone or together with the password")
+	run := cmd.setup(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	// only the sqlite database is encrypted, the json storage types don't need a password
+	if globalConfig.StorageType == StorageSQLite {
+		// a database that is unlocked with only a keyfile needs no password
+		var password string
+		if *keyFile == "" || pwSrc.provided() {
+			var err error
+			if password, err = pwSrc.read(stdin); err != nil {
+				fmt.Fprintf(stderr, "%s: %s\n", name, err)
+				return 2
+			}
+		}
+
+		unlock := unlockHeadless
+		if *readOnly {
+			unlock = unlockHeadlessReadOnly
+		}
+		if err := unlock(password, *keyFile); err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", name, err)
+			return 1
+		}
+		defer closeAndEncryptDb(globalConfig)
+	}
+
+	if err := run(stdout); err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", name, err)
+		return 1
+	}
+
+	return 0
+}
+
+func printCliUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: expense-tracking [command] [flags]")
+	fmt.Fprintln(w, "\nwithout a command the interactive TUI is started\n\ncommands:")
+
+	var names []string
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, cliCommands[name].description)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nrun 'expense-tracking [command] -h' to see the flags of a command")
+}
+
+// helper to refuse headless commands before the first run, a new database only gets its password from the TUI
+func requireEncryptedDb(config *Config) error {
+	if _, err := os.Stat(config.EncryptedDBFile); os.IsNotExist(err) {
+		return fmt.Errorf("no encrypted database found at %s, start the TUI once to set a password and create it", config.EncryptedDBFile)
+	} else if err != nil {
+		return fmt.Errorf("failed to check for the encrypted database: %w", err)
+	}
+	return nil
+}
+
+// headless equivalent of the login form - decrypts the database with the provided password and/or keyfile and opens the db connection
+func unlockHeadless(password, keyFilePath string) error {
+	if err := requireEncryptedDb(globalConfig); err != nil {
+		return err
+	}
+
+	setUserPassword(password)
+	if err := setUserKeyFile(keyFilePath); err != nil {
+		clearUserPassword()
+		return err
+	}
+
+	// another instance decrypts to the same files and would overwrite whatever this one writes back
+	if _, err := acquireInstanceLock(globalConfig); err != nil {
+		clearUserPassword()
+		if errors.Is(err, ErrInstanceLocked) {
+			return fmt.Errorf("%w, use --read-only to read the database anyway", err)
+		}
+		return err
+	}
+
+	// never overwrite a leftover unencrypted database from a run that died, the TUI asks what to do with it
+	leftover, err := detectLeftoverDb(globalConfig)
+	if err != nil {
+		clearUserPassword() // remove pass from memory on error
+		releaseInstanceLock()
+		if errors.Is(err, ErrWrongPassword) || errors.Is(err, ErrWrongUnlockMode) {
+			return err
+		}
+		return fmt.Errorf("failed to check for a leftover database: %w", err)
+	}
+	if leftover != nil {
+		clearUserPassword()
+		releaseInstanceLock()
+		return fmt.Errorf("found an unencrypted database from %s left behind by a previous run that differs from the encrypted one, start the TUI to recover, discard or keep both", leftover.plaintextModTime.Format("2006-01-02 15:04"))
+	}
+
+	image, err := decryptSessionDb(globalConfig)
+	if err != nil {
+		clearUserPassword() // remove pass from memory on error
+		releaseInstanceLock()
+		if errors.Is(err, ErrWrongPassword) || errors.Is(err, ErrWrongUnlockMode) {
+			return err
+		}
+		return fmt.Errorf("decryption failed: %w", err)
+	}
+
+	if err := initSessionDb(globalConfig, image); err != nil {
+		discardDecryptedDb(globalConfig)
+		clearUserPassword() // remove pass from memory on error
+		releaseInstanceLock()
+		return fmt.Errorf("failed to initialize DB: %w", err)
+	}
+
+	return nil
+}
+
+// headless unlock that leaves the lock to whichever instance holds it, the database is decrypted into memory and can't be changed
+func unlockHeadlessReadOnly(password, keyFilePath string) error {
+	if err := requireEncryptedDb(globalConfig); err != nil {
+		return err
+	}
+
+	setUserPassword(password)
+	if err := setUserKeyFile(keyFilePath); err != nil {
+		clearUserPassword()
+		return err
+	}
+
+	if err := initReadOnlySessionDb(); err != nil {
+		clearUserPassword() // remove pass from memory on error
+		if errors.Is(err, ErrWrongPassword) || errors.Is(err, ErrWrongUnlockMode) {
+			return err
+		}
+		return fmt.Errorf("failed to open database read-only: %w", err)
+	}
+
+	return nil
+}
+
+// helper to validate and normalize the month and year passed on the command line, empty values default to the current month and year
+func normalizeCliPeriod(month, year string) (string, string, error) {
+	now := time.Now()
+	if month == "" {
+		month = strings.ToLower(now.Month().String())
+	}
+	if year == "" {
+		year = strconv.Itoa(now.Year())
+	}
+
+	month = strings.ToLower(month)
+	if _, ok := monthOrder[month]; !ok {
+		return "", "", fmt.Errorf("invalid month %q", month)
+	}
+	if _, err := strconv.Atoi(year); err != nil {
+		return "", "", fmt.Errorf("invalid year %q", year)
+	}
+
+	return month, year, nil
+}
+
+func cliAddSetup(fs *flag.FlagSet) func(out io.Writer) error {
+	txType := fs.String("type", "expense", "transaction type - income, expense or investment")
+	amount := fs.String("amount", "", "transaction amount, e.g. 12.50")
+	category := fs.String("category", "", "transaction category")
+	description := fs.String("description", "", "transaction description")
+	month := fs.String("month", "", "month of the transaction (default current month)")
+	year := fs.String("year", "", "year of the transaction (default current year)")
+	date := fs.String("date", "", "date of the transaction as YYYY-MM-DD, replaces --month and --year (default today or the first of the month)")
+	tags := fs.String("tags", "", "comma separated tags, e.g. vacation-2025,reimbursable")
+
+	return func(out io.Writer) error {
+		if *amount == "" || *category == "" {
+			return fmt.Errorf("--amount and --category are required")
+		}
+
+		var m, y string
+		if *date != "" {
+			if *month != "" || *year != "" {
+				return fmt.Errorf("--date can't be combined with --month or --year")
+			}
+			d, err := parseTransactionDate(*date)
+			if err != nil {
+				return err
+			}
+			m, y = periodOfDate(d)
+		} else {
+			var err error
+			if m, y, err = normalizeCliPeriod(*month, *year); err != nil {
+				return err
+			}
+		}
+
+		if len(*description) > DescriptionMaxCharLength {
+			return fmt.Errorf("description should have a maximum of %d chars", DescriptionMaxCharLength)
+		}
+
+		addReq := AddTransactionRequest{
+			Type:        *txType,
+			Amount:      *amount,
+			Category:    *category,
+			Description: *description,
+			Month:       m,
+			Year:        y,
+			Date:        *date,
+			Tags:        *tags,
+		}
+		// worked out before adding, afterwards the expense would already be part of what was spent
+		warning := expenseBudgetWarning(addReq)
+		if err := handleAddTransaction(addReq); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "added %s of %s (%s) to %s %s\n", addReq.Type, addReq.Amount, addReq.Category, m, y)
+		if warning != "" {
+			fmt.Fprintf(out, "warning: %s\n", warning)
+		}
+		return nil
+	}
+}
+
+func cliListSetup(fs *flag.FlagSet) func(out io.Writer) error {
+	month := fs.String("month", "", "only list transactions for this month")
+	year := fs.String("year", "", "only list transactions for this year")
+	txType := fs.String("type", "", "only list transactions of this type")
+	tag := fs.String("tag", "", "only list transactions with this tag")
+
+	return func(out io.Writer) error {
+		filterType := ""
+		if *txType != "" {
+			t, err := normalizeTransactionType(*txType)
+			if err != nil {
+				return err
+			}
+			filterType = t
+		}
+		filterMonth := strings.ToLower(*month)
+
+		transactions, err := LoadTransactions()
+		if err != nil {
+			return fmt.Errorf("unable to load transactions: %w", err)
+		}
+
+		var years []string
+		for y := range transactions {
+			if *year == "" || y == *year {
+				years = append(years, y)
+			}
+		}
+		sort.Strings(years)
+
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tDATE\tYEAR\tMONTH\tTYPE\tAMOUNT\tCATEGORY\tDESCRIPTION\tTAGS")
+		for _, y := range years {
+			var months []string
+			for m := range transactions[y] {
+				if filterMonth == "" || m == filterMonth {
+					months = append(months, m)
+				}
+			}
+			sort.Slice(months, func(i, j int) bool { return monthOrder[months[i]] < monthOrder[months[j]] })
+
+			for _, m := range months {
+				for _, t := range []string{"income", "expense", "investment"} {
+					if filterType != "" && t != filterType {
+						continue
+					}
+					for _, tx := range sortTransactionsByDate(transactions[y][m][t]) {
+						if *tag != "" && !hasTag(tx, *tag) {
+							continue
+						}
+						fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.2f\t%s\t%s\t%s\n", tx.Id, tx.Date.Format(TransactionDateFormat), y, m, t, tx.Amount, tx.Category, tx.Description, strings.Join(tx.Tags, TagSeparator))
+					}
+				}
+			}
+		}
+
+		return tw.Flush()
+	}
+}
+
+// helper to find the type of a transaction from its id, a --type that was passed has to match it
+func cliTransactionType(id, txType string) (string, error) {
+	storedType, err := getTransactionTypeById(id)
+	if err != nil {
+		return "", err
+	}
+	if txType == "" {
+		return storedType, nil
+	}
+
+	normalized, err := normalizeTransactionType(txType)
+	if err != nil {
+		return "", err
+	}
+	if normalized != storedType {
+		return "", fmt.Errorf("transaction %s is an %s, not an %s, leave out --type or pass --type %s", id, storedType, normalized, storedType)
+	}
+	return storedType, nil
+}
+
+func cliUpdateSetup(fs *flag.FlagSet) func(out io.Writer) error {
+	id := fs.String("id", "", "id of the transaction to update")
+	txType := fs.String("type", "", "type of the transaction to update, checked against the stored one (default looked up by id)")
+	amount := fs.String("amount", "", "new amount (default unchanged)")
+	category := fs.String("category", "", "new category (default unchanged)")
+	description := fs.String("description", "", "new description (default unchanged)")
+	date := fs.String("date", "", "new date as YYYY-MM-DD, moves the transaction to another month if needed (default unchanged)")
+	tags := fs.String("tags", "", "new comma separated tags, an empty value removes them (default unchanged)")
+
+	return func(out io.Writer) error {
+		if *id == "" {
+			return fmt.Errorf("--id is required")
+		}
+		// the id alone identifies the transaction
+		storedType, err := cliTransactionType(*id, *txType)
+		if err != nil {
+			return err
+		}
+
+		// only the flags that were explicitly passed are changed, everything else keeps its current value
+		tx, err := getTransactionById(*id)
+		if err != nil {
+			return err
+		}
+
+		updateReq := UpdateTransactionRequest{
+			Type:        storedType,
+			Id:          *id,
+			Amount:      strconv.FormatFloat(tx.Amount, 'f', 2, 64),
+			Category:    tx.Category,
+			Description: tx.Description,
+			Tags:        strings.Join(tx.Tags, TagSeparator),
+		}
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "amount":
+				updateReq.Amount = *amount
+			case "category":
+				updateReq.Category = *category
+			case "description":
+				updateReq.Description = *description
+			case "date":
+				updateReq.Date = *date
+			case "tags":
+				updateReq.Tags = *tags
+			}
+		})
+
+		if len(updateReq.Description) > DescriptionMaxCharLength {
+			return fmt.Errorf("description should have a maximum of %d chars", DescriptionMaxCharLength)
+		}
+
+		if err := handleUpdateTransaction(updateReq); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "updated transaction %s\n", *id)
+		return nil
+	}
+}
+
+func cliDeleteSetup(fs *flag.FlagSet) func(out io.Writer) error {
+	id := fs.String("id", "", "id of the transaction to delete")
+	txType := fs.String("type", "", "type of the transaction to delete, checked against the stored one (default looked up by id)")
+
+	return func(out io.Writer) error {
+		if *id == "" {
+			return fmt.Errorf("--id is required")
+		}
+		// the id alone identifies the transaction
+		storedType, err := cliTransactionType(*id, *txType)
+		if err != nil {
+			return err
+		}
+
+		if err := handleDeleteTransaction(storedType, *id); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "deleted transaction %s\n", *id)
+		return nil
+	}
+}
+
+func cliImportLegacySetup(fs *flag.FlagSet) func(out io.Writer) error {
+	file := fs.String("file", "", "path to the transactions.json to copy transactions from")
+	commit := fs.Bool("commit", false, "add the transactions, without it only a preview is shown")
+
+	return func(out io.Writer) error {
+		if *file == "" {
+			return fmt.Errorf("--file is required")
+		}
+
+		imported, skipped, err := importLegacyJsonFile(*file, *commit)
+		if err != nil {
+			return err
+		}
+
+		if !*commit {
+			fmt.Fprintf(out, "%d transactions will be imported, %d skipped because their id is already in use\nrun again with --commit to import them\n", imported, skipped)
+			return nil
+		}
+		fmt.Fprintf(out, "imported %d transactions, %d skipped because their id is already in use\n", imported, skipped)
+		return nil
+	}
+}
+
+func cliInfoSetup(fs *flag.FlagSet) func(out io.Writer) error {
+	return func(out io.Writer) error {
+		store, err := currentStore()
+		if err != nil {
+			return err
+		}
+		meta, err := store.Metadata()
+		if err != nil {
+			return fmt.Errorf("failed to read storage metadata: %w", err)
+		}
+
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "storage\t%s\n", meta.Backend)
+		if meta.ReadOnly {
+			fmt.Fprintln(tw, "read-only\tyes")
+		}
+		if meta.SchemaVersion > 0 {
+			fmt.Fprintf(tw, "schema version\t%d\n", meta.SchemaVersion)
+		}
+		fmt.Fprintf(tw, "transactions\t%d\n", meta.TransactionCount)
+		if meta.TransactionCount > 0 {
+			fmt.Fprintf(tw, "first\t%s\n", meta.FirstDate.Format(TransactionDateFormat))
+			fmt.Fprintf(tw, "last\t%s\n", meta.LastDate.Format(TransactionDateFormat))
+		}
+		return tw.Flush()
+	}
+}
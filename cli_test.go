@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runs a subcommand against the test storage, skipping the password and unlock steps
+func runTestCliCommand(t *testing.T, setup func(fs *flag.FlagSet) func(out io.Writer) error, args ...string) (string, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	run := setup(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Failed to parse flags %v: %v", args, err)
+	}
+
+	var out bytes.Buffer
+	err := run(&out)
+	return out.String(), err
+}
+
+func TestPasswordSourceRead(t *testing.T) {
+	cases := []struct {
+		name          string
+		src           passwordSource
+		stdin         string
+		expected      string
+		expectedError bool
+	}{
+		{"password from stdin", passwordSource{fromStdin: true, fd: -1}, "secret\n", "secret", false},
+		{"password from stdin with CRLF", passwordSource{fromStdin: true, fd: -1}, "secret\r\n", "secret", false},
+		{"password from stdin without newline", passwordSource{fromStdin: true, fd: -1}, "secret", "secret", false},
+		{"only first line is used", passwordSource{fromStdin: true, fd: -1}, "secret\nother\n", "secret", false},
+		{"empty password", passwordSource{fromStdin: true, fd: -1}, "\n", "", true},
+		{"no password source", passwordSource{fd: -1}, "secret\n", "", true},
+		{"both password sources", passwordSource{fromStdin: true, fd: 3}, "secret\n", "", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			password, err := c.src.read(strings.NewReader(c.stdin))
+			if (err != nil) != c.expectedError {
+				t.Fatalf("read() error = %v; expected error = %v", err, c.expectedError)
+			}
+			if password != c.expected {
+				t.Errorf("Expected password %q, got %q", c.expected, password)
+			}
+		})
+	}
+}
+
+func TestNormalizeCliPeriod(t *testing.T) {
+	month, year, err := normalizeCliPeriod("March", "2024")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if month != "march" || year != "2024" {
+		t.Errorf("Expected march 2024, got %s %s", month, year)
+	}
+
+	if _, _, err := normalizeCliPeriod("smarch", "2024"); err == nil {
+		t.Errorf("Expected error for invalid month")
+	}
+	if _, _, err := normalizeCliPeriod("march", "twenty"); err == nil {
+		t.Errorf("Expected error for invalid year")
+	}
+
+	month, year, err = normalizeCliPeriod("", "")
+	if err != nil || month == "" || year == "" {
+		t.Errorf("Expected current month and year as defaults, got %q %q (err %v)", month, year, err)
+	}
+}
+
+func TestRunCliUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := runCli([]string{"unknown"}, strings.NewReader(""), &stdout, &stderr); code == 0 {
+		t.Errorf("Expected non-zero exit code for unknown command")
+	}
+	if !strings.Contains(stderr.String(), "unknown command") {
+		t.Errorf("Expected unknown command message, got %q", stderr.String())
+	}
+
+	stdout.Reset()
+	if code := runCli([]string{"help"}, strings.NewReader(""), &stdout, &stderr); code != 0 {
+		t.Errorf("Expected zero exit code for help, got %d", code)
+	}
+	for _, name := range []string{"add", "list", "update", "delete"} {
+		if !strings.Contains(stdout.String(), name) {
+			t.Errorf("Expected usage to mention %s, got %q", name, stdout.String())
+		}
+	}
+}
+
+func TestCliAddListUpdateDelete(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	if _, err := runTestCliCommand(t, cliAddSetup, "--type", "expense", "--amount", "12.50", "--category", "food", "--description", "lunch", "--month", "March", "--year", "2024"); err != nil {
+		t.Fatalf("Expected no error adding transaction, got %v", err)
+	}
+
+	if _, err := runTestCliCommand(t, cliAddSetup, "--type", "expense", "--category", "food"); err == nil {
+		t.Errorf("Expected error when amount is missing")
+	}
+
+	if _, err := runTestCliCommand(t, cliAddSetup, "--amount", "1", "--category", "madeUpCategory"); err == nil {
+		t.Errorf("Expected error for invalid category")
+	}
+
+	out, err := runTestCliCommand(t, cliListSetup, "--year", "2024", "--month", "march")
+	if err != nil {
+		t.Fatalf("Expected no error listing transactions, got %v", err)
+	}
+	if !strings.Contains(out, "lunch") || !strings.Contains(out, "12.50") {
+		t.Fatalf("Expected listed transaction, got %q", out)
+	}
+
+	transactions, err := loadTransactionsFromTestStorage()
+	if err != nil {
+		t.Fatalf("Failed to load transactions: %v", err)
+	}
+	expenses := transactions["2024"]["march"]["expense"]
+	if len(expenses) != 1 {
+		t.Fatalf("Expected one expense, got %v", expenses)
+	}
+	id := expenses[0].Id
+
+	// only the description is changed, amount and category are kept
+	if _, err := runTestCliCommand(t, cliUpdateSetup, "--id", id, "--type", "expense", "--description", "dinner"); err != nil {
+		t.Fatalf("Expected no error updating transaction, got %v", err)
+	}
+	tx, err := getTransactionById(id)
+	if err != nil {
+		t.Fatalf("Failed to get transaction: %v", err)
+	}
+	if tx.Description != "dinner" || tx.Amount != 12.50 || tx.Category != "food" {
+		t.Errorf("Expected only the description to change, got %+v", tx)
+	}
+
+	// the type is looked up by id, a --type that doesn't match is rejected
+	if _, err := runTestCliCommand(t, cliUpdateSetup, "--id", id, "--amount", "15"); err != nil {
+		t.Fatalf("Expected no error updating without --type, got %v", err)
+	}
+	if _, err := runTestCliCommand(t, cliDeleteSetup, "--id", id, "--type", "income"); err == nil || !strings.Contains(err.Error(), "is an expense") {
+		t.Errorf("Expected a mismatched --type to be rejected, got %v", err)
+	}
+	if _, err := runTestCliCommand(t, cliDeleteSetup); err == nil {
+		t.Errorf("Expected error when id is missing")
+	}
+	if _, err := runTestCliCommand(t, cliDeleteSetup, "--id", id); err != nil {
+		t.Fatalf("Expected no error deleting transaction, got %v", err)
+	}
+
+	out, err = runTestCliCommand(t, cliListSetup, "--type", "expense")
+	if err != nil {
+		t.Fatalf("Expected no error listing transactions, got %v", err)
+	}
+	if strings.Contains(out, id) {
+		t.Errorf("Expected deleted transaction not to be listed, got %q", out)
+	}
+}
+
+func TestCliTags(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	if _, err := runTestCliCommand(t, cliAddSetup, "--amount", "300", "--category", "travel", "--date", "2025-07-14", "--tags", "vacation-2025,reimbursable"); err != nil {
+		t.Fatalf("Expected no error adding a tagged transaction, got %v", err)
+	}
+	if _, err := runTestCliCommand(t, cliAddSetup, "--amount", "20", "--category", "food", "--date", "2025-07-15", "--description", "lunch"); err != nil {
+		t.Fatalf("Expected no error adding transaction, got %v", err)
+	}
+
+	out, err := runTestCliCommand(t, cliListSetup, "--tag", "vacation-2025")
+	if err != nil {
+		t.Fatalf("Expected no error listing transactions, got %v", err)
+	}
+	if !strings.Contains(out, "reimbursable,vacation-2025") || strings.Contains(out, "lunch") {
+		t.Fatalf("Expected only the tagged transaction with its tags, got %q", out)
+	}
+
+	id := strings.Fields(strings.Split(out, "\n")[1])[0]
+	// tags are kept unless --tags is passed, an empty value removes them
+	if _, err := runTestCliCommand(t, cliUpdateSetup, "--id", id, "--type", "expense", "--amount", "320"); err != nil {
+		t.Fatalf("Expected no error updating transaction, got %v", err)
+	}
+	if tx, _ := getTransactionById(id); len(tx.Tags) != 2 {
+		t.Errorf("Expected the tags to be kept, got %v", tx.Tags)
+	}
+	if _, err := runTestCliCommand(t, cliUpdateSetup, "--id", id, "--type", "expense", "--tags", ""); err != nil {
+		t.Fatalf("Expected no error updating transaction, got %v", err)
+	}
+	if tx, _ := getTransactionById(id); len(tx.Tags) != 0 {
+		t.Errorf("Expected the tags to be removed, got %v", tx.Tags)
+	}
+
+	if _, err := runTestCliCommand(t, cliReportSetup, "--tag", "a,b"); err == nil {
+		t.Errorf("Expected the report to take a single tag")
+	}
+}
+
+func TestUnlockHeadlessWithoutDb(t *testing.T) {
+	original := globalConfig
+	t.Cleanup(func() { globalConfig = original })
+	dir := t.TempDir()
+	globalConfig = &Config{
+		StorageType:       StorageSQLite,
+		UnencryptedDbFile: filepath.Join(dir, "transactions.db"),
+		EncryptedDBFile:   filepath.Join(dir, "transactions.db.enc"),
+	}
+
+	// the first run has to go through the TUI, which asks for the password of the new database
+	for _, unlock := range []func(password, keyFilePath string) error{unlockHeadless, unlockHeadlessReadOnly} {
+		if err := unlock("testpassword", ""); err == nil || !strings.Contains(err.Error(), "start the TUI") {
+			t.Errorf("Expected headless unlock to refuse a missing database, got %v", err)
+		}
+	}
+	if _, err := os.Stat(globalConfig.UnencryptedDbFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no database to be created, got %v", err)
+	}
+	if haveCredentials() {
+		t.Errorf("Expected no password to be kept in memory")
+	}
+}
@@ -4,26 +4,46 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 )
 
 const (
-	// previously also supported JSON but was deprecated, leaving the current approach in case I want to extend with other storage options in the future
 	StorageSQLite StorageType = "sqlite"
+	// plaintext json file, meant for fixtures and development - not encrypted
+	StorageJSON StorageType = "json"
+	// read-only access to a transactions.json in the layout used before sqlite, e.g. to run the TUI against test_data
+	StorageLegacyJSON StorageType = "legacy-json"
+	// only kept in memory, used by tests
+	StorageMemory StorageType = "memory"
 
 	defaultExpenseToolDir = ".expense-tracking"
 	defaultUnencryptedDb  = "transactions.db"
 	defaultEncryptedDb    = "transactions.enc"
 	defaultSaltFile       = "transactions.salt"
+	defaultJsonFile       = "transactions.json"
 	defaultLogFile        = "expense-tracking.log"
 
 	// encryption configuration
 	keyLen     = 32      // AES-256 key length
-	iterations = 200_000 // PBKDF2 iterations for key derivation
+	iterations = 200_000 // PBKDF2 iterations for key derivation of files from before the versioned header
 	saltLen    = 16      // Salt length in bytes
 
+	// Argon2id parameters for new encrypted files, files with other parameters are re-encrypted with these on the next save
+	argon2Time      = 3
+	argon2MemoryKiB = 64 * 1024
+	argon2Threads   = 4
+
 	DescriptionMaxCharLength = 160
 
 	TransactionIDLength = 8
+
+	TransactionDateFormat = "2006-01-02"
+
+	defaultCheckpointEvery    = 20
+	defaultCheckpointInterval = 5 * time.Minute
+
+	defaultIdleLockTimeout = 10 * time.Minute
 )
 
 type Config struct {
@@ -32,6 +52,17 @@ type Config struct {
 	LogFilePath       string
 	EncryptedDBFile   string
 	SaltFile          string
+	JsonFile          string // only used by the json storage types
+	InMemoryDb        bool   // keep the decrypted database in memory only instead of writing it to UnencryptedDbFile
+	KeyFile           string // keyfile that is prefilled in the login form and used by the command line, e.g. on a usb stick
+	LockMemory        bool   // lock the password and keys into memory so they are never swapped out, linux only
+
+	// the database is re-encrypted in the background after this many changes and this often, 0 disables either trigger
+	CheckpointEvery    int
+	CheckpointInterval time.Duration
+
+	// the TUI session is locked after this long without a key press, 0 disables it
+	IdleLockTimeout time.Duration
 }
 
 func SetGlobalConfig(config *Config) {
@@ -61,6 +92,7 @@ func DefaultConfig() (*Config, error) {
 	unencryptedDbFilePath := filepath.Join(expenseToolDir, defaultUnencryptedDb)
 	logFilePath := filepath.Join(expenseToolDir, defaultLogFile)
 	saltFilePath := filepath.Join(expenseToolDir, defaultSaltFile)
+	jsonFilePath := filepath.Join(expenseToolDir, defaultJsonFile)
 
 	return &Config{
 		StorageType:       StorageSQLite,
@@ -68,6 +100,13 @@ func DefaultConfig() (*Config, error) {
 		EncryptedDBFile:   encryptedDbFilePath,
 		LogFilePath:       logFilePath,
 		SaltFile:          saltFilePath,
+		JsonFile:          jsonFilePath,
+		LockMemory:        true,
+
+		CheckpointEvery:    defaultCheckpointEvery,
+		CheckpointInterval: defaultCheckpointInterval,
+
+		IdleLockTimeout: defaultIdleLockTimeout,
 	}, nil
 }
 
@@ -80,6 +119,20 @@ func loadConfigFromEnvVars() (*Config, error) {
 		return nil, fmt.Errorf("failed to use default config, err: %w", err)
 	}
 
+	if storageType := os.Getenv("EXPENSE_STORAGE_TYPE"); storageType != "" {
+		switch StorageType(storageType) {
+		case StorageSQLite, StorageJSON, StorageLegacyJSON:
+			config.StorageType = StorageType(storageType)
+		default:
+			// unknown values keep the default, so a typo never points the tool at a different storage
+			fmt.Fprintf(os.Stderr, "unsupported storage type %q, expected %s, %s or %s - using %s\n", storageType, StorageSQLite, StorageJSON, StorageLegacyJSON, StorageSQLite)
+		}
+	}
+
+	if jsonFilePath := os.Getenv("EXPENSE_JSON_PATH"); jsonFilePath != "" {
+		config.JsonFile = jsonFilePath
+	}
+
 	if encryptedDbFilePath := os.Getenv("EXPENSE_ENCRYPTED_DB_PATH"); encryptedDbFilePath != "" {
 		config.EncryptedDBFile = encryptedDbFilePath
 	}
@@ -88,6 +141,46 @@ func loadConfigFromEnvVars() (*Config, error) {
 		config.UnencryptedDbFile = unencryptedDbFilePath
 	}
 
+	if inMemory := os.Getenv("EXPENSE_IN_MEMORY_DB"); inMemory != "" {
+		if enabled, err := strconv.ParseBool(inMemory); err != nil {
+			warnInvalidEnvVar("EXPENSE_IN_MEMORY_DB", inMemory, "true or false", config.InMemoryDb)
+		} else {
+			config.InMemoryDb = enabled
+		}
+	}
+
+	if lockMemory := os.Getenv("EXPENSE_LOCK_MEMORY"); lockMemory != "" {
+		if enabled, err := strconv.ParseBool(lockMemory); err != nil {
+			warnInvalidEnvVar("EXPENSE_LOCK_MEMORY", lockMemory, "true or false", config.LockMemory)
+		} else {
+			config.LockMemory = enabled
+		}
+	}
+
+	if every := os.Getenv("EXPENSE_CHECKPOINT_CHANGES"); every != "" {
+		if n, err := strconv.Atoi(every); err != nil || n < 0 {
+			warnInvalidEnvVar("EXPENSE_CHECKPOINT_CHANGES", every, "a number of changes", config.CheckpointEvery)
+		} else {
+			config.CheckpointEvery = n
+		}
+	}
+
+	if minutes := os.Getenv("EXPENSE_CHECKPOINT_MINUTES"); minutes != "" {
+		if n, err := strconv.Atoi(minutes); err != nil || n < 0 {
+			warnInvalidEnvVar("EXPENSE_CHECKPOINT_MINUTES", minutes, "a number of minutes", config.CheckpointInterval)
+		} else {
+			config.CheckpointInterval = time.Duration(n) * time.Minute
+		}
+	}
+
+	if minutes := os.Getenv("EXPENSE_IDLE_LOCK_MINUTES"); minutes != "" {
+		if n, err := strconv.Atoi(minutes); err != nil || n < 0 {
+			warnInvalidEnvVar("EXPENSE_IDLE_LOCK_MINUTES", minutes, "a number of minutes", config.IdleLockTimeout)
+		} else {
+			config.IdleLockTimeout = time.Duration(n) * time.Minute
+		}
+	}
+
 	if logFilePath := os.Getenv("EXPENSE_LOG_PATH"); logFilePath != "" {
 		config.LogFilePath = logFilePath
 	}
@@ -96,9 +189,19 @@ func loadConfigFromEnvVars() (*Config, error) {
 		config.SaltFile = saltFilePath
 	}
 
+	if keyFilePath := os.Getenv("EXPENSE_KEYFILE_PATH"); keyFilePath != "" {
+		config.KeyFile = keyFilePath
+	}
+
 	return config, nil
 }
 
+// helper to warn about an env var that can't be parsed, the default is kept so a typo never stops the tool from starting
+// the log file isn't open yet while the config is loaded, so the warning goes to stderr
+func warnInvalidEnvVar(name, value, expected string, fallback any) {
+	fmt.Fprintf(os.Stderr, "invalid %s value %q, expected %s - using %v\n", name, value, expected, fallback)
+}
+
 func createLogFileIfNotPresent(logFilePath string) (logFile *os.File, err error) {
 	logFile, err = os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
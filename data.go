@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/rivo/tview"
 )
@@ -13,7 +14,8 @@ var allowedTransactionTypes = map[string]struct{}{
 	"investment": {},
 }
 
-var allowedTransactionCategories = map[string]map[string]string{
+// the categories a new database starts with, the json storage types only ever use these - see categories.go
+var defaultTransactionCategories = map[string]map[string]string{
 	"expense": {
 		"bills":          "utilities (usually recurring) - electricity, water, gas, internet, phone, etc",
 		"car":            "any expense around car ownership - insurance, fuel, lease, etc",
@@ -66,12 +68,14 @@ var allowedTransactionCategories = map[string]map[string]string{
 	},
 }
 
-// minimal expense without year and date
+// minimal expense without year and month, the date always falls within the year and month the transaction is stored under
 type Transaction struct {
 	Id          string
 	Amount      float64
 	Category    string
 	Description string
+	Date        time.Time
+	Tags        []string // sorted and lowercased, see parseTags
 }
 
 // helper to build a table for a specific transaction type for visualization in the TUI
@@ -82,7 +86,7 @@ func createTransactionsTable(txType, month, year string, transactions Transactio
 	table.SetBorder(false)
 	table.SetTitle(capitalize(txType)).SetBorder(true)
 
-	headers := []string{"ID", "Amount", "Category", "Description"}
+	headers := []string{"ID", "Date", "Amount", "Category", "Description", "Tags"}
 	for c, h := range headers {
 		table.SetCell(0, c, tview.NewTableCell(h).SetSelectable(false))
 	}
@@ -105,14 +109,9 @@ func createTransactionsTable(txType, month, year string, transactions Transactio
 		filteredTxList = txList
 	} else {
 		// search through transactions if filter is provided (for vim like search functionality)
-		filterLower := strings.ToLower(filter)
 		for _, tx := range txList {
-			// search for a pattern in any of the sections if present, append to the filtered list
 			// filtered list will later be used to show only trasactions that match the search pattern during searching
-			if strings.Contains(strings.ToLower(tx.Id), filterLower) ||
-				strings.Contains(strings.ToLower(fmt.Sprintf("%.2f", tx.Amount)), filterLower) ||
-				strings.Contains(strings.ToLower(tx.Category), filterLower) ||
-				strings.Contains(strings.ToLower(tx.Description), filterLower) {
+			if transactionMatchesSearch(tx, filter) {
 				filteredTxList = append(filteredTxList, tx)
 			}
 		}
@@ -123,13 +122,23 @@ func createTransactionsTable(txType, month, year string, transactions Transactio
 		return table
 	}
 
+	// the total of the matches, e.g. what was spent on a #vacation-2025 tag
+	if filter != "" {
+		table.SetTitle(searchResultsTitle(txType, filteredTxList))
+	}
+
+	// show transactions in chronological order
+	filteredTxList = sortTransactionsByDate(filteredTxList)
+
 	// populate a table with only the transactions that match the specific pattern that we are searching for
 	for r, tx := range filteredTxList {
 		table.SetCell(r+1, 0, tview.NewTableCell(fmt.Sprintf("%s    ", tx.Id)).
 			SetReference(tx.Id)) // setting a reference for transaction IDs that will later be used when trying to match specific transaction IDs during update and delete operations
-		table.SetCell(r+1, 1, tview.NewTableCell(fmt.Sprintf("€%.2f", tx.Amount)))
-		table.SetCell(r+1, 2, tview.NewTableCell(tx.Category))
-		table.SetCell(r+1, 3, tview.NewTableCell(tx.Description))
+		table.SetCell(r+1, 1, tview.NewTableCell(tx.Date.Format(TransactionDateFormat)))
+		table.SetCell(r+1, 2, tview.NewTableCell(fmt.Sprintf("€%.2f", tx.Amount)))
+		table.SetCell(r+1, 3, tview.NewTableCell(tx.Category))
+		table.SetCell(r+1, 4, tview.NewTableCell(tx.Description))
+		table.SetCell(r+1, 5, tview.NewTableCell(formatTags(tx.Tags)))
 
 	}
 	// make sure selection always starts on the first row
@@ -140,6 +149,34 @@ func createTransactionsTable(txType, month, year string, transactions Transactio
 	return table
 }
 
+// helper to check if a transaction matches a search, a search starting with # only matches transactions with that exact tag
+// any other search matches a part of the id, date, amount, category, description or tags
+func transactionMatchesSearch(tx Transaction, filter string) bool {
+	if filter == TagSearchPrefix {
+		return len(tx.Tags) > 0 // a lone # lists everything that is tagged
+	}
+	if strings.HasPrefix(filter, TagSearchPrefix) {
+		return hasTag(tx, filter)
+	}
+
+	filterLower := strings.ToLower(filter)
+	return strings.Contains(strings.ToLower(tx.Id), filterLower) ||
+		strings.Contains(tx.Date.Format(TransactionDateFormat), filterLower) ||
+		strings.Contains(strings.ToLower(fmt.Sprintf("%.2f", tx.Amount)), filterLower) ||
+		strings.Contains(strings.ToLower(tx.Category), filterLower) ||
+		strings.Contains(strings.ToLower(tx.Description), filterLower) ||
+		strings.Contains(formatTags(tx.Tags), filterLower)
+}
+
+// helper to title a table with the number and total of the transactions that match a search
+func searchResultsTitle(txType string, matches []Transaction) string {
+	var total float64
+	for _, tx := range matches {
+		total += tx.Amount
+	}
+	return fmt.Sprintf("%s - %d matches, €%.2f", capitalize(txType), len(matches), total)
+}
+
 // helper to update an existing table with filtered transactions
 func updateTransactionsTable(table *tview.Table, txType, month, year string, transactions TransactionHistory, filter string) {
 	// get the currently selected transaction ID to preserve selection
@@ -161,7 +198,7 @@ func updateTransactionsTable(table *tview.Table, txType, month, year string, tra
 	table.SetBorder(false)
 	table.SetTitle(capitalize(txType)).SetBorder(true)
 
-	headers := []string{"ID", "Amount", "Category", "Description"}
+	headers := []string{"ID", "Date", "Amount", "Category", "Description", "Tags"}
 	for c, h := range headers {
 		table.SetCell(0, c, tview.NewTableCell(h).SetSelectable(false))
 	}
@@ -181,12 +218,8 @@ func updateTransactionsTable(table *tview.Table, txType, month, year string, tra
 	if filter == "" {
 		filteredTxList = txList
 	} else {
-		filterLower := strings.ToLower(filter)
 		for _, tx := range txList {
-			if strings.Contains(strings.ToLower(tx.Id), filterLower) ||
-				strings.Contains(strings.ToLower(fmt.Sprintf("%.2f", tx.Amount)), filterLower) ||
-				strings.Contains(strings.ToLower(tx.Category), filterLower) ||
-				strings.Contains(strings.ToLower(tx.Description), filterLower) {
+			if transactionMatchesSearch(tx, filter) {
 				filteredTxList = append(filteredTxList, tx)
 			}
 		}
@@ -197,12 +230,20 @@ func updateTransactionsTable(table *tview.Table, txType, month, year string, tra
 		return
 	}
 
+	if filter != "" {
+		table.SetTitle(searchResultsTitle(txType, filteredTxList))
+	}
+
+	filteredTxList = sortTransactionsByDate(filteredTxList)
+
 	for r, tx := range filteredTxList {
 		table.SetCell(r+1, 0, tview.NewTableCell(fmt.Sprintf("%s    ", tx.Id)).
 			SetReference(tx.Id))
-		table.SetCell(r+1, 1, tview.NewTableCell(fmt.Sprintf("€%.2f", tx.Amount)))
-		table.SetCell(r+1, 2, tview.NewTableCell(tx.Category))
-		table.SetCell(r+1, 3, tview.NewTableCell(tx.Description))
+		table.SetCell(r+1, 1, tview.NewTableCell(tx.Date.Format(TransactionDateFormat)))
+		table.SetCell(r+1, 2, tview.NewTableCell(fmt.Sprintf("€%.2f", tx.Amount)))
+		table.SetCell(r+1, 3, tview.NewTableCell(tx.Category))
+		table.SetCell(r+1, 4, tview.NewTableCell(tx.Description))
+		table.SetCell(r+1, 5, tview.NewTableCell(formatTags(tx.Tags)))
 	}
 
 	// Try to preserve selection on the same transaction, otherwise select first row
@@ -223,40 +264,109 @@ func updateTransactionsTable(table *tview.Table, txType, month, year string, tra
 // year -> month -> transcation type (expense, income, or investment) -> transaction
 type TransactionHistory map[string]map[string]map[string][]Transaction
 
+// helper to make sure a storage config is present before touching storage
+func ensureStorageConfig() error {
+	if globalConfig != nil {
+		return nil
+	}
+	var err error
+	globalConfig, err = DefaultConfig()
+	return err
+}
+
 // load transactions from storage
 func LoadTransactions() (TransactionHistory, error) {
-	var err error
-	if globalConfig == nil {
-		globalConfig, err = DefaultConfig()
-		if err != nil {
-			return nil, fmt.Errorf("failed to load transactions, err: %w", err)
-		}
+	store, err := currentStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transactions, err: %w", err)
 	}
+	return store.LoadAll()
+}
 
-	// previously also supported JSON but was deprecated, leaving the current approach in case I want to extend with other storage options in the future
-	switch globalConfig.StorageType {
-	case StorageSQLite:
-		return loadTransactionsFromDb()
-	default:
-		return nil, fmt.Errorf("unsupported storage type: %s", globalConfig.StorageType)
+// load only the transactions of one year, month and/or type from storage, empty values match everything
+func LoadTransactionsForPeriod(year, month, txType string) (TransactionHistory, error) {
+	store, err := currentStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transactions, err: %w", err)
 	}
+	return store.LoadPeriod(year, month, txType)
 }
 
-// load transactions to storage
-func SaveTransactions(transactions TransactionHistory) error {
-	var err error
-	if globalConfig == nil {
-		globalConfig, err = DefaultConfig()
-		if err != nil {
-			return fmt.Errorf("failed to save transactions, err: %w", err)
-		}
+// load only the transactions dated between from and to from storage, a zero time leaves that side of the range open
+func LoadTransactionsInRange(from, to time.Time) (TransactionHistory, error) {
+	store, err := currentStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transactions, err: %w", err)
+	}
+	return store.LoadRange(from, to)
+}
+
+// load a single transaction of the given type from storage
+func GetTransaction(txType, id string) (Transaction, error) {
+	store, err := currentStore()
+	if err != nil {
+		return Transaction{}, fmt.Errorf("failed to load transaction, err: %w", err)
+	}
+	return store.Get(txType, id)
+}
+
+// check if a transaction id is already in use in storage
+func TransactionIdExists(id string) (bool, error) {
+	store, err := currentStore()
+	if err != nil {
+		return false, fmt.Errorf("failed to check transaction id, err: %w", err)
+	}
+	return store.IdExists(id)
+}
+
+// add a single transaction to storage, the month it belongs to is decided by its date
+func InsertTransaction(txType string, tx Transaction) error {
+	store, err := currentStore()
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction, err: %w", err)
+	}
+	if err := store.Insert(txType, tx); err != nil {
+		return err
 	}
+	recordDbMutation()
+	return nil
+}
+
+// update a single transaction in storage, a changed date moves it to the month of that date
+func UpdateTransaction(txType string, tx Transaction) error {
+	store, err := currentStore()
+	if err != nil {
+		return fmt.Errorf("failed to update transaction, err: %w", err)
+	}
+	if err := store.Update(txType, tx); err != nil {
+		return err
+	}
+	recordDbMutation()
+	return nil
+}
 
-	// previously also supported JSON but was deprecated, leaving the current approach in case I want to extend with other storage options in the future
-	switch globalConfig.StorageType {
-	case StorageSQLite:
-		return saveTransactionsToDb(transactions)
-	default:
-		return fmt.Errorf("unsupported storage type: %s", globalConfig.StorageType)
+// delete a single transaction from storage
+func DeleteTransaction(txType, id string) error {
+	store, err := currentStore()
+	if err != nil {
+		return fmt.Errorf("failed to delete transaction, err: %w", err)
+	}
+	if err := store.Delete(txType, id); err != nil {
+		return err
+	}
+	recordDbMutation()
+	return nil
+}
+
+// load transactions to storage, this replaces everything that is stored - use the single transaction functions above for regular changes
+func SaveTransactions(transactions TransactionHistory) error {
+	store, err := currentStore()
+	if err != nil {
+		return fmt.Errorf("failed to save transactions, err: %w", err)
+	}
+	if err := store.ReplaceAll(transactions); err != nil {
+		return err
 	}
+	recordDbMutation()
+	return nil
 }
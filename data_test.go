@@ -141,6 +141,28 @@ func TestLoadConfigFromEnvVars(t *testing.T) {
 	if config.StorageType != StorageSQLite {
 		t.Errorf("Expected invalid storage type to default to SQLite, got %s", config.StorageType)
 	}
+
+	// Test with invalid values of the other settings, they keep their defaults instead of failing
+	defaults, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to load default config, err %v", err)
+	}
+	os.Setenv("EXPENSE_IN_MEMORY_DB", "maybe")
+	os.Setenv("EXPENSE_CHECKPOINT_CHANGES", "-1")
+	os.Setenv("EXPENSE_CHECKPOINT_MINUTES", "often")
+	os.Setenv("EXPENSE_IDLE_LOCK_MINUTES", "10m")
+	os.Setenv("EXPENSE_LOCK_MEMORY", "sometimes")
+	config, err = loadConfigFromEnvVars()
+	if err != nil || config == nil {
+		t.Fatalf("Expected invalid values to fall back to defaults, got err %v", err)
+	}
+	if config.InMemoryDb != defaults.InMemoryDb ||
+		config.CheckpointEvery != defaults.CheckpointEvery ||
+		config.CheckpointInterval != defaults.CheckpointInterval ||
+		config.IdleLockTimeout != defaults.IdleLockTimeout ||
+		config.LockMemory != defaults.LockMemory {
+		t.Errorf("Expected invalid values to keep the defaults, got %+v", config)
+	}
 }
 
 func TestSaveTransactions(t *testing.T) {
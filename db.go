@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 var db *sql.DB
@@ -31,42 +37,252 @@ func initDb(dbFilePath string) error {
 		return fmt.Errorf("unable to open db connection, err: %w", err)
 	}
 
-	prepTransactionTable := `
-		CREATE TABLE IF NOT EXISTS transactions (
-			id				  TEXT PRIMARY KEY,
-			amount 			NUMERIC(12, 2) NOT NULL,
-			type 				TEXT NOT NULL CHECK (type IN ('income', 'expense', 'investment')),
-			category 		TEXT NOT NULL,
-			description TEXT,
-			year 				INTEGER NOT NULL,
-			month 			TEXT NOT NULL CHECK (
-				month IN (
-            'january','february','march','april','may','june',
-            'july','august','september','october','november','december'
-				)
-			)
-		);
-	`
+	// schema changes are applied through versioned migrations, the encrypted database is backed up before migrating
+	if err = runMigrations(backupEncryptedDbBeforeMigration(dbFilePath)); err != nil {
+		return fmt.Errorf("database migration err: %w", err)
+	}
+
+	return nil
+}
+
+// set for a session that opened the database read-only because another instance holds the lock on the data directory
+// nothing is written back to the encrypted database and every change is refused
+var readOnlySession bool
+
+// opens the decrypted database as an in-memory SQLite database, so the plaintext never touches the disk
+// an empty image starts a new database
+func initInMemoryDb(image []byte) error {
+	if err := openInMemoryDb(image); err != nil {
+		return err
+	}
+
+	if err := runMigrations(func(from, to int) error {
+		if globalConfig == nil {
+			return nil
+		}
+		return backupEncryptedDbFile(globalConfig.EncryptedDBFile, from, to)
+	}); err != nil {
+		return fmt.Errorf("database migration err: %w", err)
+	}
+
+	return nil
+}
+
+// opens a copy of the decrypted database in memory that can't be changed, used while another instance holds the lock
+// pending migrations are only applied to the copy in memory, the encrypted database is left to the instance holding the lock
+func initReadOnlyDb(image []byte) error {
+	if err := openInMemoryDb(image); err != nil {
+		return err
+	}
+
+	if err := runMigrations(func(from, to int) error { return nil }); err != nil {
+		return fmt.Errorf("database migration err: %w", err)
+	}
+
+	// the pool keeps its single connection open, so the pragma holds for the whole session
+	if _, err := db.Exec(`PRAGMA query_only = ON`); err != nil {
+		return fmt.Errorf("unable to make db read-only, err: %w", err)
+	}
+
+	readOnlySession = true
+	return nil
+}
+
+// helper to open an in-memory SQLite database and load a decrypted database image into it
+func openInMemoryDb(image []byte) error {
+	var err error
+	db, err = sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return fmt.Errorf("unable to initialize in-memory db connection, err: %w", err)
+	}
+
+	// every connection to :memory: is a separate database, so the pool has to stick to a single connection that is never closed
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	// keep temporary tables and indexes in memory as well
+	if _, err = db.Exec(`PRAGMA temp_store = MEMORY`); err != nil {
+		return fmt.Errorf("unable to open in-memory db connection, err: %w", err)
+	}
+
+	if len(image) > 0 {
+		if err = loadDbImage(image); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// helper to copy a serialized database into the in-memory database
+// sqlite can't grow a deserialized buffer, so the image is deserialized into a scratch connection and copied over with the backup api
+func loadDbImage(image []byte) error {
+	scratchDb, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return fmt.Errorf("unable to open scratch db: %w", err)
+	}
+	defer scratchDb.Close()
+
+	ctx := context.Background()
+	scratchConn, err := scratchDb.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open scratch db connection: %w", err)
+	}
+	defer scratchConn.Close()
+
+	targetConn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to open in-memory db connection: %w", err)
+	}
+	defer targetConn.Close()
+
+	return scratchConn.Raw(func(scratchDriverConn any) error {
+		scratch := scratchDriverConn.(*sqlite3.SQLiteConn)
+		if err := scratch.Deserialize(image, "main"); err != nil {
+			return fmt.Errorf("unable to load decrypted database: %w", err)
+		}
+
+		return targetConn.Raw(func(targetDriverConn any) error {
+			backup, err := targetDriverConn.(*sqlite3.SQLiteConn).Backup("main", scratch, "main")
+			if err != nil {
+				return fmt.Errorf("unable to copy decrypted database: %w", err)
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return fmt.Errorf("unable to copy decrypted database: %w", err)
+			}
+			return backup.Finish()
+		})
+	})
+}
 
-	_, err = db.Exec(prepTransactionTable)
+// serializes the open database into a byte slice that can be encrypted, used for in-memory databases
+func serializeDb() ([]byte, error) {
+	conn, err := db.Conn(context.Background())
 	if err != nil {
-		return fmt.Errorf("prep transactions db table err: %w", err)
+		return nil, fmt.Errorf("unable to get db connection: %w", err)
 	}
+	defer conn.Close()
 
+	var image []byte
+	err = conn.Raw(func(driverConn any) error {
+		image, err = driverConn.(*sqlite3.SQLiteConn).Serialize("main")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialize database: %w", err)
+	}
+	return image, nil
+}
+
+// decrypts the encrypted database at login, with InMemoryDb the plaintext is returned instead of being written next to the encrypted file
+func decryptSessionDb(config *Config) ([]byte, error) {
+	if !config.InMemoryDb {
+		return nil, decryptDatabase(config.UnencryptedDbFile)
+	}
+	return decryptDatabaseImage()
+}
+
+// opens the db connection on the database decrypted by decryptSessionDb, a nil image starts a new in-memory database
+func initSessionDb(config *Config, image []byte) error {
+	if !config.InMemoryDb {
+		return initDb(config.UnencryptedDbFile)
+	}
+	return initInMemoryDb(image)
+}
+
+// decrypts the encrypted database straight into a read-only in-memory database, used while another instance holds the lock
+// the plaintext database of the other instance is never touched
+func initReadOnlySessionDb() error {
+	image, err := decryptDatabaseImage()
+	if err != nil {
+		return err
+	}
+	if err := initReadOnlyDb(image); err != nil {
+		closeDb()
+		return err
+	}
 	return nil
 }
 
 func closeDb() {
 	if db != nil {
 		db.Close()
+		db = nil // a closed handle would still look like an open database
 	}
+	readOnlySession = false
 }
 
+// closes the db and removes the decrypted copy after a failed initialization (e.g. a failed migration or a database from a newer version)
+// the encrypted file was not touched in that case, so the plaintext copy is only removed if the encrypted one exists
+func discardDecryptedDb(config *Config) {
+	closeDb()
+	if config.InMemoryDb {
+		return // nothing was written to disk
+	}
+	if _, err := os.Stat(config.EncryptedDBFile); err != nil {
+		return
+	}
+	if err := os.Remove(config.UnencryptedDbFile); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove decrypted database: %v", err)
+	}
+}
+
+var ErrTransactionNotFound = errors.New("transaction not found")
+
 func loadTransactionsFromDb() (TransactionHistory, error) {
-	rows, err := db.Query(`
-			SELECT id, amount, type, category, description, year, month
-			FROM transactions
-		`)
+	return queryTransactionsFromDb(nil, nil)
+}
+
+// loads only the transactions of a single period and/or type, empty values match everything - served by the (year, month, type) index
+func loadTransactionsForPeriodFromDb(year, month, txType string) (TransactionHistory, error) {
+	var conditions []string
+	var args []any
+	if year != "" {
+		y, err := strconv.Atoi(year)
+		if err != nil {
+			return nil, fmt.Errorf("invalid year %q: %w", year, err)
+		}
+		conditions = append(conditions, "year = ?")
+		args = append(args, y)
+	}
+	if month != "" {
+		conditions = append(conditions, "month = ?")
+		args = append(args, month)
+	}
+	if txType != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, txType)
+	}
+	return queryTransactionsFromDb(conditions, args)
+}
+
+// loads the transactions dated between from and to (both inclusive), a zero time leaves that side of the range open
+func loadTransactionsInRangeFromDb(from, to time.Time) (TransactionHistory, error) {
+	var conditions []string
+	var args []any
+	if !from.IsZero() {
+		conditions = append(conditions, "date >= ?")
+		args = append(args, from.Format(TransactionDateFormat))
+	}
+	if !to.IsZero() {
+		conditions = append(conditions, "date <= ?")
+		args = append(args, to.Format(TransactionDateFormat))
+	}
+	return queryTransactionsFromDb(conditions, args)
+}
+
+// helper to query transactions into the year -> month -> type structure, the conditions are joined with AND
+func queryTransactionsFromDb(conditions []string, args []any) (TransactionHistory, error) {
+	query := `SELECT id, amount, type, category, description, year, month, date, ` + transactionTagsColumn + ` FROM transactions`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY date, id"
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute load transactions sql query: %w", err)
 	}
@@ -79,14 +295,20 @@ func loadTransactionsFromDb() (TransactionHistory, error) {
 			id, txType, category, description, month string
 			amount                                   float64
 			year                                     int
+			date, tags                               sql.NullString
 		)
 
-		if err := rows.Scan(&id, &amount, &txType, &category, &description, &year, &month); err != nil {
+		if err := rows.Scan(&id, &amount, &txType, &category, &description, &year, &month, &date, &tags); err != nil {
 			return nil, fmt.Errorf("db scan failed during load transactions: %w", err)
 		}
 
 		y := fmt.Sprintf("%d", year)
 
+		txDate, err := storedTransactionDate(date.String, month, y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date for transaction %s: %w", id, err)
+		}
+
 		if _, ok := transactions[y]; !ok {
 			transactions[y] = make(map[string]map[string][]Transaction)
 		}
@@ -99,6 +321,8 @@ func loadTransactionsFromDb() (TransactionHistory, error) {
 			Amount:      amount,
 			Category:    category,
 			Description: description,
+			Date:        txDate,
+			Tags:        storedTransactionTags(tags.String),
 		})
 	}
 
@@ -109,6 +333,231 @@ func loadTransactionsFromDb() (TransactionHistory, error) {
 	return transactions, nil
 }
 
+// loads a single transaction of the given type by its id
+func getTransactionFromDb(txType, id string) (Transaction, error) {
+	var (
+		tx                Transaction
+		month, date, tags sql.NullString
+		year              int
+	)
+	err := db.QueryRow(`
+			SELECT id, amount, category, description, year, month, date, `+transactionTagsColumn+`
+			FROM transactions
+			WHERE id = ? AND type = ?
+		`, id, txType).Scan(&tx.Id, &tx.Amount, &tx.Category, &tx.Description, &year, &month, &date, &tags)
+	if errors.Is(err, sql.ErrNoRows) {
+		return tx, fmt.Errorf("%w: %s transaction with id %s", ErrTransactionNotFound, txType, id)
+	}
+	if err != nil {
+		return tx, fmt.Errorf("failed to load transaction %s: %w", id, err)
+	}
+
+	if tx.Date, err = storedTransactionDate(date.String, month.String, strconv.Itoa(year)); err != nil {
+		return tx, fmt.Errorf("invalid date for transaction %s: %w", id, err)
+	}
+	tx.Tags = storedTransactionTags(tags.String)
+	return tx, nil
+}
+
+// helper to check if an id is already taken by a transaction of any type
+func transactionIdExistsInDb(id string) (bool, error) {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE id = ?`, id).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check transaction id %s: %w", id, err)
+	}
+	return count > 0, nil
+}
+
+// inserts a single new transaction, the year and month columns are derived from its date
+func insertTransactionToDb(txType string, tx Transaction) error {
+	return withDbTransaction(func(sqlTx *sql.Tx) error {
+		return insertTransactionInTx(sqlTx, txType, tx)
+	})
+}
+
+// helper to insert a transaction as part of a bigger change, its tags and category are written in the same db transaction so it is never saved half way
+func insertTransactionInTx(sqlTx *sql.Tx, txType string, tx Transaction) error {
+	month, year := periodOfDate(tx.Date)
+	y, err := strconv.Atoi(year)
+	if err != nil {
+		return fmt.Errorf("invalid year for transaction %s: %w", tx.Id, err)
+	}
+
+	_, err = sqlTx.Exec(`
+			INSERT INTO transactions
+			(id, amount, type, category, description, year, month, date)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, tx.Id, tx.Amount, txType, tx.Category, tx.Description, y, month, tx.Date.Format(TransactionDateFormat))
+	if err != nil {
+		return fmt.Errorf("insert failed for transaction %s: %w", tx.Id, err)
+	}
+	if err := saveTransactionTags(sqlTx, tx.Id, tx.Tags); err != nil {
+		return err
+	}
+
+	// the handlers only accept known categories, a copied legacy transaction can bring along one that becomes a regular category
+	if _, err := sqlTx.Exec(`INSERT OR IGNORE INTO categories (type, name) VALUES (?, ?)`, txType, tx.Category); err != nil {
+		return fmt.Errorf("failed to add category of transaction %s: %w", tx.Id, err)
+	}
+	return nil
+}
+
+// updates a single transaction in place, a date in another month moves it to that month by updating the year and month columns as well
+func updateTransactionInDb(txType string, tx Transaction) error {
+	month, year := periodOfDate(tx.Date)
+	y, err := strconv.Atoi(year)
+	if err != nil {
+		return fmt.Errorf("invalid year for transaction %s: %w", tx.Id, err)
+	}
+
+	// the row and its tags are changed together, a failed tag write leaves the transaction as it was
+	return withDbTransaction(func(sqlTx *sql.Tx) error {
+		result, err := sqlTx.Exec(`
+			UPDATE transactions
+			SET amount = ?, category = ?, description = ?, year = ?, month = ?, date = ?
+			WHERE id = ? AND type = ?
+		`, tx.Amount, tx.Category, tx.Description, y, month, tx.Date.Format(TransactionDateFormat), tx.Id, txType)
+		if err != nil {
+			return fmt.Errorf("update failed for transaction %s: %w", tx.Id, err)
+		}
+		if err := expectOneAffectedRow(result, txType, tx.Id); err != nil {
+			return err
+		}
+		return saveTransactionTags(sqlTx, tx.Id, tx.Tags)
+	})
+}
+
+// deletes a single transaction of the given type by its id, together with its tags and its import reference
+// without the reference a statement that is imported again brings the transaction back instead of skipping it
+func deleteTransactionFromDb(txType, id string) error {
+	return withDbTransaction(func(sqlTx *sql.Tx) error {
+		result, err := sqlTx.Exec(`DELETE FROM transactions WHERE id = ? AND type = ?`, id, txType)
+		if err != nil {
+			return fmt.Errorf("delete failed for transaction %s: %w", id, err)
+		}
+		if err := expectOneAffectedRow(result, txType, id); err != nil {
+			return err
+		}
+		if _, err := sqlTx.Exec(`DELETE FROM imported_transactions WHERE transaction_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to remove import reference of transaction %s: %w", id, err)
+		}
+		return saveTransactionTags(sqlTx, id, nil)
+	})
+}
+
+// the tags of a transaction as a single comma separated column, so transactions can still be loaded with one query
+const transactionTagsColumn = `(SELECT group_concat(tag, '` + TagSeparator + `') FROM transaction_tags WHERE transaction_id = transactions.id) AS tags`
+
+// helper to split the tags column back into the tags of a transaction
+func storedTransactionTags(column string) []string {
+	if column == "" {
+		return nil
+	}
+	tags := strings.Split(column, TagSeparator)
+	sort.Strings(tags)
+	return tags
+}
+
+// helper to replace the tags of a transaction, no tags removes them all - always part of the db transaction that writes the transaction itself
+func saveTransactionTags(sqlTx *sql.Tx, id string, tags []string) error {
+	if _, err := sqlTx.Exec(`DELETE FROM transaction_tags WHERE transaction_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to clear tags of transaction %s: %w", id, err)
+	}
+	for _, tag := range tags {
+		if _, err := sqlTx.Exec(`INSERT OR IGNORE INTO transaction_tags (transaction_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+			return fmt.Errorf("failed to tag transaction %s with %s: %w", id, tag, err)
+		}
+	}
+	return nil
+}
+
+// helper to turn an update or delete that didn't match any row into ErrTransactionNotFound
+func expectOneAffectedRow(result sql.Result, txType, id string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows for transaction %s: %w", id, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: %s transaction with id %s", ErrTransactionNotFound, txType, id)
+	}
+	return nil
+}
+
+// helper to describe what is stored in the database
+func transactionsMetadataFromDb() (StoreMetadata, error) {
+	meta := StoreMetadata{Backend: string(StorageSQLite), ReadOnly: readOnlySession}
+
+	var first, last sql.NullString
+	err := db.QueryRow(`SELECT COUNT(*), MIN(date), MAX(date) FROM transactions`).Scan(&meta.TransactionCount, &first, &last)
+	if err != nil {
+		return meta, fmt.Errorf("failed to query transactions metadata: %w", err)
+	}
+	if first.Valid {
+		if meta.FirstDate, err = parseTransactionDate(first.String); err != nil {
+			return meta, err
+		}
+	}
+	if last.Valid {
+		if meta.LastDate, err = parseTransactionDate(last.String); err != nil {
+			return meta, err
+		}
+	}
+
+	if meta.SchemaVersion, err = currentSchemaVersion(); err != nil {
+		return meta, err
+	}
+	return meta, nil
+}
+
+// lists the tags in use from their own indexed table, without loading any transactions
+func transactionTagsFromDb() ([]string, error) {
+	rows, err := db.Query(`SELECT DISTINCT tag FROM transaction_tags ORDER BY tag`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("db scan failed during tag listing: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// helper to run statements that belong together in a single db transaction, nothing is written unless all of them succeed
+func withDbTransaction(change func(sqlTx *sql.Tx) error) error {
+	sqlTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin db transaction failed: %w", err)
+	}
+	if err := change(sqlTx); err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+// helper to run a change to data that is only kept in the sqlite database in a single db transaction, the change counts towards the next checkpoint
+func runDbChange(change func(sqlTx *sql.Tx) error) error {
+	if err := requireWritableSQLiteDb(); err != nil {
+		return err
+	}
+	if err := withDbTransaction(change); err != nil {
+		return err
+	}
+
+	recordDbMutation()
+	return nil
+}
+
+// replaces all transactions at once, only used for bulk changes - single transactions are added, updated and deleted row by row
 func saveTransactionsToDb(transactions TransactionHistory) error {
 	sqlTx, err := db.Begin()
 	if err != nil {
@@ -121,11 +570,15 @@ func saveTransactionsToDb(transactions TransactionHistory) error {
 		sqlTx.Rollback()
 		return fmt.Errorf("failed to clear transactions: %w", err)
 	}
+	if _, err = sqlTx.Exec("DELETE FROM transaction_tags"); err != nil {
+		sqlTx.Rollback()
+		return fmt.Errorf("failed to clear transaction tags: %w", err)
+	}
 
 	sqlStatement, err := sqlTx.Prepare(`
 			INSERT INTO transactions
-			(id, amount, type, category, description, year, month)
-			VALUES (?, ?, ?, ?, ?, ?, ?)
+			(id, amount, type, category, description, year, month, date)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		`)
 	if err != nil {
 		sqlTx.Rollback()
@@ -145,6 +598,12 @@ func saveTransactionsToDb(transactions TransactionHistory) error {
 			for txType, list := range types {
 
 				for _, tr := range list {
+					date, err := transactionDateInPeriod(tr, month, year)
+					if err != nil {
+						sqlTx.Rollback()
+						return err
+					}
+
 					_, err = sqlStatement.Exec(
 						tr.Id,
 						tr.Amount,
@@ -153,19 +612,55 @@ func saveTransactionsToDb(transactions TransactionHistory) error {
 						tr.Description,
 						y,     // integer, e.g. 2025
 						month, // string, e.g. August
+						date,  // string, e.g. 2025-08-14
 					)
 					if err != nil {
 						sqlTx.Rollback()
 						return fmt.Errorf("insert failed for transaction %s: %w", tr.Id, err)
 					}
+					if err := saveTransactionTags(sqlTx, tr.Id, tr.Tags); err != nil {
+						sqlTx.Rollback()
+						return err
+					}
 				}
 			}
 		}
 	}
 
+	// bulk loads aren't checked against the categories, any category they bring along becomes a regular one
+	if _, err := sqlTx.Exec(`INSERT OR IGNORE INTO categories (type, name) SELECT DISTINCT type, category FROM transactions`); err != nil {
+		sqlTx.Rollback()
+		return fmt.Errorf("failed to add categories of saved transactions: %w", err)
+	}
+
 	if err := sqlTx.Commit(); err != nil {
 		return fmt.Errorf("commit failed: %w", err)
 	}
 
 	return nil
 }
+
+// helper to parse the date column, rows without a date fall back to the first of their month
+func storedTransactionDate(date, month, year string) (time.Time, error) {
+	if date == "" {
+		return firstOfMonth(month, year)
+	}
+	return parseTransactionDate(date)
+}
+
+// helper to get the date a transaction is stored with, transactions without a date get the first of their month
+// and a date outside of the month the transaction is stored under is rejected to keep the year/month columns consistent
+func transactionDateInPeriod(tx Transaction, month, year string) (string, error) {
+	if tx.Date.IsZero() {
+		date, err := firstOfMonth(month, year)
+		if err != nil {
+			return "", fmt.Errorf("invalid period for transaction %s: %w", tx.Id, err)
+		}
+		return date.Format(TransactionDateFormat), nil
+	}
+
+	if m, y := periodOfDate(tx.Date); m != month || y != year {
+		return "", fmt.Errorf("date %s of transaction %s is outside of %s %s", tx.Date.Format(TransactionDateFormat), tx.Id, month, year)
+	}
+	return tx.Date.Format(TransactionDateFormat), nil
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestInitDb(t *testing.T) {
@@ -173,3 +177,192 @@ func TestSaveTransactionsToDbInvalidData(t *testing.T) {
 		t.Errorf("Expected error saving transactions with invalid year")
 	}
 }
+
+func TestRowLevelTransactionChanges(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	keep := Transaction{Id: "aaaa1111", Amount: 10, Category: "food", Description: "kept", Date: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)}
+	tx := Transaction{Id: "bbbb2222", Amount: 20, Category: "food", Description: "lunch", Date: time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)}
+	for _, row := range []Transaction{keep, tx} {
+		if err := insertTransactionToDb("expense", row); err != nil {
+			t.Fatalf("Expected no error inserting transaction, got %v", err)
+		}
+	}
+
+	if err := insertTransactionToDb("income", tx); err == nil {
+		t.Errorf("Expected error inserting a duplicate id")
+	}
+
+	// a new date in another month moves only this row
+	tx.Amount = 25
+	tx.Date = time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	if err := updateTransactionInDb("expense", tx); err != nil {
+		t.Fatalf("Expected no error updating transaction, got %v", err)
+	}
+
+	march, err := loadTransactionsForPeriodFromDb("2025", "march", "expense")
+	if err != nil {
+		t.Fatalf("Expected no error loading march, got %v", err)
+	}
+	if got := march["2025"]["march"]["expense"]; len(got) != 1 || got[0].Id != keep.Id {
+		t.Errorf("Expected only the untouched transaction in march, got %+v", got)
+	}
+
+	got, err := getTransactionFromDb("expense", tx.Id)
+	if err != nil || got.Amount != 25 || got.Date.Format(TransactionDateFormat) != "2025-04-01" {
+		t.Errorf("Expected updated transaction in april, got %+v (err %v)", got, err)
+	}
+
+	// the type is part of the lookup
+	if _, err := getTransactionFromDb("income", tx.Id); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("Expected ErrTransactionNotFound for another type, got %v", err)
+	}
+	if err := updateTransactionInDb("income", tx); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("Expected ErrTransactionNotFound updating another type, got %v", err)
+	}
+
+	if err := deleteTransactionFromDb("expense", tx.Id); err != nil {
+		t.Fatalf("Expected no error deleting transaction, got %v", err)
+	}
+	if err := deleteTransactionFromDb("expense", tx.Id); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("Expected ErrTransactionNotFound deleting twice, got %v", err)
+	}
+
+	all, err := loadTransactionsFromDb()
+	if err != nil {
+		t.Fatalf("Expected no error loading transactions, got %v", err)
+	}
+	if len(all["2025"]["march"]["expense"]) != 1 || len(all["2025"]["april"]) != 0 {
+		t.Errorf("Expected only the untouched transaction to remain, got %+v", all)
+	}
+}
+
+func TestInMemoryDbRoundTrip(t *testing.T) {
+	setUserPassword("testpassword")
+	defer clearUserPassword()
+
+	dir := t.TempDir()
+	config := &Config{
+		StorageType:       StorageSQLite,
+		UnencryptedDbFile: filepath.Join(dir, "transactions.db"),
+		EncryptedDBFile:   filepath.Join(dir, "transactions.enc"),
+		SaltFile:          filepath.Join(dir, "transactions.salt"),
+		InMemoryDb:        true,
+	}
+	original, originalDb := globalConfig, db
+	SetGlobalConfig(config)
+	t.Cleanup(func() {
+		SetGlobalConfig(original)
+		db = originalDb
+	})
+
+	// first session starts with a new database
+	if err := initSessionDb(config, nil); err != nil {
+		t.Fatalf("Expected no error opening new in-memory db, got %v", err)
+	}
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "food", Description: "lunch", Date: "2025-03-14"}); err != nil {
+		t.Fatalf("Expected no error adding transaction, got %v", err)
+	}
+	closeAndEncryptDb(config)
+
+	// second session has to be able to grow past the size of the decrypted image
+	image, err := decryptSessionDb(config)
+	if err != nil {
+		t.Fatalf("Expected no error decrypting, got %v", err)
+	}
+	if err := initSessionDb(config, image); err != nil {
+		t.Fatalf("Expected no error loading in-memory db, got %v", err)
+	}
+	for i := 0; i < 200; i++ {
+		if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "1", Category: "food", Description: strings.Repeat("x", 150), Date: "2025-04-01"}); err != nil {
+			t.Fatalf("Expected no error growing in-memory db, got %v", err)
+		}
+	}
+	closeAndEncryptDb(config)
+
+	image, err = decryptSessionDb(config)
+	if err != nil {
+		t.Fatalf("Expected no error decrypting, got %v", err)
+	}
+	if err := initSessionDb(config, image); err != nil {
+		t.Fatalf("Expected no error loading in-memory db, got %v", err)
+	}
+	defer closeDb()
+
+	transactions, err := loadTransactionsFromDb()
+	if err != nil {
+		t.Fatalf("Expected no error loading transactions, got %v", err)
+	}
+	if len(transactions["2025"]["march"]["expense"]) != 1 || len(transactions["2025"]["april"]["expense"]) != 200 {
+		t.Errorf("Expected transactions of both sessions, got %d in march and %d in april",
+			len(transactions["2025"]["march"]["expense"]), len(transactions["2025"]["april"]["expense"]))
+	}
+
+	// the plaintext database never touched the disk
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if e.Name() != "transactions.enc" && e.Name() != "transactions.salt" {
+			t.Errorf("Unexpected file %s next to the encrypted database", e.Name())
+		}
+	}
+}
+
+// helper to make every insert into a table fail, to check that a change is rolled back as a whole
+func failTestDbInserts(t *testing.T, table string) {
+	t.Helper()
+	_, err := db.Exec(`CREATE TRIGGER fail_` + table + ` BEFORE INSERT ON ` + table + ` BEGIN SELECT RAISE(ABORT, 'insert into ` + table + ` failed'); END`)
+	if err != nil {
+		t.Fatalf("Failed to create trigger on %s: %v", table, err)
+	}
+}
+
+func TestInsertTransactionIsAtomic(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	failTestDbInserts(t, "categories")
+
+	tx := Transaction{Id: "cccc3333", Amount: 10, Category: "food", Date: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)}
+	if err := insertTransactionToDb("expense", tx); err == nil {
+		t.Fatalf("Expected the insert to fail when the category can't be written")
+	}
+	if exists, err := transactionIdExistsInDb(tx.Id); err != nil || exists {
+		t.Errorf("Expected the transaction to be rolled back, exists %v (err %v)", exists, err)
+	}
+}
+
+func TestTransactionTagsWrittenWithTheRow(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	tx := Transaction{Id: "dddd4444", Amount: 10, Category: "food", Description: "lunch", Date: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC), Tags: []string{"work"}}
+	if err := insertTransactionToDb("expense", tx); err != nil {
+		t.Fatalf("Expected no error inserting transaction, got %v", err)
+	}
+
+	// a tag that can't be written leaves the transaction as it was
+	failTestDbInserts(t, "transaction_tags")
+	changed := tx
+	changed.Amount = 99
+	changed.Tags = []string{"reimbursable"}
+	if err := updateTransactionInDb("expense", changed); err == nil {
+		t.Fatalf("Expected the update to fail when the tags can't be written")
+	}
+	got, err := getTransactionFromDb("expense", tx.Id)
+	if err != nil || got.Amount != 10 || len(got.Tags) != 1 || got.Tags[0] != "work" {
+		t.Errorf("Expected the transaction and its tags to be unchanged, got %+v (err %v)", got, err)
+	}
+
+	if err := insertTransactionToDb("expense", Transaction{Id: "eeee5555", Amount: 5, Category: "food", Date: tx.Date, Tags: []string{"work"}}); err == nil {
+		t.Errorf("Expected the insert to fail when the tags can't be written")
+	}
+	if exists, _ := transactionIdExistsInDb("eeee5555"); exists {
+		t.Errorf("Expected the transaction without its tags to be rolled back")
+	}
+
+	// deleting removes the row and its tags together
+	if err := deleteTransactionFromDb("expense", tx.Id); err != nil {
+		t.Fatalf("Expected no error deleting transaction, got %v", err)
+	}
+	var left int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM transaction_tags WHERE transaction_id = ?`, tx.Id).Scan(&left); err != nil || left != 0 {
+		t.Errorf("Expected no tags left behind, got %d (err %v)", left, err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/rivo/tview"
@@ -69,11 +70,6 @@ func formDeleteTransaction(transactionId, transactionType, selectedMonth, select
 
 // handles deleting an existing transaction to storage
 func handleDeleteTransaction(transactionType, transactionId string) error {
-	transactions, loadFileErr := LoadTransactions()
-	if loadFileErr != nil {
-		return fmt.Errorf("unable to load transactions file: %w", loadFileErr)
-	}
-
 	txType, err := normalizeTransactionType(transactionType)
 	if err != nil {
 		return fmt.Errorf("transaction type error: %w", err)
@@ -83,32 +79,12 @@ func handleDeleteTransaction(transactionType, transactionId string) error {
 		return fmt.Errorf("invalid transaction id length, expected %v char id, got %v", TransactionIDLength, len(transactionId))
 	}
 
-	for year, months := range transactions {
-
-		for month := range months {
-
-			var txList = transactions[year][month][txType]
-			for i, t := range txList {
-				if t.Id == transactionId {
-					transactions[year][month][txType] = removeTransactionAtIndex(txList, i)
-
-					if saveTransactionErr := SaveTransactions(transactions); saveTransactionErr != nil {
-						return fmt.Errorf("error saving transaction: %w", saveTransactionErr)
-					}
-
-					return nil
-				}
-			}
+	if err := DeleteTransaction(txType, transactionId); err != nil {
+		if errors.Is(err, ErrTransactionNotFound) {
+			return fmt.Errorf("\ndid not match any transaction by id %s, please run list %s or show-total and confirm the transaction id that you want to delete\n", transactionId, txType)
 		}
+		return fmt.Errorf("error saving transaction: %w", err)
 	}
 
-	return fmt.Errorf("\ndid not match any transaction by id %s, please run list %s or show-total and confirm the transaction id that you want to delete\n", transactionId, txType)
-}
-
-// helper to remove a transaction at a specific index
-func removeTransactionAtIndex(transactions []Transaction, index int) []Transaction {
-	if index < 0 || index >= len(transactions) {
-		return transactions // index out of range return original
-	}
-	return append(transactions[:index], transactions[index+1:]...)
+	return nil
 }
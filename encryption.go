@@ -10,21 +10,64 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"golang.org/x/crypto/pbkdf2"
 )
 
-// global var to store the user's password in memory for encryption key derivation
-var userPassword string
+// global var to store the user's password in memory for encryption key derivation, nil when logged out
+var userPassword []byte
+
+// guards the password and the keyfile, they are wiped and their memory is freed on lock or exit while a checkpoint
+// or the UI may still be encrypting with them - touching freed memory would crash the process instead of panicking
+var credentialsMu sync.RWMutex
+
+var ErrNoCredentials = errors.New("user password not set")
 
 // stores the user's password in memory to derive an encryption key from it
 func setUserPassword(password string) {
-	userPassword = password
+	setUserPasswordBytes([]byte(password))
+}
+
+// stores a copy of the user's password in memory, the caller can wipe its own copy afterwards
+func setUserPasswordBytes(password []byte) {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	freeSecret(userPassword)
+	userPassword = newSecret(password)
 }
 
-// clears the password from memory for security
+// wipes the password, the keyfile and the data key of the open database from memory, waits for anything that still uses them to finish
 func clearUserPassword() {
-	userPassword = ""
+	credentialsMu.Lock()
+	freeSecret(userPassword)
+	userPassword = nil
+	clearUserKeyFileLocked()
+	credentialsMu.Unlock()
+	forgetDataKey()
+}
+
+// replaces the password and the keyfile at once after the database was re-encrypted with them, keyFile is nil without a keyfile
+func replaceUserCredentials(password []byte, keyFilePath string, keyFile []byte) {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	freeSecret(userPassword)
+	userPassword = newSecret(password)
+	clearUserKeyFileLocked()
+	if keyFile != nil {
+		userKeyFilePath, userKeyFile = keyFilePath, newSecret(keyFile)
+	}
+}
+
+// runs fn with the password and the keyfile while they can't be wiped, they are only valid until fn returns
+// fn must not change the credentials itself
+func withCredentials(fn func(password, keyFile []byte) error) error {
+	credentialsMu.RLock()
+	defer credentialsMu.RUnlock()
+	if userPassword == nil && userKeyFile == nil {
+		return ErrNoCredentials
+	}
+	return fn(userPassword, userKeyFile)
 }
 
 // creates a random salt of specified length
@@ -94,9 +137,10 @@ func getOrCreateSalt() ([]byte, error) {
 	}
 }
 
-// derives an encryption key from password and salt using PBKDF2
-func deriveEncryptionKey(password string) ([]byte, error) {
-	if password == "" {
+// derives an encryption key from password and salt using PBKDF2, only used for files from before the versioned header
+// that keep their salt in transactions.salt
+func deriveEncryptionKey(password []byte) ([]byte, error) {
+	if len(password) == 0 {
 		return nil, fmt.Errorf("password cannot be empty")
 	}
 
@@ -105,14 +149,14 @@ func deriveEncryptionKey(password string) ([]byte, error) {
 		return nil, fmt.Errorf("failed to get salt: %w", err)
 	}
 
-	key := pbkdf2.Key([]byte(password), salt, iterations, keyLen, sha256.New)
+	key := pbkdf2.Key(password, salt, iterations, keyLen, sha256.New)
 	return key, nil
 }
 
 // encrypts the SQLite database file
 func encryptDatabase(dbPath string) error {
-	if userPassword == "" {
-		return fmt.Errorf("user password not set")
+	if !haveCredentials() {
+		return ErrNoCredentials
 	}
 
 	dbData, err := os.ReadFile(dbPath)
@@ -120,60 +164,106 @@ func encryptDatabase(dbPath string) error {
 		return fmt.Errorf("failed to read database file: %w", err)
 	}
 
-	key, err := deriveEncryptionKey(userPassword)
-	if err != nil {
-		return fmt.Errorf("failed to derive encryption key: %w", err)
-	}
+	return encryptDatabaseImage(dbData)
+}
+
+// encrypts a serialized SQLite database and writes it to the encrypted database file
+func encryptDatabaseImage(dbData []byte) error {
+	return encryptDatabaseImageTo(globalConfig.EncryptedDBFile, dbData)
+}
 
-	encryptedData, err := encryptTransactions(key, dbData)
+// encrypts a serialized SQLite database with the user's password and/or keyfile and writes it to encryptedPath
+func encryptDatabaseImageTo(encryptedPath string, dbData []byte) error {
+	var encryptedData []byte
+	err := withCredentials(func(password, keyFile []byte) error {
+		header, dataKey, err := encHeaderForWrite(encryptedPath, password, keyFile)
+		if err != nil {
+			if errors.Is(err, ErrWrongPassword) || errors.Is(err, ErrWrongUnlockMode) {
+				return fmt.Errorf("the encrypted database at %s can't be opened with the current password: %w", encryptedPath, err)
+			}
+			return fmt.Errorf("failed to read encrypted database header: %w", err)
+		}
+
+		encryptedData, err = sealDatabaseImage(header, dataKey, dbData)
+		wipe(dataKey)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to encrypt database: %w", err)
+		return err
 	}
 
 	// make sure dir exists
-	dir := filepath.Dir(globalConfig.EncryptedDBFile)
+	dir := filepath.Dir(encryptedPath)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("failed to create encryption directory: %w", err)
 	}
 
-	// write the encrypted file
-	if err := os.WriteFile(globalConfig.EncryptedDBFile, encryptedData, 0600); err != nil {
+	// write the encrypted file, atomically so a crash mid-write never leaves a half written database behind
+	if err := writeFileAtomically(encryptedPath, encryptedData, 0600); err != nil {
 		return fmt.Errorf("failed to write encrypted database: %w", err)
 	}
 
 	return nil
 }
 
-var ErrWrongPassword = errors.New("wrong password")
-
-// decrypts the SQLite database file
-func decryptDatabase(dbPath string) error {
-	if userPassword == "" {
-		return fmt.Errorf("user password not set")
+// helper to encrypt a serialized SQLite database with the data key into the contents of an encrypted database file,
+// the header is authenticated together with the ciphertext
+func sealDatabaseImage(header *encHeader, dataKey, dbData []byte) ([]byte, error) {
+	headerData := header.marshal()
+	sealed, err := encryptTransactionsWithHeader(dataKey, dbData, headerData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt database: %w", err)
 	}
+	return append(headerData, sealed...), nil
+}
 
-	// check if encrypted file exists
-	if _, err := os.Stat(globalConfig.EncryptedDBFile); os.IsNotExist(err) {
-		return nil // nothing to decrypt
+// writes to a temporary file in the same directory first, syncs it and renames it over the target
+// readers either see the old or the new contents, never a partial write
+func writeFileAtomically(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
 	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
 
-	encryptedData, err := os.ReadFile(globalConfig.EncryptedDBFile)
-	if err != nil {
-		return fmt.Errorf("failed to read encrypted database: %w", err)
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to set permissions of temporary file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
 	}
 
-	key, err := deriveEncryptionKey(userPassword)
-	if err != nil {
-		return fmt.Errorf("failed to derive encryption key: %w", err)
+	// persist the rename itself, not supported on every platform so errors are ignored
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
 	}
+	return nil
+}
+
+var ErrWrongPassword = errors.New("wrong password")
 
-	decryptedData, err := decryptTransactions(key, encryptedData)
+// decrypts the SQLite database file
+func decryptDatabase(dbPath string) error {
+	decryptedData, err := decryptDatabaseImage()
 	if err != nil {
-		// when decryption fails due to wrong password, return ErrWrongPassword
-		if errors.Is(err, ErrWrongPassword) {
-			return ErrWrongPassword
-		}
-		return fmt.Errorf("failed to decrypt database: %w", err)
+		return err
+	}
+	if decryptedData == nil {
+		return nil // nothing to decrypt
 	}
 
 	// make sure dir exists
@@ -190,8 +280,91 @@ func decryptDatabase(dbPath string) error {
 	return nil
 }
 
+// decrypts the encrypted database file into memory, returns nil if there is no encrypted database yet
+func decryptDatabaseImage() ([]byte, error) {
+	if !haveCredentials() {
+		return nil, ErrNoCredentials
+	}
+
+	// check if encrypted file exists
+	if _, err := os.Stat(globalConfig.EncryptedDBFile); os.IsNotExist(err) {
+		return nil, nil // nothing to decrypt
+	}
+
+	encryptedData, err := os.ReadFile(globalConfig.EncryptedDBFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted database: %w", err)
+	}
+
+	var image []byte
+	err = withCredentials(func(password, keyFile []byte) error {
+		image, err = openDatabaseImage(password, keyFile, encryptedData)
+		return err
+	})
+	return image, err
+}
+
+// helper to decrypt the contents of an encrypted database file into a serialized SQLite database, keyFile is nil without a keyfile
+func openDatabaseImage(password, keyFile, encryptedData []byte) ([]byte, error) {
+	header, sealed, err := parseEncHeader(encryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted database header: %w", err)
+	}
+
+	// files from before the header derive their key from transactions.salt, they get a header on the next save
+	var key []byte
+	switch {
+	case header == nil && keyFile != nil:
+		return nil, fmt.Errorf("%w: the database is unlocked with %s", ErrWrongUnlockMode, unlockModeName(keySlotPassword))
+	case header == nil:
+		key, err = deriveEncryptionKey(password)
+	default:
+		key, err = header.dataKey(password, keyFile)
+		if err == nil {
+			rememberDataKey(header, key)
+		}
+	}
+	if errors.Is(err, ErrWrongPassword) {
+		return nil, ErrWrongPassword
+	}
+	if errors.Is(err, ErrWrongUnlockMode) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	defer wipe(key)
+
+	return openSealedImage(header, key, sealed)
+}
+
+// helper to decrypt the encrypted data that follows the header with the key that unlocks it
+func openSealedImage(header *encHeader, key, sealed []byte) ([]byte, error) {
+	var decryptedData []byte
+	var err error
+	if header == nil {
+		decryptedData, err = decryptTransactions(key, sealed)
+	} else {
+		decryptedData, err = decryptTransactionsWithHeader(key, sealed, header.marshal())
+	}
+	if err != nil {
+		// when decryption fails due to wrong password, return ErrWrongPassword
+		if errors.Is(err, ErrWrongPassword) {
+			return nil, ErrWrongPassword
+		}
+		return nil, fmt.Errorf("failed to decrypt database: %w", err)
+	}
+
+	return decryptedData, nil
+}
+
 // encrypts transaction data using AES-GCM
 func encryptTransactions(key, plainText []byte) ([]byte, error) {
+	return encryptTransactionsWithHeader(key, plainText, nil)
+}
+
+// encrypts transaction data using AES-GCM, the header is authenticated but not encrypted and has to be passed again for decryption
+func encryptTransactionsWithHeader(key, plainText, header []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
@@ -209,12 +382,17 @@ func encryptTransactions(key, plainText []byte) ([]byte, error) {
 	}
 
 	// encrypt and authenticate
-	cipherText := gcm.Seal(nonce, nonce, plainText, nil)
+	cipherText := gcm.Seal(nonce, nonce, plainText, header)
 	return cipherText, nil
 }
 
 // decrypts transaction data using AES-GCM
 func decryptTransactions(key, cipherText []byte) ([]byte, error) {
+	return decryptTransactionsWithHeader(key, cipherText, nil)
+}
+
+// decrypts transaction data using AES-GCM that was encrypted together with a header
+func decryptTransactionsWithHeader(key, cipherText, header []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create cipher: %w", err)
@@ -234,7 +412,7 @@ func decryptTransactions(key, cipherText []byte) ([]byte, error) {
 	nonce, data := cipherText[:gcm.NonceSize()], cipherText[gcm.NonceSize():]
 
 	// decrypt and verify
-	plainText, err := gcm.Open(nil, nonce, data, nil)
+	plainText, err := gcm.Open(nil, nonce, data, header)
 	if err != nil {
 		// map AES-GCM authentication failure to wrong password
 		return nil, ErrWrongPassword
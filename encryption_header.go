@@ -0,0 +1,512 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// the encrypted database file describes how its key is derived, so a single .enc file is a complete backup:
+//
+//	magic (8 bytes) | format version (1) | kdf (1) | kdf time (4) | kdf memory in KiB (4) | kdf threads (1) | salt length (1) | salt |
+//	key slot count (1) | key slots | nonce | ciphertext
+//
+// the database is encrypted with a random data key, every key slot holds that data key wrapped by one way of unlocking the file:
+//
+//	slot type (1) | wrapped key length (1) | nonce | wrapped data key
+//
+// the unlock slot is wrapped with the key derived from the password, a keyfile or both, the recovery slot with a key derived from the recovery key
+// the header is authenticated as additional data of the AES-GCM ciphertext, so its parameters can't be changed without a key
+//
+// version 1 files have no key slots, the key derived from the password encrypts the database directly
+// files from before the header are just nonce | ciphertext with the salt in transactions.salt
+// both are still read and are written as the current version on the next save
+var encFileMagic = []byte("EXPTRKDB")
+
+const (
+	encFormatVersion = 2
+
+	// key derivation functions
+	kdfPBKDF2SHA256 byte = 1 // time is the number of iterations
+	kdfArgon2id     byte = 2
+
+	// ways of unlocking the data key, a file has one of the password, keyfile or password and keyfile slots and optionally a recovery slot
+	keySlotPassword        byte = 1
+	keySlotRecovery        byte = 2
+	keySlotKeyFile         byte = 3
+	keySlotPasswordKeyFile byte = 4
+
+	encHeaderFixedLen = 8 + 1 + 1 + 4 + 4 + 1 + 1 // everything up to the salt
+	encHeaderMaxLen   = 4096                      // more than enough for the salt and a handful of key slots
+	minSaltLen        = 8
+
+	recoveryKeyLen = 20 // random bytes of a recovery key, 32 characters once encoded
+)
+
+var (
+	ErrWrongRecoveryKey = errors.New("wrong recovery key")
+	ErrNoRecoveryKey    = errors.New("no recovery key has been set up for this database")
+	ErrWrongUnlockMode  = errors.New("wrong unlock mode")
+)
+
+// the parsed header of an encrypted database file
+type encHeader struct {
+	version byte
+	kdf     byte
+	time    uint32
+	memory  uint32 // KiB, only used by argon2id
+	threads uint8  // only used by argon2id
+	salt    []byte
+	slots   []encKeySlot
+}
+
+// the data key wrapped by one way of unlocking the file
+type encKeySlot struct {
+	kind    byte
+	wrapped []byte
+}
+
+// helper to get the key slot that the password and keyfile unlock, keyFile is nil without a keyfile
+func unlockSlotKind(password, keyFile []byte) byte {
+	switch {
+	case len(password) > 0 && keyFile != nil:
+		return keySlotPasswordKeyFile
+	case keyFile != nil:
+		return keySlotKeyFile
+	default:
+		return keySlotPassword
+	}
+}
+
+// helper to describe what a key slot needs for error messages
+func unlockModeName(kind byte) string {
+	switch kind {
+	case keySlotPasswordKeyFile:
+		return "a password and a keyfile"
+	case keySlotKeyFile:
+		return "a keyfile"
+	default:
+		return "a password"
+	}
+}
+
+// helper to check if a key slot unlocks the database with the password and/or the keyfile, as opposed to the recovery key
+func isUnlockSlot(kind byte) bool {
+	return kind == keySlotPassword || kind == keySlotKeyFile || kind == keySlotPasswordKeyFile
+}
+
+// creates a header with a new salt, the current key derivation parameters and a new data key wrapped by the password and/or keyfile
+// returns the header together with the data key that encrypts the database
+func newEncHeader(password, keyFile []byte) (*encHeader, []byte, error) {
+	dataKey := make([]byte, keyLen)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	header, err := (&encHeader{}).rekeyed(password, keyFile, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, dataKey, nil
+}
+
+// returns a copy of the header with a fresh salt, the current key derivation parameters and the data key wrapped by the password and/or keyfile
+// the recovery key slot is kept, it doesn't depend on the password
+func (h *encHeader) rekeyed(password, keyFile, dataKey []byte) (*encHeader, error) {
+	salt, err := generateSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	rekeyed := &encHeader{
+		version: encFormatVersion,
+		kdf:     kdfArgon2id,
+		time:    argon2Time,
+		memory:  argon2MemoryKiB,
+		threads: argon2Threads,
+		salt:    salt,
+	}
+	for _, slot := range h.slots {
+		if !isUnlockSlot(slot.kind) {
+			rekeyed.slots = append(rekeyed.slots, slot)
+		}
+	}
+
+	kek, err := rekeyed.deriveKey(password, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	defer wipe(kek)
+	if err := rekeyed.wrapDataKey(unlockSlotKind(password, keyFile), kek, dataKey); err != nil {
+		return nil, err
+	}
+	return rekeyed, nil
+}
+
+// reports if the header uses the current format and key derivation parameters, other files are upgraded on the next save
+func (h *encHeader) current() bool {
+	return h.version == encFormatVersion && h.kdf == kdfArgon2id &&
+		h.time == argon2Time && h.memory == argon2MemoryKiB && h.threads == argon2Threads
+}
+
+// helper to get the key slot of a kind, nil if the header has none
+func (h *encHeader) slot(kind byte) *encKeySlot {
+	for i := range h.slots {
+		if h.slots[i].kind == kind {
+			return &h.slots[i]
+		}
+	}
+	return nil
+}
+
+// wraps the data key with a key encryption key and stores it in the slot of that kind, replacing an existing one
+func (h *encHeader) wrapDataKey(kind byte, kek, dataKey []byte) error {
+	wrapped, err := encryptTransactionsWithHeader(kek, dataKey, []byte{kind})
+	if err != nil {
+		return fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	if slot := h.slot(kind); slot != nil {
+		slot.wrapped = wrapped
+		return nil
+	}
+	h.slots = append(h.slots, encKeySlot{kind: kind, wrapped: wrapped})
+	return nil
+}
+
+// helper to unwrap the data key of a slot, a key that doesn't fit fails the authentication of the wrapped key
+func (h *encHeader) unwrapDataKey(kind byte, kek []byte) ([]byte, error) {
+	slot := h.slot(kind)
+	if slot == nil {
+		return nil, fmt.Errorf("encrypted file header has no key slot %d", kind)
+	}
+	return decryptTransactionsWithHeader(kek, slot.wrapped, []byte{kind})
+}
+
+// helper to get the kind of the slot that unlocks the database without the recovery key, version 1 files only have a password
+func (h *encHeader) unlockSlotKind() byte {
+	for _, slot := range h.slots {
+		if isUnlockSlot(slot.kind) {
+			return slot.kind
+		}
+	}
+	return keySlotPassword
+}
+
+// unlocks the key that encrypts the database with the password and/or keyfile, keyFile is nil without a keyfile
+func (h *encHeader) dataKey(password, keyFile []byte) ([]byte, error) {
+	// a password alone never opens a database that needs a keyfile and the other way around, say so instead of reporting a wrong password
+	kind := unlockSlotKind(password, keyFile)
+	if want := h.unlockSlotKind(); kind != want {
+		return nil, fmt.Errorf("%w: the database is unlocked with %s", ErrWrongUnlockMode, unlockModeName(want))
+	}
+
+	kek, err := h.deriveKey(password, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	// version 1 files are encrypted with the derived key itself
+	if h.version == 1 {
+		return kek, nil
+	}
+	defer wipe(kek)
+	return h.unwrapDataKey(kind, kek)
+}
+
+// unlocks the key that encrypts the database with the recovery key
+func (h *encHeader) dataKeyFromRecoveryKey(recoveryKey string) ([]byte, error) {
+	if h.slot(keySlotRecovery) == nil {
+		return nil, ErrNoRecoveryKey
+	}
+	kek, err := recoveryKeyEncryptionKey(recoveryKey)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(kek)
+	dataKey, err := h.unwrapDataKey(keySlotRecovery, kek)
+	if errors.Is(err, ErrWrongPassword) {
+		return nil, ErrWrongRecoveryKey
+	}
+	return dataKey, err
+}
+
+// wraps the data key with a recovery key, replacing the previous recovery key
+func (h *encHeader) setRecoveryKey(recoveryKey string, dataKey []byte) error {
+	kek, err := recoveryKeyEncryptionKey(recoveryKey)
+	if err != nil {
+		return err
+	}
+	defer wipe(kek)
+	return h.wrapDataKey(keySlotRecovery, kek, dataKey)
+}
+
+// the part of the header the key derived from the password and keyfile depends on - format version, kdf parameters and salt
+func (h *encHeader) kdfParams() []byte {
+	buf := make([]byte, 0, encHeaderFixedLen+len(h.salt))
+	buf = append(buf, encFileMagic...)
+	buf = append(buf, h.version, h.kdf)
+	buf = binary.BigEndian.AppendUint32(buf, h.time)
+	buf = binary.BigEndian.AppendUint32(buf, h.memory)
+	buf = append(buf, h.threads, byte(len(h.salt)))
+	return append(buf, h.salt...)
+}
+
+func (h *encHeader) marshal() []byte {
+	buf := h.kdfParams()
+	if h.version == 1 {
+		return buf
+	}
+	buf = append(buf, byte(len(h.slots)))
+	for _, slot := range h.slots {
+		buf = append(buf, slot.kind, byte(len(slot.wrapped)))
+		buf = append(buf, slot.wrapped...)
+	}
+	return buf
+}
+
+// splits an encrypted database file into its header and the encrypted data
+// a file without the magic bytes is from before the header, nil is returned for the header in that case
+func parseEncHeader(data []byte) (*encHeader, []byte, error) {
+	if !bytes.HasPrefix(data, encFileMagic) {
+		return nil, data, nil
+	}
+	if len(data) < encHeaderFixedLen {
+		return nil, nil, fmt.Errorf("encrypted file header is truncated")
+	}
+
+	h := &encHeader{
+		version: data[8],
+		kdf:     data[9],
+		time:    binary.BigEndian.Uint32(data[10:14]),
+		memory:  binary.BigEndian.Uint32(data[14:18]),
+		threads: data[18],
+	}
+	if h.version == 0 || h.version > encFormatVersion {
+		return nil, nil, fmt.Errorf("unsupported encrypted file format version %d, it was written by a newer release", h.version)
+	}
+
+	pos := encHeaderFixedLen + int(data[19])
+	if int(data[19]) < minSaltLen || len(data) < pos {
+		return nil, nil, fmt.Errorf("encrypted file header has an invalid salt")
+	}
+	h.salt = append([]byte(nil), data[encHeaderFixedLen:pos]...)
+
+	switch h.kdf {
+	case kdfArgon2id:
+		if h.time == 0 || h.memory == 0 || h.threads == 0 {
+			return nil, nil, fmt.Errorf("encrypted file header has invalid argon2id parameters")
+		}
+	case kdfPBKDF2SHA256:
+		if h.time == 0 {
+			return nil, nil, fmt.Errorf("encrypted file header has invalid pbkdf2 parameters")
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported key derivation function %d in encrypted file header", h.kdf)
+	}
+
+	if h.version == 1 {
+		return h, data[pos:], nil
+	}
+
+	if len(data) < pos+1 {
+		return nil, nil, fmt.Errorf("encrypted file header is truncated")
+	}
+	count := int(data[pos])
+	pos++
+	for i := 0; i < count; i++ {
+		if len(data) < pos+2 || len(data) < pos+2+int(data[pos+1]) {
+			return nil, nil, fmt.Errorf("encrypted file header has a truncated key slot")
+		}
+		kind, length := data[pos], int(data[pos+1])
+		h.slots = append(h.slots, encKeySlot{kind: kind, wrapped: append([]byte(nil), data[pos+2:pos+2+length]...)})
+		pos += 2 + length
+	}
+	unlockSlots := 0
+	for _, slot := range h.slots {
+		if isUnlockSlot(slot.kind) {
+			unlockSlots++
+		}
+	}
+	if unlockSlots != 1 {
+		return nil, nil, fmt.Errorf("encrypted file header needs exactly one password or keyfile key slot, found %d", unlockSlots)
+	}
+
+	return h, data[pos:], nil
+}
+
+// helper to read only the header of an encrypted database file, returns nil if the file doesn't exist or is from before the header
+func readEncHeader(path string) (*encHeader, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open encrypted database: %w", err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, encHeaderMaxLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read encrypted database: %w", err)
+	}
+	h, _, err := parseEncHeader(buf[:n])
+	return h, err
+}
+
+// the data key of the open database, so checkpoints don't pay for the slow key derivation on every save
+// it is only reused for a file with the same header it was unlocked from and is wiped once the database is locked or closed
+var (
+	sessionDataKeyMu     sync.Mutex
+	sessionDataKeyHeader []byte
+	sessionDataKey       []byte
+)
+
+// remembers the data key unlocked from a header in the current format, older files are upgraded on the next save anyway
+func rememberDataKey(h *encHeader, dataKey []byte) {
+	if !h.current() {
+		return
+	}
+	sessionDataKeyMu.Lock()
+	defer sessionDataKeyMu.Unlock()
+	forgetDataKeyLocked()
+	sessionDataKeyHeader, sessionDataKey = h.marshal(), newSecret(dataKey)
+}
+
+// returns a copy of the remembered data key if it was unlocked from this header, nil otherwise
+// a file that was re-keyed, e.g. by another instance, has another header and needs the password again
+func rememberedDataKey(h *encHeader) []byte {
+	sessionDataKeyMu.Lock()
+	defer sessionDataKeyMu.Unlock()
+	if sessionDataKey == nil || !bytes.Equal(sessionDataKeyHeader, h.marshal()) {
+		return nil
+	}
+	return append([]byte(nil), sessionDataKey...)
+}
+
+// wipes the remembered data key, called when the password is cleared and when the database is closed for good
+func forgetDataKey() {
+	sessionDataKeyMu.Lock()
+	defer sessionDataKeyMu.Unlock()
+	forgetDataKeyLocked()
+}
+
+func forgetDataKeyLocked() {
+	freeSecret(sessionDataKey)
+	sessionDataKeyHeader, sessionDataKey = nil, nil
+}
+
+// derives the key that wraps the data key from the password and/or the keyfile, for version 1 files it encrypts the database directly
+// the password goes through the slow kdf, the keyfile is already random key material and is mixed in with HKDF
+// the caller wipes the returned key once it is done with it
+func (h *encHeader) deriveKey(password, keyFile []byte) ([]byte, error) {
+	if len(password) == 0 && keyFile == nil {
+		return nil, fmt.Errorf("password cannot be empty")
+	}
+
+	var key []byte
+	if len(password) > 0 {
+		switch h.kdf {
+		case kdfArgon2id:
+			key = argon2.IDKey(password, h.salt, h.time, h.memory, h.threads, keyLen)
+		case kdfPBKDF2SHA256:
+			key = pbkdf2.Key(password, h.salt, int(h.time), keyLen, sha256.New)
+		default:
+			return nil, fmt.Errorf("unsupported key derivation function %d", h.kdf)
+		}
+	}
+
+	if keyFile != nil {
+		secret := append(append([]byte(nil), key...), keyFile...)
+		mixed, err := hkdf.Key(sha256.New, secret, h.salt, "expense-tracking keyfile", keyLen)
+		wipe(secret)
+		wipe(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mix in keyfile: %w", err)
+		}
+		key = mixed
+	}
+
+	return key, nil
+}
+
+// picks the header and data key for writing an encrypted database file - the header of an existing file is kept so its recovery key
+// keeps working and the key doesn't have to be derived again, a file with an older format or outdated parameters is upgraded
+func encHeaderForWrite(path string, password, keyFile []byte) (*encHeader, []byte, error) {
+	existing, err := readEncHeader(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing == nil || existing.version < encFormatVersion {
+		if _, err := os.Stat(path); err == nil {
+			log.Printf("upgrading %s to encrypted file format v%d with argon2id key derivation", path, encFormatVersion)
+		}
+		return newEncHeader(password, keyFile)
+	}
+
+	if existing.current() {
+		if dataKey := rememberedDataKey(existing); dataKey != nil {
+			return existing, dataKey, nil
+		}
+	}
+
+	// never overwrite a file the password can't open, e.g. one that was re-keyed by another instance
+	dataKey, err := existing.dataKey(password, keyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing.current() {
+		rememberDataKey(existing, dataKey)
+		return existing, dataKey, nil
+	}
+
+	log.Printf("upgrading key derivation parameters of %s", path)
+	rekeyed, err := existing.rekeyed(password, keyFile, dataKey)
+	if err != nil {
+		wipe(dataKey)
+		return nil, nil, err
+	}
+	return rekeyed, dataKey, nil
+}
+
+// recovery keys are written in groups of four characters that are easy to copy down, e.g. ABCD-EFGH-...
+var recoveryKeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// creates a new random recovery key in its printable form
+func generateRecoveryKey() (string, error) {
+	raw := make([]byte, recoveryKeyLen)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", fmt.Errorf("failed to generate recovery key: %w", err)
+	}
+
+	encoded := recoveryKeyEncoding.EncodeToString(raw)
+	var groups []string
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:min(i+4, len(encoded))])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// derives the key that wraps the data key from a recovery key, dashes, spaces and lowercase letters are accepted when it is typed in
+// the recovery key is random, so unlike a password it doesn't need a slow key derivation
+func recoveryKeyEncryptionKey(recoveryKey string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(recoveryKey)))
+	raw, err := recoveryKeyEncoding.DecodeString(normalized)
+	defer wipe(raw)
+	if err != nil || len(raw) != recoveryKeyLen {
+		return nil, ErrWrongRecoveryKey
+	}
+	return hkdf.Key(sha256.New, raw, nil, "expense-tracking recovery key", keyLen)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// helper to point the config at temporary encryption files
+func setupEncHeaderTestConfig(t *testing.T) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	config := &Config{
+		EncryptedDBFile: filepath.Join(dir, "transactions.enc"),
+		SaltFile:        filepath.Join(dir, "transactions.salt"),
+	}
+	original := globalConfig
+	SetGlobalConfig(config)
+	setUserPassword("testpassword")
+	t.Cleanup(func() {
+		clearUserPassword()
+		SetGlobalConfig(original)
+	})
+	return config
+}
+
+func TestEncHeaderRoundTrip(t *testing.T) {
+	header, _, err := newEncHeader([]byte("testpassword"), nil)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+	if !header.current() {
+		t.Errorf("Expected a new header to use the current parameters")
+	}
+
+	data := append(header.marshal(), []byte("ciphertext")...)
+	parsed, rest, err := parseEncHeader(data)
+	if err != nil {
+		t.Fatalf("Expected no error parsing header, got %v", err)
+	}
+	if !bytes.Equal(parsed.marshal(), header.marshal()) {
+		t.Errorf("Expected parsed header to match the written one")
+	}
+	if string(rest) != "ciphertext" {
+		t.Errorf("Expected the ciphertext after the header, got %q", rest)
+	}
+
+	// files from before the header are returned as they are
+	legacy := []byte("nonce and ciphertext")
+	if parsed, rest, err := parseEncHeader(legacy); err != nil || parsed != nil || !bytes.Equal(rest, legacy) {
+		t.Errorf("Expected no header for a legacy file, got %v, %q, %v", parsed, rest, err)
+	}
+}
+
+func TestParseInvalidEncHeader(t *testing.T) {
+	header, _, _ := newEncHeader([]byte("testpassword"), nil)
+	valid := header.marshal()
+
+	modify := func(change func(data []byte) []byte) []byte {
+		return change(append([]byte(nil), valid...))
+	}
+
+	cases := map[string][]byte{
+		"truncated":      valid[:12],
+		"newer version":  modify(func(d []byte) []byte { d[8] = encFormatVersion + 1; return d }),
+		"unknown kdf":    modify(func(d []byte) []byte { d[9] = 99; return d }),
+		"short salt":     modify(func(d []byte) []byte { d[19] = 4; return d }),
+		"missing salt":   valid[:encHeaderFixedLen+4],
+		"zero time":      modify(func(d []byte) []byte { copy(d[10:14], []byte{0, 0, 0, 0}); return d }),
+		"zero threads":   modify(func(d []byte) []byte { d[18] = 0; return d }),
+		"magic only":     encFileMagic,
+		"zero memory":    modify(func(d []byte) []byte { copy(d[14:18], []byte{0, 0, 0, 0}); return d }),
+		"pbkdf2 no time": modify(func(d []byte) []byte { d[9] = kdfPBKDF2SHA256; copy(d[10:14], []byte{0, 0, 0, 0}); return d }),
+		"no key slots":   valid[:encHeaderFixedLen+len(header.salt)],
+		"cut key slot":   valid[:len(valid)-10],
+		"no password":    modify(func(d []byte) []byte { d[encHeaderFixedLen+len(header.salt)+1] = keySlotRecovery; return d }),
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := parseEncHeader(data); err == nil {
+				t.Errorf("Expected error parsing header")
+			}
+		})
+	}
+}
+
+func TestEncryptDatabaseImageWritesHeader(t *testing.T) {
+	config := setupEncHeaderTestConfig(t)
+	image := []byte("test database content")
+
+	if err := encryptDatabaseImage(image); err != nil {
+		t.Fatalf("Expected no error encrypting, got %v", err)
+	}
+	first, _ := readEncHeader(config.EncryptedDBFile)
+	if first == nil || !first.current() {
+		t.Fatalf("Expected a current header, got %v", first)
+	}
+
+	// the file is a complete backup, no salt file is needed
+	if _, err := os.Stat(config.SaltFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no salt file to be written")
+	}
+
+	decrypted, err := decryptDatabaseImage()
+	if err != nil || !bytes.Equal(decrypted, image) {
+		t.Fatalf("Expected to decrypt the image, got %q (err %v)", decrypted, err)
+	}
+
+	// the header and with it the salt is kept on later saves
+	if err := encryptDatabaseImage(image); err != nil {
+		t.Fatalf("Expected no error encrypting again, got %v", err)
+	}
+	second, _ := readEncHeader(config.EncryptedDBFile)
+	if !bytes.Equal(first.marshal(), second.marshal()) {
+		t.Errorf("Expected the header to be kept across saves")
+	}
+
+	setUserPassword("wrongpassword")
+	if _, err := decryptDatabaseImage(); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected ErrWrongPassword, got %v", err)
+	}
+}
+
+func TestTamperedEncHeaderFailsDecryption(t *testing.T) {
+	config := setupEncHeaderTestConfig(t)
+	if err := encryptDatabaseImage([]byte("test database content")); err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	data, _ := os.ReadFile(config.EncryptedDBFile)
+	data[encHeaderFixedLen] ^= 0xff // first salt byte
+	if err := os.WriteFile(config.EncryptedDBFile, data, 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if _, err := decryptDatabaseImage(); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected a tampered header to fail authentication, got %v", err)
+	}
+}
+
+func TestLegacyEncryptedFileIsUpgradedOnSave(t *testing.T) {
+	config := setupEncHeaderTestConfig(t)
+	image := []byte("test database content")
+
+	// nonce | ciphertext with a PBKDF2 key from the separate salt file, like files written before the header
+	salt, err := generateSalt()
+	if err != nil {
+		t.Fatalf("Failed to generate salt: %v", err)
+	}
+	if err := saveSalt(salt); err != nil {
+		t.Fatalf("Failed to save salt: %v", err)
+	}
+	legacy, err := encryptTransactions(pbkdf2.Key([]byte("testpassword"), salt, iterations, keyLen, sha256.New), image)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if err := os.WriteFile(config.EncryptedDBFile, legacy, 0600); err != nil {
+		t.Fatalf("Failed to write legacy file: %v", err)
+	}
+
+	decrypted, err := decryptDatabaseImage()
+	if err != nil || !bytes.Equal(decrypted, image) {
+		t.Fatalf("Expected to decrypt the legacy file, got %q (err %v)", decrypted, err)
+	}
+
+	if err := encryptDatabaseImage(decrypted); err != nil {
+		t.Fatalf("Expected no error saving, got %v", err)
+	}
+	if header, _ := readEncHeader(config.EncryptedDBFile); header == nil || !header.current() {
+		t.Fatalf("Expected the saved file to have a current header, got %v", header)
+	}
+
+	// the upgraded file no longer depends on the salt file
+	if err := os.Remove(config.SaltFile); err != nil {
+		t.Fatalf("Failed to remove salt file: %v", err)
+	}
+	decrypted, err = decryptDatabaseImage()
+	if err != nil || !bytes.Equal(decrypted, image) {
+		t.Errorf("Expected to decrypt the upgraded file, got %q (err %v)", decrypted, err)
+	}
+}
+
+func TestOutdatedEncHeaderIsUpgradedOnSave(t *testing.T) {
+	config := setupEncHeaderTestConfig(t)
+	image := []byte("test database content")
+
+	// a version 1 file without key slots and with weaker parameters than the current ones
+	header, _, _ := newEncHeader([]byte("testpassword"), nil)
+	header.version, header.slots = 1, nil
+	header.kdf, header.time, header.memory, header.threads = kdfPBKDF2SHA256, 1000, 0, 0
+	key, err := header.deriveKey([]byte("testpassword"), nil)
+	if err != nil {
+		t.Fatalf("Failed to derive key: %v", err)
+	}
+	sealed, err := encryptTransactionsWithHeader(key, image, header.marshal())
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if err := os.WriteFile(config.EncryptedDBFile, append(header.marshal(), sealed...), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	decrypted, err := decryptDatabaseImage()
+	if err != nil || !bytes.Equal(decrypted, image) {
+		t.Fatalf("Expected to decrypt the pbkdf2 file, got %q (err %v)", decrypted, err)
+	}
+	if err := encryptDatabaseImage(decrypted); err != nil {
+		t.Fatalf("Expected no error saving, got %v", err)
+	}
+
+	upgraded, _ := readEncHeader(config.EncryptedDBFile)
+	if upgraded == nil || !upgraded.current() || bytes.Equal(upgraded.salt, header.salt) {
+		t.Errorf("Expected a current header with a new salt, got %v", upgraded)
+	}
+}
@@ -8,13 +8,13 @@ import (
 func TestSetUserPassword(t *testing.T) {
 	// Test setting password
 	setUserPassword("testpassword")
-	if userPassword != "testpassword" {
+	if string(userPassword) != "testpassword" {
 		t.Errorf("Expected userPassword to be 'testpassword', got %s", userPassword)
 	}
 
 	// Test clearing password
 	clearUserPassword()
-	if userPassword != "" {
+	if userPassword != nil {
 		t.Errorf("Expected userPassword to be empty after clear, got %s", userPassword)
 	}
 }
@@ -130,13 +130,13 @@ func TestDeriveEncryptionKey(t *testing.T) {
 	SetGlobalConfig(testConfig)
 
 	// Test deriving key with empty password
-	_, err = deriveEncryptionKey("")
+	_, err = deriveEncryptionKey(nil)
 	if err == nil {
 		t.Errorf("Expected error with empty password")
 	}
 
 	// Test deriving key with valid password
-	key, err := deriveEncryptionKey("testpassword")
+	key, err := deriveEncryptionKey([]byte("testpassword"))
 	if err != nil {
 		t.Errorf("Expected no error deriving key, got %v", err)
 	}
@@ -145,7 +145,7 @@ func TestDeriveEncryptionKey(t *testing.T) {
 	}
 
 	// Test that same password produces same key
-	key2, err := deriveEncryptionKey("testpassword")
+	key2, err := deriveEncryptionKey([]byte("testpassword"))
 	if err != nil {
 		t.Errorf("Expected no error deriving key second time, got %v", err)
 	}
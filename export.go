@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ExportLedger    = "ledger"
+	ExportHledger   = "hledger"
+	ExportBeancount = "beancount"
+)
+
+// top level account of each transaction type, the category becomes the sub account - e.g. Expenses:Food
+var exportAccountRoots = map[string]string{
+	"income":     "Income",
+	"expense":    "Expenses",
+	"investment": "Assets:Investments",
+}
+
+var (
+	exportCurrencyPattern     = regexp.MustCompile(`^[A-Z][A-Z0-9'._-]{0,22}[A-Z0-9]$`)
+	exportAccountInvalidChars = regexp.MustCompile(`[^A-Za-z0-9-]+`)
+)
+
+// a single transaction with its period flattened, ready to be written as a journal entry
+type journalEntry struct {
+	Date        time.Time
+	Type        string
+	Account     string
+	Amount      float64
+	Description string
+	Id          string
+}
+
+// an inclusive year/month range, a zero value on either side means unbounded
+type exportRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// parses --from/--to values, either a year (2024) or a year and month (2024-03)
+func parseExportRange(from, to string) (exportRange, error) {
+	var r exportRange
+	var err error
+	if from != "" {
+		if r.From, err = parseExportPeriod(from, false); err != nil {
+			return r, err
+		}
+	}
+	if to != "" {
+		if r.To, err = parseExportPeriod(to, true); err != nil {
+			return r, err
+		}
+	}
+	if !r.From.IsZero() && !r.To.IsZero() && r.From.After(r.To) {
+		return r, fmt.Errorf("--from %s is after --to %s", from, to)
+	}
+	return r, nil
+}
+
+// helper to parse a single period, a year on its own means january for the start and december for the end of a range
+func parseExportPeriod(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid period %q, expected YYYY or YYYY-MM", value)
+	}
+	if end {
+		t = t.AddDate(0, 11, 0)
+	}
+	return t, nil
+}
+
+// reports whether the first day of a month falls within the range
+func (r exportRange) contains(t time.Time) bool {
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && t.After(r.To) {
+		return false
+	}
+	return true
+}
+
+// flattens the transaction history into journal entries in chronological order
+func collectJournalEntries(transactions TransactionHistory, r exportRange) ([]journalEntry, error) {
+	var entries []journalEntry
+	for year, months := range transactions {
+		y, err := strconv.Atoi(year)
+		if err != nil {
+			return nil, fmt.Errorf("invalid year %q in transactions: %w", year, err)
+		}
+
+		for month, types := range months {
+			m, ok := monthOrder[month]
+			if !ok {
+				return nil, fmt.Errorf("invalid month %q in transactions", month)
+			}
+			period := time.Date(y, time.Month(m), 1, 0, 0, 0, 0, time.UTC)
+			if !r.contains(period) {
+				continue
+			}
+
+			for txType, txList := range types {
+				for _, tx := range txList {
+					date := tx.Date
+					if date.IsZero() {
+						date = period
+					}
+					entries = append(entries, journalEntry{
+						Date:        date,
+						Type:        txType,
+						Account:     exportAccount(txType, tx.Category),
+						Amount:      tx.Amount,
+						Description: tx.Description,
+						Id:          tx.Id,
+					})
+				}
+			}
+		}
+	}
+
+	// income first so the running balance of the bank account doesn't dip below zero on the same day
+	typeOrder := map[string]int{"income": 0, "expense": 1, "investment": 2}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if typeOrder[a.Type] != typeOrder[b.Type] {
+			return typeOrder[a.Type] < typeOrder[b.Type]
+		}
+		return a.Id < b.Id
+	})
+
+	return entries, nil
+}
+
+// helper to map a transaction type and category onto an account name, e.g. investment/privateEquity -> Assets:Investments:PrivateEquity
+// a subcategory becomes a sub-account of its parent, e.g. expense food/groceries -> Expenses:Food:Groceries
+func exportAccount(txType, category string) string {
+	root, ok := exportAccountRoots[txType]
+	if !ok {
+		root = "Expenses"
+	}
+
+	var components []string
+	for _, part := range strings.Split(category, CategorySeparator) {
+		if name := exportAccountComponent(part); name != "" {
+			components = append(components, name)
+		}
+	}
+	if len(components) == 0 {
+		return root + ":Uncategorized"
+	}
+	return root + ":" + strings.Join(components, ":")
+}
+
+// helper to turn a category name into an account component, empty if nothing usable is left
+func exportAccountComponent(name string) string {
+	// account components have to start with an upper case letter and can only contain letters, digits and dashes
+	name = exportAccountInvalidChars.ReplaceAllString(strings.TrimSpace(name), "-")
+	name = strings.Trim(name, "-")
+	if name == "" {
+		return ""
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "X" + name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// writes the entries as a ledger journal, hledger reads the same format
+func writeLedgerJournal(out io.Writer, entries []journalEntry, bankAccount, currency string) error {
+	w := bufio.NewWriter(out)
+	for i, e := range entries {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		payee := e.Description
+		if payee == "" {
+			payee = e.Account
+		}
+		fmt.Fprintf(w, "%s %s\n", e.Date.Format("2006-01-02"), strings.Join(strings.Fields(payee), " "))
+		fmt.Fprintf(w, "    ; id: %s\n", e.Id)
+
+		// income moves money into the bank account, expenses and investments move it out
+		amount := e.Amount
+		if e.Type == "income" {
+			amount = -amount
+		}
+		fmt.Fprintf(w, "    %-40s %12.2f %s\n", e.Account, amount, currency)
+		fmt.Fprintf(w, "    %-40s %12.2f %s\n", bankAccount, -amount, currency)
+	}
+	return w.Flush()
+}
+
+// writes the entries as a beancount file, beancount needs every account opened before it is used
+func writeBeancountJournal(out io.Writer, entries []journalEntry, bankAccount, currency string) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "option \"operating_currency\" \"%s\"\n", currency)
+
+	if len(entries) > 0 {
+		accounts := map[string]bool{bankAccount: true}
+		for _, e := range entries {
+			accounts[e.Account] = true
+		}
+		names := make([]string, 0, len(accounts))
+		for a := range accounts {
+			names = append(names, a)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintln(w)
+		opened := entries[0].Date.Format("2006-01-02")
+		for _, a := range names {
+			fmt.Fprintf(w, "%s open %s %s\n", opened, a, currency)
+		}
+	}
+
+	for _, e := range entries {
+		amount := e.Amount
+		if e.Type == "income" {
+			amount = -amount
+		}
+
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s * %s\n", e.Date.Format("2006-01-02"), beancountString(e.Description))
+		fmt.Fprintf(w, "  id: %s\n", beancountString(e.Id))
+		fmt.Fprintf(w, "  %-40s %12.2f %s\n", e.Account, amount, currency)
+		fmt.Fprintf(w, "  %-40s %12.2f %s\n", bankAccount, -amount, currency)
+	}
+	return w.Flush()
+}
+
+// helper to quote a beancount string literal
+func beancountString(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// helper to validate an account name given on the command line, e.g. Assets:Bank:Checking
+func validateExportAccount(account string) error {
+	parts := strings.Split(account, ":")
+	if len(parts) < 2 {
+		return fmt.Errorf("account %q needs at least two components, e.g. Assets:Bank", account)
+	}
+	for _, p := range parts {
+		if p == "" || exportAccountInvalidChars.MatchString(p) || p[0] < 'A' || p[0] > 'Z' {
+			return fmt.Errorf("invalid account %q, components have to start with an upper case letter and contain only letters, digits and dashes", account)
+		}
+	}
+	return nil
+}
+
+func cliExportSetup(fs *flag.FlagSet) func(out io.Writer) error {
+	format := fs.String("format", ExportLedger, fmt.Sprintf("export format - %s, %s or %s", ExportLedger, ExportHledger, ExportBeancount))
+	from := fs.String("from", "", "first month to export as YYYY-MM or YYYY (default: all)")
+	to := fs.String("to", "", "last month to export as YYYY-MM or YYYY (default: all)")
+	bankAccount := fs.String("account", "Assets:Bank", "balancing account the money goes in and out of")
+	currency := fs.String("currency", "EUR", "commodity used for the amounts")
+	output := fs.String("output", "", "write the journal to this file instead of stdout")
+
+	return func(out io.Writer) error {
+		r, err := parseExportRange(*from, *to)
+		if err != nil {
+			return err
+		}
+		if err := validateExportAccount(*bankAccount); err != nil {
+			return err
+		}
+		if !exportCurrencyPattern.MatchString(*currency) {
+			return fmt.Errorf("invalid currency %q, expected an upper case commodity like EUR", *currency)
+		}
+
+		var write func(io.Writer, []journalEntry, string, string) error
+		switch strings.ToLower(*format) {
+		case ExportLedger, ExportHledger:
+			write = writeLedgerJournal
+		case ExportBeancount:
+			write = writeBeancountJournal
+		default:
+			return fmt.Errorf("unsupported export format %q", *format)
+		}
+
+		// the range covers every day of its last month
+		to := r.To
+		if !to.IsZero() {
+			to = to.AddDate(0, 1, -1)
+		}
+		transactions, err := LoadTransactionsInRange(r.From, to)
+		if err != nil {
+			return fmt.Errorf("failed to load transactions: %w", err)
+		}
+		entries, err := collectJournalEntries(transactions, r)
+		if err != nil {
+			return err
+		}
+
+		if *output == "" {
+			return write(out, entries, *bankAccount, *currency)
+		}
+
+		// the export is plaintext, so it's only readable by the current user
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		if err := write(f, entries, *bankAccount, *currency); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write export file: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close export file: %w", err)
+		}
+		fmt.Fprintf(out, "exported %d transactions to %s\n", len(entries), *output)
+		return nil
+	}
+}
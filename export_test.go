@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportAccount(t *testing.T) {
+	tests := []struct {
+		txType, category, expected string
+	}{
+		{"expense", "food", "Expenses:Food"},
+		{"income", "salary", "Income:Salary"},
+		{"investment", "privateEquity", "Assets:Investments:PrivateEquity"},
+		{"expense", "home & garden", "Expenses:Home-garden"},
+		{"investment", "p2p", "Assets:Investments:P2p"},
+		{"expense", "", "Expenses:Uncategorized"},
+		{"expense", "food/groceries", "Expenses:Food:Groceries"},
+		{"investment", "funds/world etf", "Assets:Investments:Funds:World-etf"},
+		{"expense", "/", "Expenses:Uncategorized"},
+	}
+	for _, tt := range tests {
+		if got := exportAccount(tt.txType, tt.category); got != tt.expected {
+			t.Errorf("exportAccount(%q, %q) = %q, expected %q", tt.txType, tt.category, got, tt.expected)
+		}
+	}
+}
+
+func TestParseExportRange(t *testing.T) {
+	r, err := parseExportRange("2024", "2024-02")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if r.From.Format("2006-01") != "2024-01" || r.To.Format("2006-01") != "2024-02" {
+		t.Errorf("Unexpected range %v - %v", r.From, r.To)
+	}
+
+	r, err = parseExportRange("", "2023")
+	if err != nil || !r.From.IsZero() || r.To.Format("2006-01") != "2023-12" {
+		t.Errorf("Expected open range until december 2023, got %v - %v (err %v)", r.From, r.To, err)
+	}
+
+	if _, err := parseExportRange("2024-05", "2024-01"); err == nil {
+		t.Errorf("Expected error when --from is after --to")
+	}
+	if _, err := parseExportRange("march", ""); err == nil {
+		t.Errorf("Expected error for invalid period")
+	}
+}
+
+func TestCliExportFormats(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	seedReportTransactions(t)
+
+	out, err := runTestCliCommand(t, cliExportSetup, "--format", "ledger")
+	if err != nil {
+		t.Fatalf("Expected no error for ledger export, got %v", err)
+	}
+	// oldest transaction first, income before expenses in the same month
+	first := strings.Index(out, "2023-12-01 dinner")
+	salary := strings.Index(out, "2024-01-01 salary")
+	groceries := strings.Index(out, "2024-01-01 groceries")
+	if first < 0 || salary < first || groceries < salary {
+		t.Errorf("Expected entries in chronological order, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Income:Salary") || !strings.Contains(out, "-1000.00 EUR") || !strings.Contains(out, "Assets:Investments:Funds") {
+		t.Errorf("Expected mapped accounts and amounts, got:\n%s", out)
+	}
+
+	out, err = runTestCliCommand(t, cliExportSetup, "--format", "beancount", "--from", "2024-02", "--account", "Assets:Bank:Checking", "--currency", "USD")
+	if err != nil {
+		t.Fatalf("Expected no error for beancount export, got %v", err)
+	}
+	if !strings.Contains(out, `option "operating_currency" "USD"`) || !strings.Contains(out, "2024-02-01 open Assets:Bank:Checking USD") {
+		t.Errorf("Expected beancount options and open directives, got:\n%s", out)
+	}
+	if !strings.Contains(out, `2024-02-01 * "rent"`) || strings.Contains(out, "groceries") {
+		t.Errorf("Expected only february entries, got:\n%s", out)
+	}
+
+	path := filepath.Join(t.TempDir(), "export.journal")
+	if _, err := runTestCliCommand(t, cliExportSetup, "--format", "hledger", "--output", path); err != nil {
+		t.Fatalf("Expected no error exporting to a file, got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "Expenses:Housing") {
+		t.Errorf("Expected journal in output file, got %q (err %v)", data, err)
+	}
+
+	if _, err := runTestCliCommand(t, cliExportSetup, "--format", "gnucash"); err == nil {
+		t.Errorf("Expected error for unsupported format")
+	}
+	if _, err := runTestCliCommand(t, cliExportSetup, "--account", "bank"); err == nil {
+		t.Errorf("Expected error for invalid account")
+	}
+}
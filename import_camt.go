@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// subset of an ISO 20022 camt.053 bank to customer statement that is needed for the import
+// element names are matched without namespace so all camt.053 versions (001.02 - 001.13) can be read
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	Iban    string      `xml:"Acct>Id>IBAN"`
+	OtherId string      `xml:"Acct>Id>Othr>Id"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtEntry struct {
+	NtryRef   string `xml:"NtryRef"`
+	Amount    string `xml:"Amt"`
+	CdtDbtInd string `xml:"CdtDbtInd"`
+	Status    struct {
+		Text string `xml:",chardata"` // up to version 001.08 the status is a plain code
+		Code string `xml:"Cd"`        // newer versions wrap it in a Cd element
+	} `xml:"Sts"`
+	BookingDate string `xml:"BookgDt>Dt"`
+	BookingTime string `xml:"BookgDt>DtTm"`
+	ValueDate   string `xml:"ValDt>Dt"`
+	AcctSvcrRef string `xml:"AcctSvcrRef"`
+	AddtlInf    string `xml:"AddtlNtryInf"`
+	Details     []struct {
+		AcctSvcrRef  string   `xml:"Refs>AcctSvcrRef"`
+		Unstructured []string `xml:"RmtInf>Ustrd"`
+		Structured   []string `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+		Creditor     string   `xml:"RltdPties>Cdtr>Nm"`
+		CreditorPty  string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+		Debtor       string   `xml:"RltdPties>Dbtr>Nm"`
+		DebtorPty    string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+// parses an ISO 20022 camt.053 statement, only booked entries are imported
+func parseCamtStatement(data []byte) ([]importedEntry, error) {
+	var doc camtDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to read camt.053 statement: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, fmt.Errorf("not a camt.053 statement, missing BkToCstmrStmt/Stmt")
+	}
+
+	var entries []importedEntry
+	for _, stmt := range doc.Statements {
+		account := stmt.Iban
+		if account == "" {
+			account = stmt.OtherId
+		}
+
+		occurrences := make(map[string]int)
+		for _, ntry := range stmt.Entries {
+			status := strings.TrimSpace(ntry.Status.Code)
+			if status == "" {
+				status = strings.TrimSpace(ntry.Status.Text)
+			}
+			if status != "BOOK" {
+				continue // pending and informational entries are not final
+			}
+
+			rawDate := ntry.BookingDate
+			if rawDate == "" && len(ntry.BookingTime) >= 10 {
+				rawDate = ntry.BookingTime[:10]
+			}
+			if rawDate == "" {
+				rawDate = ntry.ValueDate
+			}
+			date, err := time.Parse("2006-01-02", strings.TrimSpace(rawDate))
+			if err != nil {
+				return nil, fmt.Errorf("invalid camt.053 booking date %q", rawDate)
+			}
+
+			amount, err := strconv.ParseFloat(strings.TrimSpace(ntry.Amount), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid camt.053 amount %q", ntry.Amount)
+			}
+
+			// credits are money in (income), debits are money out (expense)
+			switch strings.TrimSpace(ntry.CdtDbtInd) {
+			case "CRDT":
+			case "DBIT":
+				amount = -amount
+			default:
+				return nil, fmt.Errorf("invalid camt.053 credit/debit indicator %q", ntry.CdtDbtInd)
+			}
+
+			var remittance, counterparty []string
+			ref := strings.TrimSpace(ntry.AcctSvcrRef)
+			for _, d := range ntry.Details {
+				remittance = append(remittance, d.Unstructured...)
+				remittance = append(remittance, d.Structured...)
+
+				// the counterparty is the creditor for outgoing payments and the debtor for incoming ones
+				if amount < 0 {
+					counterparty = append(counterparty, d.Creditor, d.CreditorPty)
+				} else {
+					counterparty = append(counterparty, d.Debtor, d.DebtorPty)
+				}
+
+				if ref == "" {
+					ref = strings.TrimSpace(d.AcctSvcrRef)
+				}
+			}
+			if ref == "" {
+				ref = strings.TrimSpace(ntry.NtryRef)
+			}
+
+			description := joinNonEmpty(remittance, " ")
+			if description == "" {
+				description = joinNonEmpty(append(counterparty, ntry.AddtlInf), " ")
+			}
+
+			// entries without a bank reference get one built from their content
+			if ref == "" {
+				key := fmt.Sprintf("%s|%.2f|%s", date.Format("2006-01-02"), amount, description)
+				occurrences[key]++
+				sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, occurrences[key])))
+				ref = hex.EncodeToString(sum[:12])
+			}
+
+			entries = append(entries, importedEntry{
+				Date:        date,
+				Amount:      amount,
+				Description: description,
+				Ref:         "camt:" + account + ":" + ref,
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+// helper to join the non empty, trimmed values
+func joinNonEmpty(values []string, sep string) string {
+	var parts []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCamtStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+<BkToCstmrStmt>
+<GrpHdr><MsgId>MSG1</MsgId></GrpHdr>
+<Stmt>
+<Id>STMT1</Id>
+<Acct><Id><IBAN>DE89370400440532013000</IBAN></Id></Acct>
+<Ntry>
+<Amt Ccy="EUR">42.10</Amt>
+<CdtDbtInd>DBIT</CdtDbtInd>
+<Sts>BOOK</Sts>
+<BookgDt><Dt>2024-03-05</Dt></BookgDt>
+<ValDt><Dt>2024-03-04</Dt></ValDt>
+<AcctSvcrRef>REF-1</AcctSvcrRef>
+<NtryDtls><TxDtls>
+<RltdPties><Cdtr><Nm>Supermarket GmbH</Nm></Cdtr></RltdPties>
+<RmtInf><Ustrd>Einkauf Lebensmittel</Ustrd><Ustrd>Filiale 12</Ustrd></RmtInf>
+</TxDtls></NtryDtls>
+</Ntry>
+<Ntry>
+<Amt Ccy="EUR">3000.00</Amt>
+<CdtDbtInd>CRDT</CdtDbtInd>
+<Sts><Cd>BOOK</Cd></Sts>
+<BookgDt><DtTm>2024-03-25T08:00:00+01:00</DtTm></BookgDt>
+<NtryDtls><TxDtls>
+<RltdPties><Dbtr><Pty><Nm>ACME AG</Nm></Pty></Dbtr></RltdPties>
+</TxDtls></NtryDtls>
+</Ntry>
+<Ntry>
+<Amt Ccy="EUR">10.00</Amt>
+<CdtDbtInd>DBIT</CdtDbtInd>
+<Sts>PDNG</Sts>
+<BookgDt><Dt>2024-03-31</Dt></BookgDt>
+</Ntry>
+</Stmt>
+</BkToCstmrStmt>
+</Document>
+`
+
+const testMt940Statement = `{1:F01BANKDEFFAXXX0000000000}{2:O940}{4:
+:20:STARTUMS
+:25:37040044/0532013000
+:28C:00001/001
+:60F:C240301EUR1000,00
+:61:2403050305D42,10NTRFNONREF//B4C05ABC123
+:86:106?00KARTENZAHLUNG?20SVWZ+Einkauf Lebensm?21ittel Filiale 12?32Supermarket Gmb
+?33H
+:61:2403250325C3000,00NTRFNONREF
+:86:166?00GUTSCHRIFT?32ACME AG
+:61:2312311229RD5,00NMSCNONREF
+:86:Storno Gebuehr
+:62F:C240331EUR3952,90
+-}
+`
+
+func TestParseCamtStatement(t *testing.T) {
+	entries, err := parseCamtStatement([]byte(testCamtStatement))
+	if err != nil {
+		t.Fatalf("Expected no error parsing camt.053 statement, got %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 booked entries, got %d: %+v", len(entries), entries)
+	}
+
+	if !entries[0].Date.Equal(time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)) || entries[0].Amount != -42.10 {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[0].Description != "Einkauf Lebensmittel Filiale 12" || entries[0].Ref != "camt:DE89370400440532013000:REF-1" {
+		t.Errorf("Unexpected first entry description or ref %+v", entries[0])
+	}
+
+	// without remittance info the counterparty is used, without a bank reference one is built from the content
+	if entries[1].Amount != 3000 || entries[1].Date.Day() != 25 || entries[1].Description != "ACME AG" {
+		t.Errorf("Unexpected second entry %+v", entries[1])
+	}
+	if !strings.HasPrefix(entries[1].Ref, "camt:DE89370400440532013000:") || entries[1].Ref == entries[0].Ref {
+		t.Errorf("Unexpected second entry ref %q", entries[1].Ref)
+	}
+
+	if _, err := parseCamtStatement([]byte("<Document></Document>")); err == nil {
+		t.Errorf("Expected error for a document without statements")
+	}
+	if _, err := parseCamtStatement([]byte(strings.Replace(testCamtStatement, "DBIT", "XXXX", 1))); err == nil {
+		t.Errorf("Expected error for an invalid credit/debit indicator")
+	}
+}
+
+func TestParseMt940Statement(t *testing.T) {
+	entries, err := parseMt940Statement([]byte(testMt940Statement))
+	if err != nil {
+		t.Fatalf("Expected no error parsing mt940 statement, got %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+
+	if !entries[0].Date.Equal(time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)) || entries[0].Amount != -42.10 {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[0].Description != "Einkauf Lebensmittel Filiale 12" || entries[0].Ref != "mt940:37040044/0532013000:20240305:B4C05ABC123" {
+		t.Errorf("Unexpected first entry description or ref %+v", entries[0])
+	}
+
+	if entries[1].Amount != 3000 || entries[1].Description != "ACME AG" {
+		t.Errorf("Unexpected second entry %+v", entries[1])
+	}
+
+	// a reversed debit is money in, the booking date falls in the year before the value date
+	if entries[2].Amount != 5 || !entries[2].Date.Equal(time.Date(2023, time.December, 29, 0, 0, 0, 0, time.UTC)) || entries[2].Description != "Storno Gebuehr" {
+		t.Errorf("Unexpected reversal entry %+v", entries[2])
+	}
+
+	// refs are stable between imports of the same statement
+	again, err := parseMt940Statement([]byte(testMt940Statement))
+	if err != nil {
+		t.Fatalf("Expected no error parsing mt940 statement again, got %v", err)
+	}
+	if again[1].Ref != entries[1].Ref {
+		t.Errorf("Expected stable refs, got %q and %q", entries[1].Ref, again[1].Ref)
+	}
+
+	// latin-1 exports are decoded
+	latin1, err := parseMt940Statement([]byte(":25:ACC\n:61:240305D1,00NTRFNONREF\n:86:Geb\xfchr\n"))
+	if err != nil || len(latin1) != 1 || latin1[0].Description != "Gebühr" {
+		t.Errorf("Expected latin-1 statement to be decoded, got %+v (err %v)", latin1, err)
+	}
+
+	if _, err := parseMt940Statement([]byte(":25:ACC\n:61:garbage\n")); err == nil {
+		t.Errorf("Expected error for an invalid statement line")
+	}
+	if _, err := parseMt940Statement([]byte("Date,Amount\n")); err == nil {
+		t.Errorf("Expected error for non mt940 content")
+	}
+}
+
+func TestTruncateDescription(t *testing.T) {
+	long := strings.Repeat("a", DescriptionMaxCharLength-1) + "ü and more"
+	got := truncateDescription(long)
+	if got != strings.Repeat("a", DescriptionMaxCharLength-1) {
+		t.Errorf("Expected truncation before the multi-byte character, got %q", got)
+	}
+	if got := truncateDescription("  Rent \n March "); got != "Rent March" {
+		t.Errorf("Expected whitespace to be collapsed, got %q", got)
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// supported sign conventions for the amount column of a csv statement
+const (
+	SignNegativeExpense = "negative-expense" // negative amounts are expenses, positive amounts are income (most bank accounts)
+	SignPositiveExpense = "positive-expense" // positive amounts are expenses, negative amounts are income (most credit cards)
+	SignDebitCredit     = "debit-credit"     // separate debit (expense) and credit (income) columns
+)
+
+// supported text encodings of csv statements
+var csvEncodings = map[string]encoding.Encoding{
+	"windows-1250": charmap.Windows1250,
+	"windows-1252": charmap.Windows1252,
+	"iso-8859-1":   charmap.ISO8859_1,
+	"iso-8859-15":  charmap.ISO8859_15,
+}
+
+// named column mapping used to read the csv export of a specific bank
+// columns are referenced either by their header name or by their 1-based position
+type csvImportProfile struct {
+	Name                   string `json:"name"`
+	Delimiter              string `json:"delimiter"`
+	HasHeader              bool   `json:"hasHeader"`
+	SkipRows               int    `json:"skipRows"` // rows before the header (or data), some banks add account info on top
+	DateColumn             string `json:"dateColumn"`
+	DateFormat             string `json:"dateFormat"` // Go reference layout, e.g. 02.01.2006
+	AmountColumn           string `json:"amountColumn"`
+	DebitColumn            string `json:"debitColumn"`
+	CreditColumn           string `json:"creditColumn"`
+	SignConvention         string `json:"signConvention"`
+	DescriptionColumn      string `json:"descriptionColumn"`
+	CategoryColumn         string `json:"categoryColumn"`
+	DecimalSeparator       string `json:"decimalSeparator"`
+	ThousandsSeparator     string `json:"thousandsSeparator"`
+	Encoding               string `json:"encoding"`
+	DefaultExpenseCategory string `json:"defaultExpenseCategory"`
+	DefaultIncomeCategory  string `json:"defaultIncomeCategory"`
+}
+
+// makes sure a profile is complete and fills in defaults for optional settings
+func (p *csvImportProfile) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("profile name cannot be empty")
+	}
+	if p.Delimiter == "" {
+		p.Delimiter = ","
+	}
+	if p.Delimiter == `\t` || p.Delimiter == "tab" {
+		p.Delimiter = "\t"
+	}
+	if len([]rune(p.Delimiter)) != 1 {
+		return fmt.Errorf("delimiter should be a single character, got %q", p.Delimiter)
+	}
+	if p.DateFormat == "" {
+		p.DateFormat = "2006-01-02"
+	}
+	if p.SignConvention == "" {
+		p.SignConvention = SignNegativeExpense
+	}
+	if p.DecimalSeparator == "" {
+		p.DecimalSeparator = "."
+	}
+	if p.DecimalSeparator == p.ThousandsSeparator {
+		return fmt.Errorf("decimal and thousands separators cannot be the same")
+	}
+	if p.Encoding == "" {
+		p.Encoding = "utf-8"
+	}
+	p.Encoding = strings.ToLower(p.Encoding)
+	if _, ok := csvEncodings[p.Encoding]; !ok && p.Encoding != "utf-8" {
+		return fmt.Errorf("unsupported encoding %q", p.Encoding)
+	}
+	if p.SkipRows < 0 {
+		return fmt.Errorf("skip rows cannot be negative")
+	}
+
+	if p.DateColumn == "" {
+		return fmt.Errorf("date column is required")
+	}
+	switch p.SignConvention {
+	case SignNegativeExpense, SignPositiveExpense:
+		if p.AmountColumn == "" {
+			return fmt.Errorf("amount column is required for sign convention %s", p.SignConvention)
+		}
+	case SignDebitCredit:
+		if p.DebitColumn == "" || p.CreditColumn == "" {
+			return fmt.Errorf("debit and credit columns are required for sign convention %s", p.SignConvention)
+		}
+	default:
+		return fmt.Errorf("unsupported sign convention %q, supported are %s, %s and %s", p.SignConvention, SignNegativeExpense, SignPositiveExpense, SignDebitCredit)
+	}
+
+	if p.DefaultExpenseCategory != "" && !isAllowedCategory("expense", p.DefaultExpenseCategory) {
+		return fmt.Errorf("invalid default expense category: %s", p.DefaultExpenseCategory)
+	}
+	if p.DefaultIncomeCategory != "" && !isAllowedCategory("income", p.DefaultIncomeCategory) {
+		return fmt.Errorf("invalid default income category: %s", p.DefaultIncomeCategory)
+	}
+
+	return nil
+}
+
+// stores (or replaces) a named import profile in the db
+func saveImportProfile(p csvImportProfile) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode import profile: %w", err)
+	}
+
+	if _, err := db.Exec(`INSERT OR REPLACE INTO import_profiles (name, profile) VALUES (?, ?)`, p.Name, string(data)); err != nil {
+		return fmt.Errorf("failed to save import profile %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+// reads a named import profile from the db
+func loadImportProfile(name string) (csvImportProfile, error) {
+	var p csvImportProfile
+	var data string
+
+	err := db.QueryRow(`SELECT profile FROM import_profiles WHERE name = ?`, name).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, fmt.Errorf("import profile %s not found", name)
+	}
+	if err != nil {
+		return p, fmt.Errorf("failed to load import profile %s: %w", name, err)
+	}
+
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return p, fmt.Errorf("failed to decode import profile %s: %w", name, err)
+	}
+	return p, nil
+}
+
+// lists all saved import profiles ordered by name
+func listImportProfiles() ([]csvImportProfile, error) {
+	rows, err := db.Query(`SELECT profile FROM import_profiles ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list import profiles: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []csvImportProfile
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("db scan failed during list import profiles: %w", err)
+		}
+		var p csvImportProfile
+		if err := json.Unmarshal([]byte(data), &p); err != nil {
+			return nil, fmt.Errorf("failed to decode import profile: %w", err)
+		}
+		profiles = append(profiles, p)
+	}
+
+	return profiles, rows.Err()
+}
+
+// removes a named import profile from the db
+func deleteImportProfile(name string) error {
+	res, err := db.Exec(`DELETE FROM import_profiles WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete import profile %s: %w", name, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("import profile %s not found", name)
+	}
+	return nil
+}
+
+// parses a csv bank statement into entries according to the column mapping of the profile
+func parseCsvStatement(data []byte, p csvImportProfile) ([]importedEntry, error) {
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid import profile: %w", err)
+	}
+
+	if enc, ok := csvEncodings[p.Encoding]; ok {
+		decoded, err := enc.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode statement as %s: %w", p.Encoding, err)
+		}
+		data = decoded
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff")) // utf-8 byte order mark
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = []rune(p.Delimiter)[0]
+	r.FieldsPerRecord = -1 // banks often add summary rows with a different number of columns
+	r.LazyQuotes = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv statement: %w", err)
+	}
+
+	if p.SkipRows >= len(records) {
+		return nil, nil
+	}
+	records = records[p.SkipRows:]
+
+	var header []string
+	if p.HasHeader && len(records) > 0 {
+		header, records = records[0], records[1:]
+	}
+
+	// resolves a column reference into an index, unset optional columns resolve to -1
+	column := func(ref string) (int, error) {
+		if ref == "" {
+			return -1, nil
+		}
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(ref)) {
+				return i, nil
+			}
+		}
+		if n, err := strconv.Atoi(ref); err == nil && n > 0 {
+			return n - 1, nil
+		}
+		return -1, fmt.Errorf("column %q not found in statement", ref)
+	}
+
+	cols := make(map[string]int)
+	for name, ref := range map[string]string{
+		"date":        p.DateColumn,
+		"amount":      p.AmountColumn,
+		"debit":       p.DebitColumn,
+		"credit":      p.CreditColumn,
+		"description": p.DescriptionColumn,
+		"category":    p.CategoryColumn,
+	} {
+		if cols[name], err = column(ref); err != nil {
+			return nil, err
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i := cols[name]
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var entries []importedEntry
+	for i, record := range records {
+		line := i + 1 + p.SkipRows
+		if p.HasHeader {
+			line++
+		}
+
+		rawDate := field(record, "date")
+		if rawDate == "" {
+			continue // empty and summary rows
+		}
+		date, err := time.Parse(p.DateFormat, rawDate)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date %q, expected format %s", line, rawDate, p.DateFormat)
+		}
+
+		var amount float64
+		switch p.SignConvention {
+		case SignDebitCredit:
+			debit, err := parseStatementAmount(field(record, "debit"), p.DecimalSeparator, p.ThousandsSeparator)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			credit, err := parseStatementAmount(field(record, "credit"), p.DecimalSeparator, p.ThousandsSeparator)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			// some banks put debits in as negative numbers, others as positive ones
+			if debit < 0 {
+				debit = -debit
+			}
+			amount = credit - debit
+		default:
+			amount, err = parseStatementAmount(field(record, "amount"), p.DecimalSeparator, p.ThousandsSeparator)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if p.SignConvention == SignPositiveExpense {
+				amount = -amount
+			}
+		}
+
+		entries = append(entries, importedEntry{
+			Date:        date,
+			Amount:      amount,
+			Description: field(record, "description"),
+			Category:    field(record, "category"),
+		})
+	}
+
+	return entries, nil
+}
+
+// parses an amount as written in a bank statement, e.g. "1.234,56", "-12.30 EUR" or "12,30-"
+func parseStatementAmount(raw, decimalSeparator, thousandsSeparator string) (float64, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return 0, nil
+	}
+
+	s = strings.NewReplacer("€", "", "EUR", "", "eur", "", " ", "", "\u00a0", "").Replace(s)
+	if thousandsSeparator != "" {
+		s = strings.ReplaceAll(s, thousandsSeparator, "")
+	}
+	if decimalSeparator != "." {
+		s = strings.ReplaceAll(s, decimalSeparator, ".")
+	}
+
+	// trailing minus sign used by some banks
+	if strings.HasSuffix(s, "-") {
+		s = "-" + strings.TrimSuffix(s, "-")
+	}
+
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", raw)
+	}
+	return amount, nil
+}
+
+func cliImportProfileSetup(fs *flag.FlagSet) func(out io.Writer) error {
+	var p csvImportProfile
+	list := fs.Bool("list", false, "list the saved profiles")
+	remove := fs.Bool("delete", false, "delete the profile with the given --name")
+	fs.StringVar(&p.Name, "name", "", "profile name")
+	fs.StringVar(&p.Delimiter, "delimiter", ",", `column delimiter, use "tab" for tab separated files`)
+	fs.BoolVar(&p.HasHeader, "header", true, "the first (non skipped) row is a header")
+	fs.IntVar(&p.SkipRows, "skip-rows", 0, "number of rows to skip before the header or data")
+	fs.StringVar(&p.DateColumn, "date-column", "", "date column name or 1-based position")
+	fs.StringVar(&p.DateFormat, "date-format", "2006-01-02", "date format as a Go reference layout, e.g. 02.01.2006")
+	fs.StringVar(&p.AmountColumn, "amount-column", "", "amount column name or 1-based position")
+	fs.StringVar(&p.DebitColumn, "debit-column", "", "debit column for the debit-credit sign convention")
+	fs.StringVar(&p.CreditColumn, "credit-column", "", "credit column for the debit-credit sign convention")
+	fs.StringVar(&p.SignConvention, "sign", SignNegativeExpense, fmt.Sprintf("amount sign convention - %s, %s or %s", SignNegativeExpense, SignPositiveExpense, SignDebitCredit))
+	fs.StringVar(&p.DescriptionColumn, "description-column", "", "description column name or 1-based position")
+	fs.StringVar(&p.CategoryColumn, "category-column", "", "optional category column name or 1-based position")
+	fs.StringVar(&p.DecimalSeparator, "decimal-separator", ".", "decimal separator")
+	fs.StringVar(&p.ThousandsSeparator, "thousands-separator", "", "thousands separator")
+	fs.StringVar(&p.Encoding, "encoding", "utf-8", "file encoding - utf-8, windows-1250, windows-1252, iso-8859-1 or iso-8859-15")
+	fs.StringVar(&p.DefaultExpenseCategory, "expense-category", "", "category for expenses that can't be categorized")
+	fs.StringVar(&p.DefaultIncomeCategory, "income-category", "", "category for income that can't be categorized")
+
+	return func(out io.Writer) error {
+		if err := requireSQLiteStorage(); err != nil {
+			return err
+		}
+		switch {
+		case *list:
+			profiles, err := listImportProfiles()
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tDATE\tAMOUNT\tDESCRIPTION\tSIGN\tENCODING")
+			for _, p := range profiles {
+				amount := p.AmountColumn
+				if p.SignConvention == SignDebitCredit {
+					amount = p.DebitColumn + "/" + p.CreditColumn
+				}
+				fmt.Fprintf(tw, "%s\t%s (%s)\t%s\t%s\t%s\t%s\n", p.Name, p.DateColumn, p.DateFormat, amount, p.DescriptionColumn, p.SignConvention, p.Encoding)
+			}
+			return tw.Flush()
+
+		case *remove:
+			if err := deleteImportProfile(p.Name); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "deleted import profile %s\n", p.Name)
+			return nil
+
+		default:
+			if err := saveImportProfile(p); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "saved import profile %s\n", p.Name)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestParseStatementAmount(t *testing.T) {
+	cases := []struct {
+		raw       string
+		decimal   string
+		thousands string
+		expected  float64
+		expectErr bool
+	}{
+		{"12.50", ".", "", 12.50, false},
+		{"-1,234.56", ".", ",", -1234.56, false},
+		{"1.234,56", ",", ".", 1234.56, false},
+		{"12,30-", ",", "", -12.30, false},
+		{"-7,00 €", ",", "", -7, false},
+		{"1 000,00 EUR", ",", " ", 1000, false},
+		{"", ".", "", 0, false},
+		{"abc", ".", "", 0, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.raw, func(t *testing.T) {
+			amount, err := parseStatementAmount(c.raw, c.decimal, c.thousands)
+			if (err != nil) != c.expectErr {
+				t.Fatalf("parseStatementAmount(%q) error = %v; expected error = %v", c.raw, err, c.expectErr)
+			}
+			if amount != c.expected {
+				t.Errorf("parseStatementAmount(%q) = %v; expected %v", c.raw, amount, c.expected)
+			}
+		})
+	}
+}
+
+func TestCsvImportProfileValidate(t *testing.T) {
+	valid := csvImportProfile{Name: "bank", DateColumn: "Date", AmountColumn: "Amount"}
+	if err := valid.validate(); err != nil {
+		t.Fatalf("Expected valid profile, got %v", err)
+	}
+	if valid.Delimiter != "," || valid.DateFormat != "2006-01-02" || valid.SignConvention != SignNegativeExpense || valid.Encoding != "utf-8" {
+		t.Errorf("Expected defaults to be filled in, got %+v", valid)
+	}
+
+	cases := []struct {
+		name    string
+		profile csvImportProfile
+	}{
+		{"missing name", csvImportProfile{DateColumn: "Date", AmountColumn: "Amount"}},
+		{"missing date column", csvImportProfile{Name: "bank", AmountColumn: "Amount"}},
+		{"missing amount column", csvImportProfile{Name: "bank", DateColumn: "Date"}},
+		{"missing credit column", csvImportProfile{Name: "bank", DateColumn: "Date", SignConvention: SignDebitCredit, DebitColumn: "Debit"}},
+		{"unknown sign convention", csvImportProfile{Name: "bank", DateColumn: "Date", AmountColumn: "Amount", SignConvention: "sideways"}},
+		{"same separators", csvImportProfile{Name: "bank", DateColumn: "Date", AmountColumn: "Amount", DecimalSeparator: ",", ThousandsSeparator: ","}},
+		{"unknown encoding", csvImportProfile{Name: "bank", DateColumn: "Date", AmountColumn: "Amount", Encoding: "ebcdic"}},
+		{"invalid default category", csvImportProfile{Name: "bank", DateColumn: "Date", AmountColumn: "Amount", DefaultExpenseCategory: "madeUpCategory"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.profile.validate(); err == nil {
+				t.Errorf("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestParseCsvStatement(t *testing.T) {
+	profile := csvImportProfile{
+		Name:               "eu-bank",
+		Delimiter:          ";",
+		HasHeader:          true,
+		SkipRows:           1,
+		DateColumn:         "Buchungstag",
+		DateFormat:         "02.01.2006",
+		AmountColumn:       "Betrag",
+		DescriptionColumn:  "Verwendungszweck",
+		CategoryColumn:     "5",
+		DecimalSeparator:   ",",
+		ThousandsSeparator: ".",
+		Encoding:           "windows-1252",
+	}
+
+	statement := "Konto;DE123\n" +
+		"Buchungstag;Betrag;Verwendungszweck;Ignored;Kategorie\n" +
+		"01.03.2024;-1.234,56;Miete März;x;housing\n" +
+		"15.03.2024;2.500,00;Gehalt;x;salary\n" +
+		";;Summe;;\n"
+	encoded, err := charmap.Windows1252.NewEncoder().String(statement)
+	if err != nil {
+		t.Fatalf("Failed to encode statement: %v", err)
+	}
+
+	entries, err := parseCsvStatement([]byte(encoded), profile)
+	if err != nil {
+		t.Fatalf("Expected no error parsing statement, got %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+	if entries[0].Amount != -1234.56 || entries[0].Description != "Miete März" || entries[0].Category != "housing" {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[1].Amount != 2500 || entries[1].Date.Day() != 15 {
+		t.Errorf("Unexpected second entry %+v", entries[1])
+	}
+
+	debitCredit := csvImportProfile{
+		Name:           "card",
+		DateColumn:     "1",
+		SignConvention: SignDebitCredit,
+		DebitColumn:    "2",
+		CreditColumn:   "3",
+	}
+	entries, err = parseCsvStatement([]byte("2024-04-02,15.00,\n2024-04-03,,40.00\n"), debitCredit)
+	if err != nil {
+		t.Fatalf("Expected no error parsing debit/credit statement, got %v", err)
+	}
+	if len(entries) != 2 || entries[0].Amount != -15 || entries[1].Amount != 40 {
+		t.Errorf("Unexpected debit/credit entries %+v", entries)
+	}
+
+	if _, err := parseCsvStatement([]byte("Date,Amount\nnot-a-date,1\n"), csvImportProfile{Name: "x", HasHeader: true, DateColumn: "Date", AmountColumn: "Amount"}); err == nil {
+		t.Errorf("Expected error for invalid date")
+	}
+	if _, err := parseCsvStatement([]byte("Date,Amount\n"), csvImportProfile{Name: "x", HasHeader: true, DateColumn: "Missing", AmountColumn: "Amount"}); err == nil {
+		t.Errorf("Expected error for missing column")
+	}
+}
+
+func TestImportProfilePersistence(t *testing.T) {
+	tmpDbFile, err := os.CreateTemp("", "test_import_profiles_*.db")
+	if err != nil {
+		t.Fatalf("Failed to create temp db file: %v", err)
+	}
+	tmpDbFile.Close()
+	defer os.Remove(tmpDbFile.Name())
+
+	if err := initDb(tmpDbFile.Name()); err != nil {
+		t.Fatalf("Failed to initialize db: %v", err)
+	}
+	defer closeDb()
+
+	profile := csvImportProfile{Name: "bank", DateColumn: "Date", AmountColumn: "Amount", DefaultExpenseCategory: "shopping"}
+	if err := saveImportProfile(profile); err != nil {
+		t.Fatalf("Expected no error saving profile, got %v", err)
+	}
+
+	loaded, err := loadImportProfile("bank")
+	if err != nil {
+		t.Fatalf("Expected no error loading profile, got %v", err)
+	}
+	if loaded.DateColumn != "Date" || loaded.DefaultExpenseCategory != "shopping" || loaded.Delimiter != "," {
+		t.Errorf("Unexpected loaded profile %+v", loaded)
+	}
+
+	profiles, err := listImportProfiles()
+	if err != nil || len(profiles) != 1 {
+		t.Errorf("Expected one profile, got %v (err %v)", profiles, err)
+	}
+
+	if err := deleteImportProfile("bank"); err != nil {
+		t.Errorf("Expected no error deleting profile, got %v", err)
+	}
+	if _, err := loadImportProfile("bank"); err == nil {
+		t.Errorf("Expected error loading deleted profile")
+	}
+	if err := deleteImportProfile("bank"); err == nil {
+		t.Errorf("Expected error deleting missing profile")
+	}
+}
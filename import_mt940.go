@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+var (
+	mt940TagPattern = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+	// value date YYMMDD, optional entry date MMDD, debit/credit mark, optional funds code, amount, transaction type, references
+	mt940StatementLinePattern = regexp.MustCompile(`^(\d{6})(\d{4})?(R?[CD])([A-Z])?(\d+,\d*)([NSF][A-Z0-9]{3})(.*)$`)
+	mt940SubfieldPattern      = regexp.MustCompile(`\?(\d{2})`)
+	mt940SepaKeywordPattern   = regexp.MustCompile(`[A-Z]{4}\+`)
+)
+
+// a single :tag: field of an mt940 statement including its continuation lines
+type mt940Field struct {
+	Tag   string
+	Lines []string
+}
+
+// parses a SWIFT MT940 statement, every :61: statement line becomes an entry and the :86: field that follows it the description
+func parseMt940Statement(data []byte) ([]importedEntry, error) {
+	// the SWIFT character set is a subset of ascii, but many banks export latin-1 umlauts
+	if !utf8.Valid(data) {
+		decoded, err := charmap.ISO8859_1.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode mt940 statement: %w", err)
+		}
+		data = decoded
+	}
+
+	fields, err := readMt940Fields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		entries     []importedEntry
+		account     string
+		current     *importedEntry
+		bankRef     string
+		occurrences = make(map[string]int)
+	)
+
+	// the reference is only known once the :86: field (or the next statement line) was read
+	finish := func() {
+		if current == nil {
+			return
+		}
+		ref := bankRef
+		if ref == "" {
+			key := fmt.Sprintf("%s|%s|%.2f|%s", account, current.Date.Format("2006-01-02"), current.Amount, current.Description)
+			occurrences[key]++
+			sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, occurrences[key])))
+			ref = hex.EncodeToString(sum[:12])
+		} else {
+			ref = current.Date.Format("20060102") + ":" + ref
+		}
+		current.Ref = "mt940:" + account + ":" + ref
+		entries = append(entries, *current)
+		current, bankRef = nil, ""
+	}
+
+	for _, f := range fields {
+		switch f.Tag {
+		case "25":
+			finish()
+			account = strings.TrimSpace(f.Lines[0])
+		case "61":
+			finish()
+			entry, ref, err := parseMt940StatementLine(f.Lines[0])
+			if err != nil {
+				return nil, err
+			}
+			current, bankRef = &entry, ref
+		case "86":
+			if current != nil {
+				current.Description = mt940Description(f.Lines)
+			}
+		case "62F", "62M":
+			finish()
+		}
+	}
+	finish()
+
+	if account == "" && len(entries) == 0 {
+		return nil, fmt.Errorf("not an mt940 statement, missing :25: account field")
+	}
+
+	return entries, nil
+}
+
+// splits an mt940 statement into its fields, lines that don't start with a tag continue the previous field
+func readMt940Fields(data []byte) ([]mt940Field, error) {
+	var fields []mt940Field
+
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		if line == "" || line == "-" || line == "-}" || strings.HasPrefix(line, "{") {
+			continue // empty lines, end of message markers and SWIFT header blocks
+		}
+
+		if match := mt940TagPattern.FindStringSubmatch(line); match != nil {
+			fields = append(fields, mt940Field{Tag: match[1], Lines: []string{match[2]}})
+			continue
+		}
+		if len(fields) > 0 {
+			last := &fields[len(fields)-1]
+			last.Lines = append(last.Lines, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mt940 statement: %w", err)
+	}
+	return fields, nil
+}
+
+// parses a :61: statement line, returns the entry and the bank reference (empty if the bank didn't assign one)
+func parseMt940StatementLine(line string) (importedEntry, string, error) {
+	match := mt940StatementLinePattern.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return importedEntry{}, "", fmt.Errorf("invalid mt940 statement line %q", line)
+	}
+
+	valueDate, err := time.Parse("060102", match[1])
+	if err != nil {
+		return importedEntry{}, "", fmt.Errorf("invalid mt940 value date in %q", line)
+	}
+
+	// the booking date has no year, it's in the year of the value date unless the two are on different sides of new year
+	date := valueDate
+	if match[2] != "" {
+		booking, err := time.Parse("0102", match[2])
+		if err != nil {
+			return importedEntry{}, "", fmt.Errorf("invalid mt940 booking date in %q", line)
+		}
+		date = time.Date(valueDate.Year(), booking.Month(), booking.Day(), 0, 0, 0, 0, time.UTC)
+		switch {
+		case valueDate.Month() == time.December && booking.Month() == time.January:
+			date = date.AddDate(1, 0, 0)
+		case valueDate.Month() == time.January && booking.Month() == time.December:
+			date = date.AddDate(-1, 0, 0)
+		}
+	}
+
+	amount, err := parseStatementAmount(match[5], ",", "")
+	if err != nil {
+		return importedEntry{}, "", err
+	}
+
+	// credits are money in (income), debits money out (expense), reversals flip the direction
+	if match[3] == "D" || match[3] == "RC" {
+		amount = -amount
+	}
+
+	var bankRef string
+	references := match[7]
+	if i := strings.Index(references, "//"); i >= 0 {
+		bankRef = strings.TrimSpace(references[i+2:])
+		references = references[:i]
+	}
+	if bankRef == "" || strings.EqualFold(bankRef, "NONREF") {
+		bankRef = strings.TrimSpace(references)
+	}
+	if strings.EqualFold(bankRef, "NONREF") {
+		bankRef = ""
+	}
+
+	return importedEntry{Date: date, Amount: amount}, bankRef, nil
+}
+
+// builds the description from the :86: field - structured (?20 - ?29 subfields) and free text variants are supported
+func mt940Description(lines []string) string {
+	content := strings.Join(lines, "")
+	if !mt940SubfieldPattern.MatchString(content) {
+		return strings.Join(lines, " ")
+	}
+
+	// the remittance info is split over the ?20 - ?29 and ?60 - ?63 subfields, ?32 and ?33 hold the counterparty name
+	var remittance, name strings.Builder
+	positions := mt940SubfieldPattern.FindAllStringSubmatchIndex(content, -1)
+	for i, pos := range positions {
+		end := len(content)
+		if i+1 < len(positions) {
+			end = positions[i+1][0]
+		}
+		code, value := content[pos[2]:pos[3]], content[pos[1]:end]
+
+		switch {
+		case code >= "20" && code <= "29", code >= "60" && code <= "63":
+			remittance.WriteString(value)
+		case code == "32", code == "33":
+			name.WriteString(value)
+		}
+	}
+
+	description := remittance.String()
+	// SEPA transfers prefix the fields with keywords (EREF+, KREF+, SVWZ+), the actual remittance text is in SVWZ+
+	if i := strings.Index(description, "SVWZ+"); i >= 0 {
+		description = description[i+len("SVWZ+"):]
+		if next := mt940SepaKeywordPattern.FindStringIndex(description); next != nil {
+			description = description[:next[0]]
+		}
+	}
+
+	description = strings.TrimSpace(description)
+	if description == "" {
+		description = strings.TrimSpace(name.String())
+	}
+	return description
+}
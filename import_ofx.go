@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+var (
+	ofxTransactionPattern = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+	ofxDatePattern        = regexp.MustCompile(`^(\d{8})(\d{6})?`)
+)
+
+// parses an OFX or QFX statement, both the SGML based OFX 1.x and the XML based OFX 2.x are supported
+func parseOfxStatement(data []byte) ([]importedEntry, error) {
+	// OFX 1.x headers declare the charset, older banks still use windows-1252
+	if bytes.Contains(data, []byte("CHARSET:1252")) {
+		decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode ofx statement: %w", err)
+		}
+		data = decoded
+	}
+
+	content := string(data)
+	if !strings.Contains(strings.ToUpper(content), "<OFX>") {
+		return nil, fmt.Errorf("not an ofx statement, missing <OFX> element")
+	}
+
+	// FITIDs are only unique per account, so the account id is part of the dedupe key
+	account := ofxField(content, "ACCTID")
+
+	var entries []importedEntry
+	for _, match := range ofxTransactionPattern.FindAllStringSubmatch(content, -1) {
+		block := match[1]
+
+		rawDate := ofxField(block, "DTPOSTED")
+		date, err := parseOfxDate(rawDate)
+		if err != nil {
+			return nil, err
+		}
+
+		rawAmount := ofxField(block, "TRNAMT")
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(rawAmount, ",", "."), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ofx amount %q", rawAmount)
+		}
+
+		fitId := ofxField(block, "FITID")
+		if fitId == "" {
+			return nil, fmt.Errorf("ofx transaction from %s without FITID", rawDate)
+		}
+
+		description := ofxField(block, "NAME")
+		if memo := ofxField(block, "MEMO"); memo != "" && !strings.EqualFold(memo, description) {
+			if description == "" {
+				description = memo
+			} else {
+				description += " - " + memo
+			}
+		}
+
+		entries = append(entries, importedEntry{
+			Date:        date,
+			Amount:      amount,
+			Description: description,
+			Ref:         "ofx:" + account + ":" + fitId,
+		})
+	}
+
+	return entries, nil
+}
+
+// reads the value of a single ofx element, in SGML the value runs until the next tag or line break
+func ofxField(block, tag string) string {
+	upper := strings.ToUpper(block)
+	start := strings.Index(upper, "<"+tag+">")
+	if start < 0 {
+		return ""
+	}
+	value := block[start+len(tag)+2:]
+	if end := strings.IndexAny(value, "<\r\n"); end >= 0 {
+		value = value[:end]
+	}
+	return strings.TrimSpace(html.UnescapeString(value))
+}
+
+// parses ofx dates - YYYYMMDD optionally followed by HHMMSS, fractional seconds and a timezone, only the date part is relevant
+func parseOfxDate(raw string) (time.Time, error) {
+	match := ofxDatePattern.FindStringSubmatch(raw)
+	if match == nil {
+		return time.Time{}, fmt.Errorf("invalid ofx date %q", raw)
+	}
+	date, err := time.Parse("20060102", match[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ofx date %q", raw)
+	}
+	return date, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+const testOfxSgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+CHARSET:1252
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>EUR
+<BANKACCTFROM><BANKID>12345<ACCTID>DE001<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240301<DTEND>20240331
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240305120000.000[-5:EST]
+<TRNAMT>-42.10
+<FITID>A1
+<NAME>SUPERMARKET
+<MEMO>card payment
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240325
+<TRNAMT>3000.00
+<FITID>A2
+<NAME>ACME &amp; CO
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const testOfxXmlStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CCACCTFROM><ACCTID>CARD9</ACCTID></CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240402</DTPOSTED><TRNAMT>-9.99</TRNAMT><FITID>X1</FITID><NAME>STREAMING</NAME></STMTTRN>
+</BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>
+`
+
+func TestParseOfxStatement(t *testing.T) {
+	entries, err := parseOfxStatement([]byte(testOfxSgmlStatement))
+	if err != nil {
+		t.Fatalf("Expected no error parsing sgml statement, got %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+	if !entries[0].Date.Equal(time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)) || entries[0].Amount != -42.10 {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[0].Description != "SUPERMARKET - card payment" || entries[0].Ref != "ofx:DE001:A1" {
+		t.Errorf("Unexpected first entry description or ref %+v", entries[0])
+	}
+	if entries[1].Description != "ACME & CO" || entries[1].Amount != 3000 {
+		t.Errorf("Unexpected second entry %+v", entries[1])
+	}
+
+	entries, err = parseOfxStatement([]byte(testOfxXmlStatement))
+	if err != nil {
+		t.Fatalf("Expected no error parsing xml statement, got %v", err)
+	}
+	if len(entries) != 1 || entries[0].Ref != "ofx:CARD9:X1" || entries[0].Amount != -9.99 || entries[0].Description != "STREAMING" {
+		t.Errorf("Unexpected xml entries %+v", entries)
+	}
+
+	if _, err := parseOfxStatement([]byte("Date,Amount\n")); err == nil {
+		t.Errorf("Expected error for non ofx content")
+	}
+	if _, err := parseOfxStatement([]byte("<OFX><STMTTRN><DTPOSTED>yesterday<TRNAMT>1<FITID>1</STMTTRN></OFX>")); err == nil {
+		t.Errorf("Expected error for invalid date")
+	}
+}
+
+func TestParseQifStatement(t *testing.T) {
+	statement := `!Account
+NChecking
+^
+!Type:Bank
+D03/05/2024
+T-42.10
+PSupermarket
+LFood:Groceries
+^
+D3/25'24
+T3,000.00
+PACME
+LSalary
+^
+D03/26/2024
+T-5.00
+PCoffee
+^
+D03/26/2024
+T-5.00
+PCoffee
+^
+D03/27/2024
+T-100.00
+L[Savings]
+^
+`
+	entries, err := parseQifStatement([]byte(statement), "")
+	if err != nil {
+		t.Fatalf("Expected no error parsing qif statement, got %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("Expected 5 entries, got %d: %+v", len(entries), entries)
+	}
+	if entries[0].Category != "Food" || entries[0].Amount != -42.10 || entries[0].Description != "Supermarket" {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[1].Amount != 3000 || entries[1].Date.Day() != 25 || entries[1].Date.Year() != 2024 {
+		t.Errorf("Unexpected second entry %+v", entries[1])
+	}
+	if entries[2].Ref == "" || entries[2].Ref == entries[3].Ref {
+		t.Errorf("Expected identical entries to get distinct refs, got %q and %q", entries[2].Ref, entries[3].Ref)
+	}
+	if entries[4].Category != "transfers" {
+		t.Errorf("Expected account transfer to map to transfers, got %q", entries[4].Category)
+	}
+
+	// refs are stable between imports of the same statement
+	again, err := parseQifStatement([]byte(statement), "")
+	if err != nil {
+		t.Fatalf("Expected no error parsing qif statement again, got %v", err)
+	}
+	if again[0].Ref != entries[0].Ref {
+		t.Errorf("Expected stable refs, got %q and %q", entries[0].Ref, again[0].Ref)
+	}
+
+	if _, err := parseQifStatement([]byte("!Type:Bank\nDsometime\nT1\n^\n"), ""); err == nil {
+		t.Errorf("Expected error for invalid date")
+	}
+	if _, err := parseQifStatement([]byte("!Type:Invst\n"), ""); err == nil {
+		t.Errorf("Expected error for investment exports")
+	}
+
+	european, err := parseQifStatement([]byte("!Type:Bank\nD25.03.2024\nT-1.00\n^\n"), "02.01.2006")
+	if err != nil || len(european) != 1 || european[0].Date.Month() != time.March {
+		t.Errorf("Expected european date format to be parsed, got %+v (err %v)", european, err)
+	}
+}
+
+func TestImportDedupeAndReview(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	entries, err := parseOfxStatement([]byte(testOfxSgmlStatement))
+	if err != nil {
+		t.Fatalf("Failed to parse statement: %v", err)
+	}
+
+	candidates := mapImportedEntries(entries, importDefaults{ExpenseCategory: "shopping", IncomeCategory: "salary"})
+	if err := markDuplicateImports(candidates); err != nil {
+		t.Fatalf("Expected no error checking duplicates, got %v", err)
+	}
+	imported, err := commitImport(candidates)
+	if err != nil || imported != 2 {
+		t.Fatalf("Expected 2 imported transactions, got %d (err %v)", imported, err)
+	}
+
+	// re-importing an overlapping statement skips what was already imported
+	candidates = mapImportedEntries(entries, importDefaults{ExpenseCategory: "shopping", IncomeCategory: "salary"})
+	if err := markDuplicateImports(candidates); err != nil {
+		t.Fatalf("Expected no error checking duplicates, got %v", err)
+	}
+	for _, c := range candidates {
+		if c.Skip != "already imported" {
+			t.Errorf("Expected candidate to be skipped as already imported, got %+v", c)
+		}
+	}
+	if imported, _ := commitImport(candidates); imported != 0 {
+		t.Errorf("Expected no transactions to be imported again, got %d", imported)
+	}
+
+	// ofx has no categories, so both entries are flagged for review
+	flagged, err := listTransactionsNeedingReview()
+	if err != nil {
+		t.Fatalf("Expected no error listing review, got %v", err)
+	}
+	if len(flagged) != 2 {
+		t.Fatalf("Expected 2 transactions flagged for review, got %+v", flagged)
+	}
+
+	if err := resolveImportReview(flagged[0].Id); err != nil {
+		t.Errorf("Expected no error resolving review, got %v", err)
+	}
+	if err := resolveImportReview(flagged[0].Id); err == nil {
+		t.Errorf("Expected error resolving an already resolved review")
+	}
+	if flagged, _ := listTransactionsNeedingReview(); len(flagged) != 1 {
+		t.Errorf("Expected 1 transaction left for review, got %+v", flagged)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// most QIF exports use US style dates, e.g. 03/25/2024 or 3/25'24
+const qifDefaultDateFormat = "01/02/2006"
+
+// parses a QIF statement, QIF has no transaction ids so the dedupe reference is built from the content of each entry
+func parseQifStatement(data []byte, dateFormat string) ([]importedEntry, error) {
+	if dateFormat == "" {
+		dateFormat = qifDefaultDateFormat
+	}
+
+	var (
+		entries     []importedEntry
+		current     importedEntry
+		payee, memo string
+		hasEntry    bool
+		account     string
+		inAccount   bool // !Account blocks describe the account instead of transactions
+		occurrences = make(map[string]int)
+	)
+
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			header := strings.ToLower(line)
+			inAccount = strings.HasPrefix(header, "!account")
+			if strings.HasPrefix(header, "!type:invst") {
+				return nil, fmt.Errorf("investment account qif exports are not supported")
+			}
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+
+		if inAccount {
+			if code == 'N' {
+				account = value
+			}
+			continue
+		}
+
+		switch code {
+		case 'D':
+			date, err := parseQifDate(value, dateFormat)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			current.Date = date
+			hasEntry = true
+		case 'T', 'U':
+			amount, err := parseStatementAmount(value, ".", ",")
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			current.Amount = amount
+			hasEntry = true
+		case 'P':
+			payee = value
+		case 'M':
+			memo = value
+		case 'L':
+			current.Category = qifCategoryHint(value)
+		case '^':
+			if hasEntry {
+				current.Description = payee
+				if memo != "" && !strings.EqualFold(memo, payee) {
+					if current.Description == "" {
+						current.Description = memo
+					} else {
+						current.Description += " - " + memo
+					}
+				}
+
+				// identical entries on the same day (e.g. two coffees) get a running occurrence number so both are kept
+				key := fmt.Sprintf("%s|%s|%.2f|%s|%s", account, current.Date.Format("2006-01-02"), current.Amount, payee, memo)
+				occurrences[key]++
+				sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, occurrences[key])))
+				current.Ref = "qif:" + hex.EncodeToString(sum[:12])
+
+				entries = append(entries, current)
+			}
+			current, payee, memo, hasEntry = importedEntry{}, "", "", false
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read qif statement: %w", err)
+	}
+
+	return entries, nil
+}
+
+// parses a qif date, apart from the configured layout it also accepts the apostrophe year separator and single digit days and months
+func parseQifDate(raw, dateFormat string) (time.Time, error) {
+	value := strings.ReplaceAll(strings.ReplaceAll(raw, "'", "/"), " ", "")
+
+	short := strings.NewReplacer("01", "1", "02", "2").Replace(dateFormat)
+	layouts := []string{dateFormat, short, strings.Replace(dateFormat, "2006", "06", 1), strings.Replace(short, "2006", "06", 1)}
+
+	for _, layout := range layouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid qif date %q, expected format %s", raw, dateFormat)
+}
+
+// helper to turn a qif category into a category hint - subcategories are dropped and [account] transfers map to transfers
+func qifCategoryHint(value string) string {
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		return "transfers"
+	}
+	if i := strings.IndexAny(value, ":/"); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+)
+
+// a single entry from a bank statement before it is mapped into a transaction
+type importedEntry struct {
+	Date        time.Time
+	Amount      float64 // signed - positive amounts are money in, negative amounts are money out
+	Description string
+	Category    string // optional category hint from the statement
+	Ref         string // unique reference assigned by the bank (e.g. OFX FITID), used to skip entries that were already imported
+}
+
+// categories used for entries that the statement doesn't categorize (or whose category is unknown)
+type importDefaults struct {
+	ExpenseCategory string
+	IncomeCategory  string
+}
+
+// an imported entry mapped into a request that can be passed to handleAddTransaction
+type importCandidate struct {
+	Request     AddTransactionRequest
+	Date        time.Time
+	Ref         string
+	NeedsReview bool   // category could not be mapped from the statement and the default one was used
+	Skip        string // reason why the entry will not be imported
+}
+
+// maps statement entries into year/month/type buckets and validates their categories
+func mapImportedEntries(entries []importedEntry, defaults importDefaults) []importCandidate {
+	candidates := make([]importCandidate, 0, len(entries))
+
+	for _, e := range entries {
+		c := importCandidate{Date: e.Date, Ref: e.Ref}
+
+		// money out is an expense (or investment if the statement says so), money in is income
+		txType := "income"
+		defaultCategory := defaults.IncomeCategory
+		if e.Amount < 0 {
+			txType = "expense"
+			defaultCategory = defaults.ExpenseCategory
+		}
+
+		category, ok := matchAllowedCategory(txType, e.Category)
+		if !ok && txType == "expense" {
+			if investmentCategory, found := matchAllowedCategory("investment", e.Category); found {
+				txType, category, ok = "investment", investmentCategory, true
+			}
+		}
+		if !ok {
+			category = defaultCategory
+			c.NeedsReview = true
+		}
+
+		c.Request = AddTransactionRequest{
+			Type:        txType,
+			Amount:      strconv.FormatFloat(math.Abs(e.Amount), 'f', 2, 64),
+			Category:    category,
+			Description: truncateDescription(e.Description),
+			Month:       strings.ToLower(e.Date.Month().String()),
+			Year:        strconv.Itoa(e.Date.Year()),
+			Date:        e.Date.Format(TransactionDateFormat),
+		}
+
+		switch {
+		case e.Date.IsZero():
+			c.Skip = "missing date"
+		case e.Amount == 0:
+			c.Skip = "zero amount"
+		case category == "":
+			c.Skip = fmt.Sprintf("no %s category, set a default one", txType)
+		case !isAllowedCategory(txType, category):
+			c.Skip = fmt.Sprintf("invalid default %s category %s", txType, category)
+		}
+
+		candidates = append(candidates, c)
+	}
+
+	return candidates
+}
+
+// marks candidates whose bank reference was already imported (or repeats within the same statement) to be skipped
+func markDuplicateImports(candidates []importCandidate) error {
+	seen := make(map[string]bool)
+	for i := range candidates {
+		ref := candidates[i].Ref
+		if ref == "" || candidates[i].Skip != "" {
+			continue
+		}
+
+		if seen[ref] {
+			candidates[i].Skip = "duplicate in statement"
+			continue
+		}
+		seen[ref] = true
+
+		var exists int
+		err := db.QueryRow(`SELECT COUNT(*) FROM imported_transactions WHERE ref = ?`, ref).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check for already imported reference %s: %w", ref, err)
+		}
+		if exists > 0 {
+			candidates[i].Skip = "already imported"
+		}
+	}
+	return nil
+}
+
+// helper to collapse whitespace and shorten a description to the maximum length without cutting a multi-byte character in half
+func truncateDescription(description string) string {
+	description = strings.Join(strings.Fields(description), " ")
+	if len(description) <= DescriptionMaxCharLength {
+		return description
+	}
+
+	cut := DescriptionMaxCharLength
+	for cut > 0 && !utf8.RuneStart(description[cut]) {
+		cut--
+	}
+	return description[:cut]
+}
+
+// helper to match a category hint from a statement against the allowed categories, matching is case insensitive
+func matchAllowedCategory(txType, hint string) (string, bool) {
+	hint = strings.TrimSpace(hint)
+	if hint == "" {
+		return "", false
+	}
+	categories, err := listOfAllowedCategories(txType)
+	if err != nil {
+		return "", false
+	}
+	for _, c := range categories {
+		if strings.EqualFold(c, hint) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// prints the candidates that would be imported so they can be reviewed before committing
+func printImportPreview(out io.Writer, candidates []importCandidate) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tTYPE\tAMOUNT\tCATEGORY\tDESCRIPTION\tNOTE")
+
+	var toImport, toReview int
+	for _, c := range candidates {
+		note := ""
+		switch {
+		case c.Skip != "":
+			note = "skip: " + c.Skip
+		case c.NeedsReview:
+			note = "review: default category"
+			toReview++
+			toImport++
+		default:
+			toImport++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", c.Date.Format("2006-01-02"), c.Request.Type, c.Request.Amount, c.Request.Category, c.Request.Description, note)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(out, "\n%d of %d entries will be imported (%d with a default category)\n", toImport, len(candidates), toReview)
+}
+
+// adds every candidate that is not skipped, returns the number of imported transactions
+// the transactions and their refs are written in a single db transaction, so a failure imports nothing and the statement can simply be imported again
+func commitImport(candidates []importCandidate) (int, error) {
+	if err := requireWritableDb(); err != nil {
+		return 0, err
+	}
+
+	// every candidate is checked and gets its id before anything is written
+	type pendingImport struct {
+		candidate importCandidate
+		txType    string
+		tx        Transaction
+	}
+	var pending []pendingImport
+	for _, c := range candidates {
+		if c.Skip != "" {
+			continue
+		}
+		txType, tx, err := newTransactionFromRequest(c.Request)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import %s %s %s: %w", c.Date.Format("2006-01-02"), c.Request.Amount, c.Request.Description, err)
+		}
+		pending = append(pending, pendingImport{candidate: c, txType: txType, tx: tx})
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	err := withDbTransaction(func(sqlTx *sql.Tx) error {
+		for _, p := range pending {
+			if err := insertTransactionInTx(sqlTx, p.txType, p.tx); err != nil {
+				return fmt.Errorf("failed to import %s %s %s: %w", p.candidate.Date.Format("2006-01-02"), p.candidate.Request.Amount, p.candidate.Request.Description, err)
+			}
+
+			// a NULL ref doesn't take part in the uniqueness check, so statements without references can be imported more than once
+			var ref any
+			if p.candidate.Ref != "" {
+				ref = p.candidate.Ref
+			}
+			if _, err := sqlTx.Exec(`INSERT INTO imported_transactions (transaction_id, ref, needs_review) VALUES (?, ?, ?)`, p.tx.Id, ref, p.candidate.NeedsReview); err != nil {
+				return fmt.Errorf("failed to record imported transaction %s: %w", p.tx.Id, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// the whole import counts as a single change towards the next checkpoint
+	recordDbMutation()
+	return len(pending), nil
+}
+
+// a transaction that was imported with a default category and still needs to be reviewed
+type reviewTransaction struct {
+	Year  string
+	Month string
+	Type  string
+	Transaction
+}
+
+// lists imported transactions that are still flagged for review
+func listTransactionsNeedingReview() ([]reviewTransaction, error) {
+	rows, err := db.Query(`
+			SELECT t.id, t.amount, t.type, t.category, t.description, t.year, t.month
+			FROM transactions t
+			JOIN imported_transactions i ON i.transaction_id = t.id
+			WHERE i.needs_review = 1
+			ORDER BY t.date, t.id
+		`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions needing review: %w", err)
+	}
+	defer rows.Close()
+
+	var result []reviewTransaction
+	for rows.Next() {
+		var r reviewTransaction
+		var year int
+		if err := rows.Scan(&r.Id, &r.Amount, &r.Type, &r.Category, &r.Description, &year, &r.Month); err != nil {
+			return nil, fmt.Errorf("db scan failed during review listing: %w", err)
+		}
+		r.Year = strconv.Itoa(year)
+		result = append(result, r)
+	}
+
+	return result, rows.Err()
+}
+
+// clears the review flag of an imported transaction
+func resolveImportReview(transactionId string) error {
+	return runDbChange(func(sqlTx *sql.Tx) error {
+		res, err := sqlTx.Exec(`UPDATE imported_transactions SET needs_review = 0 WHERE transaction_id = ? AND needs_review = 1`, transactionId)
+		if err != nil {
+			return fmt.Errorf("failed to resolve review for transaction %s: %w", transactionId, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("transaction %s is not flagged for review", transactionId)
+		}
+		return nil
+	})
+}
+
+func cliImportSetup(fs *flag.FlagSet) func(out io.Writer) error {
+	format := fs.String("format", "csv", "statement format - csv, ofx, qfx, qif, camt053 or mt940")
+	file := fs.String("file", "", "path to the statement file")
+	profileName := fs.String("profile", "", "name of the csv column mapping profile (see import-profile)")
+	dateFormat := fs.String("date-format", qifDefaultDateFormat, "date format of qif files as a Go reference layout")
+	expenseCategory := fs.String("expense-category", "", "category for expenses that can't be categorized (default from profile)")
+	incomeCategory := fs.String("income-category", "", "category for income that can't be categorized (default from profile)")
+	commit := fs.Bool("commit", false, "import the previewed transactions, without it only a preview is shown")
+
+	return func(out io.Writer) error {
+		if err := requireSQLiteStorage(); err != nil {
+			return err
+		}
+		if *file == "" {
+			return fmt.Errorf("--file is required")
+		}
+
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return fmt.Errorf("failed to read statement file: %w", err)
+		}
+
+		var entries []importedEntry
+		var defaults importDefaults
+
+		switch strings.ToLower(*format) {
+		case "csv":
+			if *profileName == "" {
+				return fmt.Errorf("--profile is required for csv imports")
+			}
+			profile, err := loadImportProfile(*profileName)
+			if err != nil {
+				return err
+			}
+			if entries, err = parseCsvStatement(data, profile); err != nil {
+				return err
+			}
+			defaults = importDefaults{ExpenseCategory: profile.DefaultExpenseCategory, IncomeCategory: profile.DefaultIncomeCategory}
+		case "ofx", "qfx":
+			if entries, err = parseOfxStatement(data); err != nil {
+				return err
+			}
+		case "qif":
+			if entries, err = parseQifStatement(data, *dateFormat); err != nil {
+				return err
+			}
+		case "camt053", "camt.053", "camt":
+			if entries, err = parseCamtStatement(data); err != nil {
+				return err
+			}
+		case "mt940", "sta":
+			if entries, err = parseMt940Statement(data); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported import format %q", *format)
+		}
+
+		if *expenseCategory != "" {
+			defaults.ExpenseCategory = *expenseCategory
+		}
+		if *incomeCategory != "" {
+			defaults.IncomeCategory = *incomeCategory
+		}
+
+		candidates := mapImportedEntries(entries, defaults)
+		if err := markDuplicateImports(candidates); err != nil {
+			return err
+		}
+		printImportPreview(out, candidates)
+
+		if !*commit {
+			fmt.Fprintln(out, "preview only, run again with --commit to import")
+			return nil
+		}
+
+		imported, err := commitImport(candidates)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "imported %d transactions\n", imported)
+		return nil
+	}
+}
+
+func cliImportReviewSetup(fs *flag.FlagSet) func(out io.Writer) error {
+	resolve := fs.String("resolve", "", "clear the review flag of the transaction with this id")
+
+	return func(out io.Writer) error {
+		if err := requireSQLiteStorage(); err != nil {
+			return err
+		}
+		if *resolve != "" {
+			if err := resolveImportReview(*resolve); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "resolved review of transaction %s\n", *resolve)
+			return nil
+		}
+
+		flagged, err := listTransactionsNeedingReview()
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tYEAR\tMONTH\tTYPE\tAMOUNT\tCATEGORY\tDESCRIPTION")
+		for _, r := range flagged {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.2f\t%s\t%s\n", r.Id, r.Year, r.Month, r.Type, r.Amount, r.Category, r.Description)
+		}
+		return tw.Flush()
+	}
+}
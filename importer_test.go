@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMapImportedEntries(t *testing.T) {
+	date := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	entries := []importedEntry{
+		{Date: date, Amount: -42.10, Description: "  Supermarket   purchase ", Category: "Food"},
+		{Date: date, Amount: 3000, Description: "Salary", Category: "salary"},
+		{Date: date, Amount: -200, Description: "ETF plan", Category: "funds"},
+		{Date: date, Amount: -15, Description: "Unknown shop"},
+		{Date: date, Amount: 0, Description: "Zero"},
+		{Date: date, Amount: 20, Description: strings.Repeat("x", DescriptionMaxCharLength+10)},
+	}
+
+	candidates := mapImportedEntries(entries, importDefaults{ExpenseCategory: "shopping"})
+	if len(candidates) != len(entries) {
+		t.Fatalf("Expected %d candidates, got %d", len(entries), len(candidates))
+	}
+
+	first := candidates[0]
+	if first.Request.Type != "expense" || first.Request.Category != "food" || first.Request.Amount != "42.10" || first.NeedsReview {
+		t.Errorf("Unexpected first candidate %+v", first)
+	}
+	if first.Request.Description != "Supermarket purchase" || first.Request.Month != "march" || first.Request.Year != "2024" {
+		t.Errorf("Unexpected first candidate request %+v", first.Request)
+	}
+
+	if candidates[1].Request.Type != "income" || candidates[1].Request.Category != "salary" {
+		t.Errorf("Expected income with salary category, got %+v", candidates[1].Request)
+	}
+	if candidates[2].Request.Type != "investment" || candidates[2].Request.Category != "funds" {
+		t.Errorf("Expected investment with funds category, got %+v", candidates[2].Request)
+	}
+	if !candidates[3].NeedsReview || candidates[3].Request.Category != "shopping" || candidates[3].Skip != "" {
+		t.Errorf("Expected default category flagged for review, got %+v", candidates[3])
+	}
+	if candidates[4].Skip == "" {
+		t.Errorf("Expected zero amount to be skipped")
+	}
+	// no default income category
+	if candidates[5].Skip == "" {
+		t.Errorf("Expected uncategorized income without default to be skipped")
+	}
+	if len(candidates[5].Request.Description) != DescriptionMaxCharLength {
+		t.Errorf("Expected description to be truncated to %d chars, got %d", DescriptionMaxCharLength, len(candidates[5].Request.Description))
+	}
+}
+
+func TestPreviewAndCommitImport(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	date := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	candidates := mapImportedEntries([]importedEntry{
+		{Date: date, Amount: -42.10, Description: "groceries", Category: "food"},
+		{Date: date, Amount: 0, Description: "skipped"},
+	}, importDefaults{})
+
+	var out bytes.Buffer
+	printImportPreview(&out, candidates)
+	if !strings.Contains(out.String(), "groceries") || !strings.Contains(out.String(), "1 of 2 entries will be imported") {
+		t.Errorf("Unexpected preview output %q", out.String())
+	}
+
+	imported, err := commitImport(candidates)
+	if err != nil {
+		t.Fatalf("Expected no error committing import, got %v", err)
+	}
+	if imported != 1 {
+		t.Errorf("Expected 1 imported transaction, got %d", imported)
+	}
+
+	transactions, err := loadTransactionsFromTestStorage()
+	if err != nil {
+		t.Fatalf("Failed to load transactions: %v", err)
+	}
+	if len(transactions["2024"]["march"]["expense"]) != 1 {
+		t.Errorf("Expected imported expense in march 2024, got %v", transactions)
+	}
+}
+
+func TestCommitImportIsAllOrNothing(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	date := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	candidates := mapImportedEntries([]importedEntry{
+		{Date: date, Amount: -42.10, Description: "groceries", Category: "food"},
+		{Date: date, Amount: -9.99, Description: "cinema", Category: "entertainment"},
+	}, importDefaults{})
+
+	// without the refs the next import of the same statement would add the rows again, so nothing is imported
+	failTestDbInserts(t, "imported_transactions")
+	if imported, err := commitImport(candidates); err == nil || imported != 0 {
+		t.Fatalf("Expected the import to fail as a whole, got %d imported (err %v)", imported, err)
+	}
+
+	transactions, err := loadTransactionsFromTestStorage()
+	if err != nil {
+		t.Fatalf("Failed to load transactions: %v", err)
+	}
+	if len(transactions["2024"]["march"]["expense"]) != 0 {
+		t.Errorf("Expected no transactions from a failed import, got %v", transactions)
+	}
+}
+
+func TestDeletedImportCanBeImportedAgain(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	date := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	statement := func() []importCandidate {
+		candidates := mapImportedEntries([]importedEntry{
+			{Date: date, Amount: -42.10, Description: "groceries", Category: "food", Ref: "FITID-1"},
+		}, importDefaults{})
+		if err := markDuplicateImports(candidates); err != nil {
+			t.Fatalf("Failed to check for duplicates: %v", err)
+		}
+		return candidates
+	}
+
+	if imported, err := commitImport(statement()); err != nil || imported != 1 {
+		t.Fatalf("Expected 1 imported transaction, got %d (err %v)", imported, err)
+	}
+	if candidates := statement(); candidates[0].Skip != "already imported" {
+		t.Fatalf("Expected the imported entry to be skipped, got %q", candidates[0].Skip)
+	}
+
+	expenses, err := LoadTransactionsForPeriod("2024", "march", "expense")
+	if err != nil || len(expenses["2024"]["march"]["expense"]) != 1 {
+		t.Fatalf("Expected the imported expense, got %v (err %v)", expenses, err)
+	}
+	if err := DeleteTransaction("expense", expenses["2024"]["march"]["expense"][0].Id); err != nil {
+		t.Fatalf("Failed to delete transaction: %v", err)
+	}
+
+	// the reference went with the transaction, so the entry is imported again
+	if candidates := statement(); candidates[0].Skip != "" {
+		t.Errorf("Expected the deleted entry to be imported again, got %q", candidates[0].Skip)
+	}
+}
+
+func TestReviewListedByDate(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	// month names sort april before january, the review list follows the dates instead
+	candidates := mapImportedEntries([]importedEntry{
+		{Date: time.Date(2024, time.April, 2, 0, 0, 0, 0, time.UTC), Amount: -20, Description: "april"},
+		{Date: time.Date(2024, time.January, 9, 0, 0, 0, 0, time.UTC), Amount: -10, Description: "january"},
+	}, importDefaults{ExpenseCategory: "shopping"})
+	if imported, err := commitImport(candidates); err != nil || imported != 2 {
+		t.Fatalf("Expected 2 imported transactions, got %d (err %v)", imported, err)
+	}
+
+	flagged, err := listTransactionsNeedingReview()
+	if err != nil || len(flagged) != 2 {
+		t.Fatalf("Expected 2 transactions flagged for review, got %+v (err %v)", flagged, err)
+	}
+	if flagged[0].Description != "january" || flagged[1].Description != "april" {
+		t.Errorf("Expected january before april, got %s and %s", flagged[0].Description, flagged[1].Description)
+	}
+
+	readOnlySession = true
+	t.Cleanup(func() { readOnlySession = false })
+	if err := resolveImportReview(flagged[0].Id); err == nil {
+		t.Errorf("Expected resolving a review to be refused in a read-only session")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// the keyfile the user unlocked with, e.g. on a usb stick - only the sha-256 of its contents is kept in memory
+// and mixed into the key derivation, nil without a keyfile
+var (
+	userKeyFilePath string
+	userKeyFile     []byte
+)
+
+// reads a keyfile into the key material that is mixed into the key derivation, any file works but random bytes are best
+func readKeyFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open keyfile: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("keyfile %s is a directory", path)
+	}
+
+	hash := sha256.New()
+	n, err := io.Copy(hash, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("keyfile %s is empty", path)
+	}
+	return hash.Sum(nil), nil
+}
+
+// reads the keyfile and stores its key material in memory next to the password, an empty path means no keyfile
+func setUserKeyFile(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		clearUserKeyFile()
+		return nil
+	}
+
+	keyFile, err := readKeyFile(path)
+	if err != nil {
+		return err
+	}
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	clearUserKeyFileLocked()
+	userKeyFilePath, userKeyFile = path, newSecret(keyFile)
+	wipe(keyFile)
+	return nil
+}
+
+// clears the keyfile material from memory, called together with clearing the password
+func clearUserKeyFile() {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	clearUserKeyFileLocked()
+}
+
+// same as clearUserKeyFile, for callers that already hold the credentials lock
+func clearUserKeyFileLocked() {
+	freeSecret(userKeyFile)
+	userKeyFilePath, userKeyFile = "", nil
+}
+
+// the path of the keyfile the database was unlocked with, empty without a keyfile
+func currentKeyFilePath() string {
+	credentialsMu.RLock()
+	defer credentialsMu.RUnlock()
+	return userKeyFilePath
+}
+
+// reports if a password or a keyfile is in memory, i.e. the user has unlocked the database
+func haveCredentials() bool {
+	credentialsMu.RLock()
+	defer credentialsMu.RUnlock()
+	return userPassword != nil || userKeyFile != nil
+}
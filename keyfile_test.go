@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// helper to write a keyfile with the given contents into a temporary directory
+func writeTestKeyFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "expense.key")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("Failed to write keyfile: %v", err)
+	}
+	return path
+}
+
+func TestReadKeyFile(t *testing.T) {
+	first, err := readKeyFile(writeTestKeyFile(t, "random key material"))
+	if err != nil {
+		t.Fatalf("Expected no error reading keyfile, got %v", err)
+	}
+	second, _ := readKeyFile(writeTestKeyFile(t, "random key material"))
+	if !bytes.Equal(first, second) {
+		t.Errorf("Expected the same contents to give the same key material")
+	}
+
+	if _, err := readKeyFile(writeTestKeyFile(t, "")); err == nil {
+		t.Errorf("Expected an empty keyfile to be rejected")
+	}
+	if _, err := readKeyFile(t.TempDir()); err == nil {
+		t.Errorf("Expected a directory to be rejected")
+	}
+	if _, err := readKeyFile(filepath.Join(t.TempDir(), "missing.key")); err == nil {
+		t.Errorf("Expected a missing keyfile to be rejected")
+	}
+}
+
+func TestKeyFileUnlockModes(t *testing.T) {
+	cases := []struct {
+		name     string
+		password string
+		kind     byte
+	}{
+		{"keyfile only", "", keySlotKeyFile},
+		{"password and keyfile", "testpassword", keySlotPasswordKeyFile},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := setupEncHeaderTestConfig(t)
+			keyFilePath := writeTestKeyFile(t, "random key material")
+			image := []byte("test database content")
+
+			setUserPassword(c.password)
+			if err := setUserKeyFile(keyFilePath); err != nil {
+				t.Fatalf("Failed to set keyfile: %v", err)
+			}
+			if err := encryptDatabaseImage(image); err != nil {
+				t.Fatalf("Expected no error encrypting, got %v", err)
+			}
+
+			header, _ := readEncHeader(config.EncryptedDBFile)
+			if header == nil || header.unlockSlotKind() != c.kind {
+				t.Fatalf("Expected key slot %d, got %v", c.kind, header)
+			}
+
+			data, _ := os.ReadFile(config.EncryptedDBFile)
+			decrypted, err := openDatabaseImage([]byte(c.password), userKeyFile, data)
+			if err != nil || !bytes.Equal(decrypted, image) {
+				t.Errorf("Expected to decrypt with the keyfile, got %q (err %v)", decrypted, err)
+			}
+
+			// the password alone is a different unlock mode
+			if _, err := openDatabaseImage([]byte("testpassword"), nil, data); !errors.Is(err, ErrWrongUnlockMode) {
+				t.Errorf("Expected ErrWrongUnlockMode without the keyfile, got %v", err)
+			}
+
+			// another keyfile derives another key
+			otherKeyFile, _ := readKeyFile(writeTestKeyFile(t, "other key material"))
+			if _, err := openDatabaseImage([]byte(c.password), otherKeyFile, data); !errors.Is(err, ErrWrongPassword) {
+				t.Errorf("Expected ErrWrongPassword with another keyfile, got %v", err)
+			}
+		})
+	}
+}
+
+func TestChangePasswordAddsAndRemovesKeyFile(t *testing.T) {
+	config := setupChangePasswordSession(t)
+	keyFilePath := writeTestKeyFile(t, "random key material")
+
+	// the same password with a new keyfile is a change
+	if err := changePassword("testpassword", "testpassword", keyFilePath); err != nil {
+		t.Fatalf("Expected no error adding a keyfile, got %v", err)
+	}
+	if userKeyFilePath != keyFilePath || userKeyFile == nil {
+		t.Errorf("Expected the session to use the keyfile")
+	}
+
+	data, _ := os.ReadFile(config.EncryptedDBFile)
+	if _, err := openDatabaseImage([]byte("testpassword"), nil, data); !errors.Is(err, ErrWrongUnlockMode) {
+		t.Errorf("Expected the password alone to be rejected, got %v", err)
+	}
+	image, err := openDatabaseImage([]byte("testpassword"), userKeyFile, data)
+	if err != nil || countTransactionsInImage(t, image) != 1 {
+		t.Errorf("Expected the password and keyfile to decrypt the database (err %v)", err)
+	}
+
+	// later saves keep using the keyfile
+	if err := checkpointDb(); err != nil {
+		t.Fatalf("Failed to encrypt db: %v", err)
+	}
+	if header, _ := readEncHeader(config.EncryptedDBFile); header.unlockSlotKind() != keySlotPasswordKeyFile {
+		t.Errorf("Expected the keyfile to be kept across saves")
+	}
+
+	// an empty keyfile path goes back to just the password
+	if err := changePassword("testpassword", "newpassword", ""); err != nil {
+		t.Fatalf("Expected no error removing the keyfile, got %v", err)
+	}
+	data, _ = os.ReadFile(config.EncryptedDBFile)
+	if _, err := openDatabaseImage([]byte("newpassword"), nil, data); err != nil {
+		t.Errorf("Expected the new password alone to decrypt the database, got %v", err)
+	}
+
+	if err := changePassword("newpassword", "", ""); err == nil {
+		t.Errorf("Expected an empty password without a keyfile to be rejected")
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// the lock file that marks the data directory as in use, it is only advisory - every instance checks it before decrypting the database
+const instanceLockFileName = "expense-tracking.lock"
+
+var ErrInstanceLocked = errors.New("the data directory is in use by another instance")
+
+// who holds the lock, written into the lock file as json
+type instanceLockInfo struct {
+	Pid     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+}
+
+func (info instanceLockInfo) String() string {
+	return fmt.Sprintf("pid %d on %s since %s", info.Pid, info.Host, info.Started.Format("2006-01-02 15:04"))
+}
+
+var (
+	instanceLockMu   sync.Mutex
+	heldInstanceLock string // path of the lock file held by this process, empty if none
+)
+
+// helper to get the lock file path, it lives next to the encrypted database that two instances would overwrite
+func instanceLockPath(config *Config) string {
+	return filepath.Join(filepath.Dir(config.EncryptedDBFile), instanceLockFileName)
+}
+
+// takes the lock on the data directory before the database is decrypted, calling it again while the lock is held does nothing
+// a lock left behind by a process that is no longer running on this host is replaced, a lock held by a running instance
+// returns ErrInstanceLocked together with the holder, so the caller can offer to open the database read-only
+func acquireInstanceLock(config *Config) (*instanceLockInfo, error) {
+	instanceLockMu.Lock()
+	defer instanceLockMu.Unlock()
+
+	path := instanceLockPath(config)
+	if heldInstanceLock == path {
+		return nil, nil
+	}
+
+	host, _ := os.Hostname()
+	own := instanceLockInfo{Pid: os.Getpid(), Host: host, Started: time.Now()}
+
+	// a stale lock is removed and the lock taken again, a second attempt that fails means someone else was faster
+	for attempt := 0; attempt < 2; attempt++ {
+		err := createLockFile(path, own)
+		if err == nil {
+			heldInstanceLock = path
+			return nil, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		holder, err := readInstanceLock(path)
+		if os.IsNotExist(err) {
+			continue // released in the meantime
+		}
+		if err == nil && !isStaleInstanceLock(holder, own) {
+			return holder, fmt.Errorf("%w (%s)", ErrInstanceLocked, holder)
+		}
+
+		// unreadable lock files are half written leftovers, they are treated as stale as well
+		if err != nil {
+			log.Printf("removing unreadable lock file %s: %s", path, err)
+		} else {
+			log.Printf("removing stale lock file of %s", holder)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale lock file: %w", err)
+		}
+	}
+
+	holder, _ := readInstanceLock(path)
+	if holder == nil {
+		return nil, fmt.Errorf("failed to acquire lock file %s", path)
+	}
+	return holder, fmt.Errorf("%w (%s)", ErrInstanceLocked, holder)
+}
+
+// helper to create the lock file only if it doesn't exist yet, the contents are written to a temporary file and hard linked into place
+// so another instance never reads a half written lock file
+func createLockFile(path string, info instanceLockInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+instanceLockFileName+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// unlike a rename, a link fails if the lock file already exists
+	err = os.Link(tmp.Name(), path)
+	if err == nil || os.IsExist(err) {
+		return err
+	}
+
+	// some filesystems (e.g. FAT formatted usb drives) have no hard links, the lock file is created exclusively and written in place there
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// helper to read who holds a lock file
+func readInstanceLock(path string) (*instanceLockInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var info instanceLockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid lock file: %w", err)
+	}
+	if info.Pid <= 0 {
+		return nil, fmt.Errorf("invalid lock file: missing pid")
+	}
+	return &info, nil
+}
+
+// a lock is stale if its process is gone, that can only be checked for processes on this host - a lock from another host
+// (e.g. a data directory on a network share) is kept until that instance releases it or the lock file is removed by hand
+func isStaleInstanceLock(holder *instanceLockInfo, own instanceLockInfo) bool {
+	if holder.Host != own.Host {
+		return false
+	}
+	if holder.Pid == own.Pid {
+		return true // left behind by an earlier process that had the same pid
+	}
+	return !processRunning(holder.Pid)
+}
+
+// removes the lock file if this process holds it, called once the database has been encrypted on exit
+func releaseInstanceLock() {
+	instanceLockMu.Lock()
+	defer instanceLockMu.Unlock()
+
+	if heldInstanceLock == "" {
+		return
+	}
+	path := heldInstanceLock
+	heldInstanceLock = ""
+
+	// never remove a lock that was taken over by another instance in the meantime
+	holder, err := readInstanceLock(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("failed to read lock file on release: %s", err)
+		}
+		return
+	}
+	if holder.Pid != os.Getpid() {
+		log.Printf("lock file is held by %s, leaving it in place", holder)
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove lock file: %s", err)
+	}
+}
+
+// tells the user that another instance holds the lock and offers to open the database read-only instead, onReadOnly continues the login
+func showInstanceLockedPrompt(holder *instanceLockInfo, focus tview.Primitive, onReadOnly func()) {
+	text := fmt.Sprintf("The database is already open in another instance (%s).\n\nOpen it read-only to look at the transactions, changes can't be made and nothing is written back on exit.", holder)
+
+	host, _ := os.Hostname()
+	if holder.Host != host {
+		text += fmt.Sprintf("\n\nIf that instance is no longer running, remove %s and log in again.", instanceLockPath(globalConfig))
+	}
+
+	closePrompt := func() {
+		pages.RemovePage("instanceLockedPrompt")
+		tui.SetFocus(focus)
+	}
+
+	modal := styleModal(tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"Open read-only", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			closePrompt()
+			if label != "Open read-only" {
+				clearUserPassword() // cancelled, back to the login prompt
+				return
+			}
+			onReadOnly()
+		}))
+
+	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closePrompt()
+			clearUserPassword()
+			return nil
+		}
+		return event
+	})
+
+	overlay := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(modal, 14, 1, true). // modal height
+		AddItem(nil, 0, 1, false)
+
+	centered := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(overlay, 70, 1, true). // modal width
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage("instanceLockedPrompt", centered, true, true)
+	tui.SetFocus(modal)
+}
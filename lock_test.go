@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// helper to point the config at a temporary data directory
+func setupLockTestConfig(t *testing.T) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	config := &Config{
+		StorageType:       StorageSQLite,
+		UnencryptedDbFile: filepath.Join(dir, "transactions.db"),
+		EncryptedDBFile:   filepath.Join(dir, "transactions.enc"),
+		SaltFile:          filepath.Join(dir, "transactions.salt"),
+	}
+	original := globalConfig
+	SetGlobalConfig(config)
+	t.Cleanup(func() {
+		releaseInstanceLock()
+		clearUserPassword()
+		SetGlobalConfig(original)
+	})
+	return config
+}
+
+// helper to write a lock file as another instance would
+func writeTestInstanceLock(t *testing.T, config *Config, info instanceLockInfo) {
+	t.Helper()
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("Failed to encode lock: %v", err)
+	}
+	if err := os.WriteFile(instanceLockPath(config), data, 0600); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+}
+
+// helper to get the pid of a process that has already exited
+func exitedPid(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to run process: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestAcquireAndReleaseInstanceLock(t *testing.T) {
+	config := setupLockTestConfig(t)
+
+	if holder, err := acquireInstanceLock(config); err != nil || holder != nil {
+		t.Fatalf("Expected to acquire the lock, got holder %v and err %v", holder, err)
+	}
+	info, err := readInstanceLock(instanceLockPath(config))
+	if err != nil {
+		t.Fatalf("Failed to read lock file: %v", err)
+	}
+	host, _ := os.Hostname()
+	if info.Pid != os.Getpid() || info.Host != host {
+		t.Errorf("Expected lock of pid %d on %s, got %s", os.Getpid(), host, info)
+	}
+
+	// taking the lock again while holding it is fine
+	if _, err := acquireInstanceLock(config); err != nil {
+		t.Errorf("Expected to acquire the held lock again, got %v", err)
+	}
+
+	releaseInstanceLock()
+	if _, err := os.Stat(instanceLockPath(config)); !os.IsNotExist(err) {
+		t.Errorf("Expected lock file to be removed on release")
+	}
+}
+
+func TestAcquireInstanceLockHeldByAnotherInstance(t *testing.T) {
+	host, _ := os.Hostname()
+	cases := []struct {
+		name   string
+		holder instanceLockInfo
+	}{
+		{"running process on this host", instanceLockInfo{Pid: os.Getppid(), Host: host}},
+		// processes on another host can't be checked, so even a pid that is gone here keeps the lock
+		{"process on another host", instanceLockInfo{Pid: exitedPid(t), Host: host + "-other"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := setupLockTestConfig(t)
+			c.holder.Started = time.Now()
+			writeTestInstanceLock(t, config, c.holder)
+
+			holder, err := acquireInstanceLock(config)
+			if !errors.Is(err, ErrInstanceLocked) {
+				t.Fatalf("Expected ErrInstanceLocked, got %v", err)
+			}
+			if holder == nil || holder.Pid != c.holder.Pid || holder.Host != c.holder.Host {
+				t.Errorf("Expected holder %s, got %v", c.holder, holder)
+			}
+
+			// releasing without holding the lock leaves the other instance's lock alone
+			releaseInstanceLock()
+			if _, err := readInstanceLock(instanceLockPath(config)); err != nil {
+				t.Errorf("Expected the other instance's lock to stay, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAcquireStaleInstanceLock(t *testing.T) {
+	host, _ := os.Hostname()
+	cases := []struct {
+		name     string
+		contents []byte
+	}{
+		{"exited process", nil},
+		{"half written lock file", []byte(`{"pid": 12`)},
+		{"empty lock file", []byte{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := setupLockTestConfig(t)
+			if c.contents == nil {
+				writeTestInstanceLock(t, config, instanceLockInfo{Pid: exitedPid(t), Host: host, Started: time.Now()})
+			} else if err := os.WriteFile(instanceLockPath(config), c.contents, 0600); err != nil {
+				t.Fatalf("Failed to write lock file: %v", err)
+			}
+
+			if holder, err := acquireInstanceLock(config); err != nil || holder != nil {
+				t.Fatalf("Expected to take over the stale lock, got holder %v and err %v", holder, err)
+			}
+			info, err := readInstanceLock(instanceLockPath(config))
+			if err != nil || info.Pid != os.Getpid() {
+				t.Errorf("Expected lock file of this process, got %v (err %v)", info, err)
+			}
+		})
+	}
+}
+
+func TestReadOnlySessionWhileLocked(t *testing.T) {
+	config := setupLockTestConfig(t)
+	originalDb := db
+	t.Cleanup(func() { db = originalDb })
+	setUserPassword("testpassword")
+
+	// an encrypted database with one transaction
+	if err := initDb(config.UnencryptedDbFile); err != nil {
+		t.Fatalf("Failed to create db: %v", err)
+	}
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "food", Date: "2025-03-14"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	closeDb()
+	if err := encryptDatabase(config.UnencryptedDbFile); err != nil {
+		t.Fatalf("Failed to encrypt db: %v", err)
+	}
+	if err := os.Remove(config.UnencryptedDbFile); err != nil {
+		t.Fatalf("Failed to remove db: %v", err)
+	}
+	encryptedBefore, _ := os.ReadFile(config.EncryptedDBFile)
+
+	host, _ := os.Hostname()
+	writeTestInstanceLock(t, config, instanceLockInfo{Pid: os.Getppid(), Host: host, Started: time.Now()})
+
+	if err := unlockHeadless("testpassword", ""); !errors.Is(err, ErrInstanceLocked) {
+		t.Fatalf("Expected ErrInstanceLocked, got %v", err)
+	}
+
+	if err := unlockHeadlessReadOnly("wrongpassword", ""); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected ErrWrongPassword, got %v", err)
+	}
+	if err := unlockHeadlessReadOnly("testpassword", ""); err != nil {
+		t.Fatalf("Expected read-only unlock to succeed, got %v", err)
+	}
+
+	transactions, err := LoadTransactions()
+	if err != nil || len(transactions["2025"]["march"]["expense"]) != 1 {
+		t.Errorf("Expected to read the stored transaction, got %v (err %v)", transactions, err)
+	}
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "12", Category: "food", Date: "2025-03-15"}); !errors.Is(err, ErrReadOnlyStore) {
+		t.Errorf("Expected ErrReadOnlyStore adding a transaction, got %v", err)
+	}
+	if _, err := db.Exec(`DELETE FROM transactions`); err == nil {
+		t.Errorf("Expected writes to the database to fail")
+	}
+	if _, err := os.Stat(config.UnencryptedDbFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no unencrypted database on disk")
+	}
+
+	closeAndEncryptDb(config)
+
+	encryptedAfter, _ := os.ReadFile(config.EncryptedDBFile)
+	if !bytes.Equal(encryptedBefore, encryptedAfter) {
+		t.Errorf("Expected the encrypted database to stay untouched by a read-only session")
+	}
+	if info, err := readInstanceLock(instanceLockPath(config)); err != nil || info.Pid != os.Getppid() {
+		t.Errorf("Expected the other instance to keep its lock, got %v (err %v)", info, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -12,6 +13,12 @@ import (
 
 // Creates a TUI form with prompt for logi. On initial login provides a form set a password. On subsequent attempts, prompts for password to login with. The same password is also used to generate an encryption key that is then used for encrypting/decrypting the database.
 func loginForm() error {
+	// the json storage types are not encrypted, so there is nothing to unlock
+	if globalConfig.StorageType != StorageSQLite {
+		_, err := gridVisualizeTransactions("", "", "", true)
+		return err
+	}
+
 	// first-run for encryption: if no encrypted DB exists, prompt to set a password
 	if _, err := os.Stat(globalConfig.EncryptedDBFile); os.IsNotExist(err) {
 		setNewPasswordForm()
@@ -21,6 +28,10 @@ func loginForm() error {
 	passwordInputField := styleInputField(tview.NewInputField().
 		SetLabel("Enter Password: ").
 		SetMaskCharacter('*'))
+	// a keyfile is used instead of the password or together with it, leave the password empty for keyfile only databases
+	keyFileInputField := styleInputField(tview.NewInputField().
+		SetLabel("Keyfile (optional): ").
+		SetText(globalConfig.KeyFile))
 
 	var formWithMessage *tview.Flex
 	var centeredModal *tview.Flex
@@ -29,50 +40,151 @@ func loginForm() error {
 		SetText("").
 		SetTextAlign(tview.AlignCenter))
 
-	form := styleForm(tview.NewForm().
-		AddFormItem(passwordInputField).
-		AddButton("Login", func() {
-			entered := passwordInputField.GetText()
+	// wrong password or keyfile, stay on login prompt
+	rejectCredentials := func(err error) {
+		if errors.Is(err, ErrWrongUnlockMode) {
+			message.SetText(unlockModeHint())
+		} else {
+			message.SetText("Wrong password. Try again.")
+		}
+		passwordInputField.SetText("")
+		clearUserPassword() // remove pass and keyfile from memory on error
+	}
 
-			// store password in memory to derive an encryption key from it
-			setUserPassword(entered)
+	// decrypts the database and opens the list of transactions, runs once a leftover unencrypted database has been dealt with
+	unlock := func() {
+		// if encrypted file exists, decrypt with provided password - either next to the encrypted file or only into memory
+		var image []byte
+		if _, err := os.Stat(globalConfig.EncryptedDBFile); err == nil {
+			var decryptErr error
+			if image, decryptErr = decryptSessionDb(globalConfig); decryptErr != nil {
 
-			// if encrypted file exists, decrypt with provided password
-			if _, err := os.Stat(globalConfig.EncryptedDBFile); err == nil {
+				if errors.Is(decryptErr, ErrWrongPassword) || errors.Is(decryptErr, ErrWrongUnlockMode) {
+					rejectCredentials(decryptErr)
+					return
+				}
 
-				if err := decryptDatabase(globalConfig.UnencryptedDbFile); err != nil {
+				// some other unexpected error occured - corrupted file, permision issues, etc
+				showErrorModal(fmt.Sprintf("decryption failed: %s", decryptErr), passwordInputField)
+				log.Printf("decryption failed: %s", decryptErr)
+				clearUserPassword()
+				return
+			}
+		}
 
-					if errors.Is(err, ErrWrongPassword) {
-						// wrong password, stay on login prompt
-						message.SetText("Wrong password. Try again.")
-						passwordInputField.SetText("")
-						clearUserPassword() // remove pass from memory on error
-						return
-					}
+		// initialize DB connection now that the DB is decrypted or already plaintext, this also applies pending schema migrations
+		if err := initSessionDb(globalConfig, image); err != nil {
+			showErrorModal(fmt.Sprintf("failed to initialize DB: %s\n", err), passwordInputField)
+			log.Printf("failed to initialize DB: %s\n", err)
+			discardDecryptedDb(globalConfig) // the encrypted database is left as it was
+			clearUserPassword()              // remove pass from memory on error
+			return
+		}
+		startCheckpoints(globalConfig)
+
+		// recurring transactions that came due since the last run are posted before the grid is drawn, so they show up right away
+		waiting := runRecurringRulesAtLogin()
+
+		// after the session was locked the login continues at the same month and table
+		month, year, tableType := takeResumeView()
+		grid, err := gridVisualizeTransactions(month, year, tableType, true)
+		if err != nil {
+			showErrorModal(fmt.Sprintf("list transactions error:\n\n%s", err), passwordInputField)
+			log.Printf("list transactions error:\n\n%s", err)
+			clearUserPassword() // remove pass from memory on error
+			return
+		}
+		armIdleLock()
 
-					// some other unexpected error occured - corrupted file, permision issues, etc
-					showErrorModal(fmt.Sprintf("decryption failed: %s", err), passwordInputField)
-					log.Printf("decryption failed: %s", err)
-					clearUserPassword()
-					return
-				}
+		// the ones that need confirmation are shown on top of the grid, but not again when only unlocking after a lock
+		if waiting > 0 && month == "" {
+			showQueuedOccurrences(grid, func() {
+				gridVisualizeTransactions("", "", "", true)
+			})
+		}
+	}
+
+	// opens a read-only copy of the database in memory while another instance holds the lock, nothing is written back on exit
+	unlockReadOnly := func() {
+		if err := initReadOnlySessionDb(); err != nil {
+			if errors.Is(err, ErrWrongPassword) || errors.Is(err, ErrWrongUnlockMode) {
+				rejectCredentials(err)
+				return
 			}
 
-			// initialize DB connection now that the DB is decrypted or already plaintext
-			if err := initDb(globalConfig.UnencryptedDbFile); err != nil {
-				showErrorModal(fmt.Sprintf("failed to initialize DB: %s\n", err), passwordInputField)
-				log.Printf("failed to initialize DB: %s\n", err)
-				clearUserPassword() // remove pass from memory on error
+			showErrorModal(fmt.Sprintf("failed to open database read-only: %s", err), passwordInputField)
+			log.Printf("failed to open database read-only: %s", err)
+			clearUserPassword()
+			return
+		}
+
+		// after the session was locked the login continues at the same month and table
+		month, year, tableType := takeResumeView()
+		if _, err := gridVisualizeTransactions(month, year, tableType, true); err != nil {
+			showErrorModal(fmt.Sprintf("list transactions error:\n\n%s", err), passwordInputField)
+			log.Printf("list transactions error:\n\n%s", err)
+			clearUserPassword() // remove pass from memory on error
+			return
+		}
+		armIdleLock()
+	}
+
+	// takes the lock and deals with a leftover database before unlocking, runs once the password is in memory
+	login := func() {
+		// another instance decrypts to the same files and would overwrite whatever this one writes back, so only one can open the database for writing
+		holder, err := acquireInstanceLock(globalConfig)
+		if err != nil {
+			if holder != nil {
+				showInstanceLockedPrompt(holder, passwordInputField, unlockReadOnly)
 				return
 			}
 
-			if _, err := gridVisualizeTransactions("", "", "", true); err != nil {
-				showErrorModal(fmt.Sprintf("list transactions error:\n\n%s", err), passwordInputField)
-				log.Printf("list transactions error:\n\n%s", err)
-				clearUserPassword() // remove pass from memory on error
+			showErrorModal(fmt.Sprintf("failed to lock the data directory: %s", err), passwordInputField)
+			log.Printf("failed to lock the data directory: %s", err)
+			clearUserPassword()
+			return
+		}
+
+		// a previous run that died before re-encrypting can leave a newer unencrypted database behind, decrypting would overwrite it
+		leftover, err := detectLeftoverDb(globalConfig)
+		if err != nil {
+			if errors.Is(err, ErrWrongPassword) || errors.Is(err, ErrWrongUnlockMode) {
+				rejectCredentials(err)
 				return
 			}
 
+			showErrorModal(fmt.Sprintf("failed to check for a leftover database: %s", err), passwordInputField)
+			log.Printf("failed to check for a leftover database: %s", err)
+			clearUserPassword()
+			return
+		}
+		if leftover != nil {
+			showLeftoverDbPrompt(leftover, passwordInputField, unlock)
+			return
+		}
+
+		unlock()
+	}
+
+	var form *tview.Form
+	form = styleForm(tview.NewForm().
+		AddFormItem(passwordInputField).
+		AddFormItem(keyFileInputField).
+		AddButton("Login", func() {
+			// store password and keyfile in memory to derive an encryption key from them, the field doesn't keep a copy
+			setUserPassword(passwordInputField.GetText())
+			passwordInputField.SetText("")
+			if err := setUserKeyFile(keyFileInputField.GetText()); err != nil {
+				message.SetText("Can't read the keyfile. Try again.")
+				log.Printf("failed to read keyfile: %s", err)
+				clearUserPassword()
+				return
+			}
+			login()
+		}).
+		AddButton("Forgot password", func() {
+			// sets a new password with the recovery key and logs in with it
+			formRecoverPassword(form, login)
 		}).
 		AddButton("Quit", func() {
 			// allow main() to run post-Run() cleanup (encrypt + remove plaintext)
@@ -104,9 +216,9 @@ func loginForm() error {
 
 	// vertical centering
 	centeredModal = styleFlex(tview.NewFlex().SetDirection(tview.FlexRow).
-		AddItem(nil, 0, 1, false).         // top spacer
-		AddItem(initialModal, 9, 1, true). // form box automatic height
-		AddItem(nil, 0, 1, false))         // bottom spacer
+		AddItem(nil, 0, 1, false).          // top spacer
+		AddItem(initialModal, 11, 1, true). // form box automatic height
+		AddItem(nil, 0, 1, false))          // bottom spacer
 
 	root := styleFlex(tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(centeredModal, 0, 1, true))
@@ -132,6 +244,9 @@ func setNewPasswordForm() {
 	repeatPasswordField := styleInputField(tview.NewInputField().
 		SetLabel("Repeat Password: ").
 		SetMaskCharacter('*'))
+	keyFileInputField := styleInputField(tview.NewInputField().
+		SetLabel("Keyfile (optional): ").
+		SetText(globalConfig.KeyFile))
 
 	var formWithMessage *tview.Flex
 	var centeredModal *tview.Flex
@@ -143,24 +258,33 @@ func setNewPasswordForm() {
 	form := styleForm(tview.NewForm().
 		AddFormItem(passwordInputField).
 		AddFormItem(repeatPasswordField).
+		AddFormItem(keyFileInputField).
 		AddButton("Confirm", func() {
 			entered := passwordInputField.GetText()
 			repeat := repeatPasswordField.GetText()
 
 			if entered == repeat {
-				if err := addInitialPassword(entered); err != nil {
+				// a second instance that is also creating a new database would overwrite it on exit
+				if _, err := acquireInstanceLock(globalConfig); err != nil {
+					showErrorModal(fmt.Sprintf("failed to lock the data directory: %v", err), passwordInputField)
+					log.Printf("failed to lock the data directory: %v", err)
+					return
+				}
+
+				if err := addInitialPassword(entered, keyFileInputField.GetText()); err != nil {
 					showErrorModal(fmt.Sprintf("failed to set a new password: %v", err), passwordInputField)
 					log.Printf("failed to set a new password: %v", err)
 					return // interrupt here
 				}
 
 				// proceed directly to app using the newly set in-memory password
-				if err := initDb(globalConfig.UnencryptedDbFile); err != nil {
+				if err := initSessionDb(globalConfig, nil); err != nil {
 					showErrorModal(fmt.Sprintf("failed to initialize DB: %s\n", err), passwordInputField)
 					log.Printf("failed to initialize DB: %s\n", err)
 					clearUserPassword() // remove pass from memory on error
 					return
 				}
+				startCheckpoints(globalConfig)
 
 				if _, err := gridVisualizeTransactions("", "", "", true); err != nil {
 					showErrorModal(fmt.Sprintf("list transactions error:\n\n%s", err), passwordInputField)
@@ -168,6 +292,16 @@ func setNewPasswordForm() {
 					clearUserPassword() // remove pass from memory on error
 					return
 				}
+				armIdleLock()
+
+				// the recovery key opens the database if the password is forgotten, it is shown once on top of the empty list of transactions
+				recoveryKey, err := regenerateRecoveryKey()
+				if err != nil {
+					showErrorModal(fmt.Sprintf("failed to create a recovery key, one can be generated later from the settings (s):\n\n%s", err), tui.GetFocus())
+					log.Printf("failed to create a recovery key: %s", err)
+					return
+				}
+				showRecoveryKeyModal(recoveryKey, tui.GetFocus())
 
 			} else {
 				message.SetText("Passwords do not match. Try Again.")
@@ -188,7 +322,7 @@ func setNewPasswordForm() {
 	// just a spacer that can be used to structure the UI, using this instead of nil because it also inherits theme styling
 	formSpacer := tview.NewBox()
 	disclaimerMsg := styleTextView(tview.NewTextView().
-		SetText("A new transaction DB will be created and your password will be used to encrypt its contents.").
+		SetText("A new transaction DB will be created and your password and/or keyfile will be used to encrypt its contents.").
 		SetWrap(true))
 
 	// disclaimer + form + message - vertical alignment
@@ -198,7 +332,7 @@ func setNewPasswordForm() {
 		AddItem(disclaimerMsg, 2, 0, false). // 2 rows after disclaimer
 		AddItem(formSpacer, 2, 0, false).    // 1 row before info message
 		AddItem(infoMsg, 1, 1, false).       // 1 row after info message
-		AddItem(form, 7, 0, true).           // the form spans 7 rows - including password and repeat password fields and buttons for ok and cancel bellow
+		AddItem(form, 9, 0, true).           // the form spans 9 rows - including password, repeat password and keyfile fields and buttons for ok and cancel bellow
 		AddItem(message, 0, 1, false))       // dynamic size of field that contains error message (such as repeat password doesn't match)
 
 	formWithMessage.SetBorder(true).
@@ -214,7 +348,7 @@ func setNewPasswordForm() {
 	// vertical centering
 	centeredModal = styleFlex(tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(nil, 0, 1, false).          // top spacer
-		AddItem(initialModal, 15, 1, true). // form box automatic height
+		AddItem(initialModal, 17, 1, true). // form box automatic height
 		AddItem(nil, 0, 1, false))          // bottom spacer
 
 	root := styleFlex(tview.NewFlex().SetDirection(tview.FlexRow).
@@ -233,10 +367,23 @@ func setNewPasswordForm() {
 }
 
 // helper to check if newly set password is adequate and stores it in memory for later use in generating an encryption key
-func addInitialPassword(providedPass string) error {
-	if providedPass == "" {
-		return fmt.Errorf("password cannot be empty")
+// the password can be left empty when a keyfile is used instead
+func addInitialPassword(providedPass, keyFilePath string) error {
+	if providedPass == "" && strings.TrimSpace(keyFilePath) == "" {
+		return fmt.Errorf("password cannot be empty without a keyfile")
+	}
+	if err := setUserKeyFile(keyFilePath); err != nil {
+		return err
 	}
 	setUserPassword(providedPass)
 	return nil
 }
+
+// helper to tell the user what unlocks the database when the password and keyfile don't match it
+func unlockModeHint() string {
+	header, err := readEncHeader(globalConfig.EncryptedDBFile)
+	if err != nil || header == nil {
+		return "This database needs a password."
+	}
+	return fmt.Sprintf("This database needs %s.", unlockModeName(header.unlockSlotKind()))
+}
@@ -22,6 +22,7 @@ func main() {
 	config, err := loadConfigFromEnvVars()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load config from env var, err %v\n", err)
+		os.Exit(1)
 	}
 	SetGlobalConfig(config)
 
@@ -34,9 +35,19 @@ func main() {
 	log.SetOutput(io.MultiWriter(logFile))
 	log.SetFlags(log.LstdFlags | log.Lshortfile) // timestamps + file:line info
 
+	// passwords and keys are kept out of swap and core dumps where the platform allows it
+	protectSecretMemory(config)
+
 	// set up graceful shutdown handler to make sure database re-encryption happens even if the tui gets killed
 	setupGracefulShutdown(config)
 
+	// a subcommand runs headless without starting the TUI (e.g. for scripting entries from cron jobs)
+	if len(os.Args) > 1 {
+		exitCode := runCli(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+		log.Printf("Exit Expense Tracking Tool")
+		os.Exit(exitCode)
+	}
+
 	tui = tview.NewApplication()
 	tui.SetBeforeDrawFunc(func(screen tcell.Screen) bool {
 		screen.Clear()
@@ -56,25 +67,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	// every key press keeps the session unlocked, after the idle timeout it is locked and the login prompt shown again
+	tui.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		recordActivity()
+		return event
+	})
+	startIdleLock(config)
+
 	if err := tui.Run(); err != nil {
 		log.Printf("tui failed to start: %s\n", err)
 		os.Exit(1)
 	}
+	stopIdleLock()
 
 	// on normal shutdown, close and re-encrypt DB if user was authenticated
-	if config.StorageType == StorageSQLite {
-		closeDb()
-		if userPassword != "" {
-			if err := encryptDatabase(config.UnencryptedDbFile); err != nil {
-				log.Printf("failed to encrypt database on shutdown: %s\n", err)
-			} else {
-				// remove unencrypted database file after successful encryption
-				if err := os.Remove(config.UnencryptedDbFile); err != nil {
-					log.Printf("warning: failed to remove plaintext database: %s\n", err)
-				}
-			}
-		}
-	}
+	closeAndEncryptDb(config)
 
 	log.Printf("Exit Expense Tracking Tool")
 }
@@ -89,19 +96,7 @@ func setupGracefulShutdown(config *Config) {
 		<-c // blocks until a signal is received
 
 		// close db and re-encrypt database before exiting
-		if config.StorageType == StorageSQLite {
-			closeDb()
-			if userPassword != "" {
-				if err := encryptDatabase(config.UnencryptedDbFile); err != nil {
-					log.Printf("failed to encrypt database on shutdown: %s\n", err)
-				} else {
-					// remove unencrypted database file after successful encryption
-					if err := os.Remove(config.UnencryptedDbFile); err != nil {
-						log.Printf("warning: failed to remove plaintext database: %s\n", err)
-					}
-				}
-			}
-		}
+		closeAndEncryptDb(config)
 		clearUserPassword() // clear password from memory
 		if logFile != nil {
 			if err := logFile.Sync(); err != nil {
@@ -114,3 +109,57 @@ func setupGracefulShutdown(config *Config) {
 		// os.Exit(0)
 	}()
 }
+
+// closes the db connection and re-encrypts the database if the user was authenticated, the plaintext copy is removed after a successful encryption
+func closeAndEncryptDb(config *Config) {
+	if config.StorageType != StorageSQLite {
+		return
+	}
+
+	// other instances wait for the lock until the database is encrypted again
+	defer releaseInstanceLock()
+	// the data key is only kept while the database is open
+	defer forgetDataKey()
+
+	// a checkpoint that is still being written finishes first, the final state is encrypted below
+	stopCheckpoints()
+
+	// a read-only session never writes the database back, that is up to the instance holding the lock
+	if readOnlySession {
+		closeDb()
+		return
+	}
+
+	if !haveCredentials() {
+		closeDb()
+		return
+	}
+
+	// the in-memory database is serialized and encrypted straight from memory, there is no plaintext file to clean up
+	if config.InMemoryDb {
+		if db == nil {
+			return
+		}
+		image, err := serializeDb()
+		closeDb()
+		if err != nil {
+			log.Printf("failed to serialize database on shutdown: %s\n", err)
+			return
+		}
+		if err := encryptDatabaseImage(image); err != nil {
+			log.Printf("failed to encrypt database on shutdown: %s\n", err)
+		}
+		return
+	}
+
+	closeDb()
+	if err := encryptDatabase(config.UnencryptedDbFile); err != nil {
+		log.Printf("failed to encrypt database on shutdown: %s\n", err)
+		return
+	}
+
+	// remove unencrypted database file after successful encryption
+	if err := os.Remove(config.UnencryptedDbFile); err != nil {
+		log.Printf("warning: failed to remove plaintext database: %s\n", err)
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// a single schema change, migrations are applied in order and every version only once
+type migration struct {
+	version     int
+	description string
+	up          func(sqlTx *sql.Tx) error
+}
+
+// all schema changes in the order they have to be applied, new migrations are only ever appended to the end
+// the first migrations are written so they also work on databases created before schema versions were tracked
+var migrations = []migration{
+	{1, "create transactions table", migrateCreateTransactionsTable},
+	{2, "create import profiles table", migrateCreateImportProfilesTable},
+	{3, "create imported transactions table", migrateCreateImportedTransactionsTable},
+	{4, "add transaction date column", migrateAddTransactionDateColumn},
+	{5, "index transactions by period and type", migrateIndexTransactionsByPeriod},
+	{6, "create categories table", migrateCreateCategoriesTable},
+	{7, "create transaction tags table", migrateCreateTransactionTagsTable},
+	{8, "create budgets table", migrateCreateBudgetsTable},
+	{9, "create recurring rules tables", migrateCreateRecurringRulesTables},
+	{10, "remove import references of deleted transactions", migrateRemoveOrphanedImportRefs},
+}
+
+var ErrDbNewerThanBinary = errors.New("database was created by a newer version of expense-tracking")
+
+// helper to get the schema version this binary expects
+func latestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
+}
+
+// brings the database schema up to date, all pending migrations run in a single transaction so a failure leaves the database untouched
+// backup is called once before anything is changed, with the current and the target version
+func runMigrations(backup func(from, to int) error) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version     INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at  TEXT NOT NULL
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("prep schema version db table err: %w", err)
+	}
+
+	current, err := currentSchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	latest := latestSchemaVersion()
+	if current > latest {
+		return fmt.Errorf("%w - database schema version is %d but this binary only supports up to %d, please upgrade expense-tracking", ErrDbNewerThanBinary, current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	if backup != nil {
+		if err := backup(current, latest); err != nil {
+			return fmt.Errorf("failed to back up database before migrating: %w", err)
+		}
+	}
+
+	sqlTx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin migration failed: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := m.up(sqlTx); err != nil {
+			sqlTx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+		}
+
+		_, err := sqlTx.Exec(`INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?)`,
+			m.version, m.description, time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			sqlTx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+		}
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("commit migrations failed: %w", err)
+	}
+
+	log.Printf("migrated database schema from version %d to %d", current, latest)
+	return nil
+}
+
+// helper to read the version of the last applied migration, 0 for a new database or one created before versions were tracked
+func currentSchemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// keeps a copy of the encrypted database as it was before migrating, e.g. transactions.enc.schema-v3.bak
+// the copy is encrypted with the same password and salt, so it can be restored by renaming it back
+func backupEncryptedDbBeforeMigration(dbFilePath string) func(from, to int) error {
+	return func(from, to int) error {
+		// only the database the encrypted file belongs to is backed up, a new database has nothing to back up
+		if globalConfig == nil || globalConfig.UnencryptedDbFile != dbFilePath {
+			return nil
+		}
+		return backupEncryptedDbFile(globalConfig.EncryptedDBFile, from, to)
+	}
+}
+
+// helper to copy the encrypted database next to itself before a migration, a missing file is not an error
+func backupEncryptedDbFile(encryptedDbFile string, from, to int) error {
+	src, err := os.Open(encryptedDbFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open encrypted database: %w", err)
+	}
+	defer src.Close()
+
+	backupPath := fmt.Sprintf("%s.schema-v%d.bak", encryptedDbFile, from)
+	dst, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to sync backup file: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close backup file: %w", err)
+	}
+
+	log.Printf("backed up encrypted database to %s before migrating from schema version %d to %d", backupPath, from, to)
+	return nil
+}
+
+// the original transactions table with only a year and month per transaction
+func migrateCreateTransactionsTable(sqlTx *sql.Tx) error {
+	_, err := sqlTx.Exec(`
+		CREATE TABLE IF NOT EXISTS transactions (
+			id				  TEXT PRIMARY KEY,
+			amount 			NUMERIC(12, 2) NOT NULL,
+			type 				TEXT NOT NULL CHECK (type IN ('income', 'expense', 'investment')),
+			category 		TEXT NOT NULL,
+			description TEXT,
+			year 				INTEGER NOT NULL,
+			month 			TEXT NOT NULL CHECK (
+				month IN (
+            'january','february','march','april','may','june',
+            'july','august','september','october','november','december'
+				)
+			)
+		);
+	`)
+	return err
+}
+
+// named csv column mapping profiles used for bank statement imports, the mapping itself is stored as json
+func migrateCreateImportProfilesTable(sqlTx *sql.Tx) error {
+	_, err := sqlTx.Exec(`
+		CREATE TABLE IF NOT EXISTS import_profiles (
+			name    TEXT PRIMARY KEY,
+			profile TEXT NOT NULL
+		);
+	`)
+	return err
+}
+
+// keeps track of imported transactions - the bank reference (e.g. OFX FITID) is used to skip entries that were already imported
+// and entries whose category could not be mapped stay flagged for review
+func migrateCreateImportedTransactionsTable(sqlTx *sql.Tx) error {
+	_, err := sqlTx.Exec(`
+		CREATE TABLE IF NOT EXISTS imported_transactions (
+			transaction_id TEXT PRIMARY KEY,
+			ref            TEXT UNIQUE,
+			needs_review   INTEGER NOT NULL DEFAULT 0
+		);
+	`)
+	return err
+}
+
+// transactions used to only have a year and month, existing rows get the first day of their month as their date
+func migrateAddTransactionDateColumn(sqlTx *sql.Tx) error {
+	var exists int
+	err := sqlTx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('transactions') WHERE name = 'date'`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check for date column: %w", err)
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	if _, err := sqlTx.Exec(`ALTER TABLE transactions ADD COLUMN date TEXT`); err != nil {
+		return fmt.Errorf("failed to add date column: %w", err)
+	}
+
+	for month, number := range monthOrder {
+		_, err := sqlTx.Exec(`UPDATE transactions SET date = printf('%04d-%02d-01', year, ?) WHERE month = ?`, number, month)
+		if err != nil {
+			return fmt.Errorf("failed to set date of %s transactions: %w", month, err)
+		}
+	}
+
+	return nil
+}
+
+// the tui and the row level handlers mostly look up transactions of a single month and type
+func migrateIndexTransactionsByPeriod(sqlTx *sql.Tx) error {
+	_, err := sqlTx.Exec(`CREATE INDEX IF NOT EXISTS idx_transactions_period ON transactions (year, month, type)`)
+	return err
+}
+
+// categories used to be hardcoded, they are seeded with the defaults and any other category existing transactions already use
+func migrateCreateCategoriesTable(sqlTx *sql.Tx) error {
+	_, err := sqlTx.Exec(`
+		CREATE TABLE IF NOT EXISTS categories (
+			type        TEXT NOT NULL CHECK (type IN ('income', 'expense', 'investment')),
+			name        TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			archived    INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (type, name)
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create categories table: %w", err)
+	}
+
+	for txType, categories := range defaultTransactionCategories {
+		for name, description := range categories {
+			_, err := sqlTx.Exec(`INSERT OR IGNORE INTO categories (type, name, description) VALUES (?, ?, ?)`, txType, name, description)
+			if err != nil {
+				return fmt.Errorf("failed to seed %s category %s: %w", txType, name, err)
+			}
+		}
+	}
+
+	_, err = sqlTx.Exec(`INSERT OR IGNORE INTO categories (type, name) SELECT DISTINCT type, category FROM transactions`)
+	if err != nil {
+		return fmt.Errorf("failed to add categories of existing transactions: %w", err)
+	}
+	return nil
+}
+
+// free-form tags of transactions, a transaction can have many tags and a tag can be on transactions of any type
+func migrateCreateTransactionTagsTable(sqlTx *sql.Tx) error {
+	_, err := sqlTx.Exec(`
+		CREATE TABLE IF NOT EXISTS transaction_tags (
+			transaction_id TEXT NOT NULL,
+			tag            TEXT NOT NULL,
+			PRIMARY KEY (transaction_id, tag)
+		);
+		CREATE INDEX IF NOT EXISTS idx_transaction_tags_tag ON transaction_tags (tag);
+	`)
+	return err
+}
+
+// monthly spending limits of expense categories, since is the first month (YYYY-MM) unspent amounts are carried over from
+func migrateCreateBudgetsTable(sqlTx *sql.Tx) error {
+	_, err := sqlTx.Exec(`
+		CREATE TABLE IF NOT EXISTS budgets (
+			category  TEXT PRIMARY KEY,
+			amount    NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+			carryover INTEGER NOT NULL DEFAULT 0,
+			since     TEXT NOT NULL
+		);
+	`)
+	return err
+}
+
+// transactions that repeat on a schedule, last_date is the latest occurrence (YYYY-MM-DD) that was posted or queued
+// occurrences of rules without auto posting wait in the queue until they are confirmed or skipped
+func migrateCreateRecurringRulesTables(sqlTx *sql.Tx) error {
+	_, err := sqlTx.Exec(`
+		CREATE TABLE IF NOT EXISTS recurring_rules (
+			id          INTEGER PRIMARY KEY AUTOINCREMENT,
+			type        TEXT NOT NULL,
+			amount      NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+			category    TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			tags        TEXT NOT NULL DEFAULT '',
+			frequency   TEXT NOT NULL CHECK (frequency IN ('monthly', 'quarterly', 'yearly')),
+			start_date  TEXT NOT NULL,
+			end_date    TEXT,
+			auto_post   INTEGER NOT NULL DEFAULT 1,
+			paused      INTEGER NOT NULL DEFAULT 0,
+			last_date   TEXT
+		);
+		CREATE TABLE IF NOT EXISTS recurring_queue (
+			rule_id INTEGER NOT NULL,
+			date    TEXT NOT NULL,
+			PRIMARY KEY (rule_id, date)
+		);
+	`)
+	return err
+}
+
+// deleting a transaction used to keep its import reference, which made importing the statement again skip it
+func migrateRemoveOrphanedImportRefs(sqlTx *sql.Tx) error {
+	_, err := sqlTx.Exec(`DELETE FROM imported_transactions WHERE transaction_id NOT IN (SELECT id FROM transactions)`)
+	return err
+}
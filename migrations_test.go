@@ -0,0 +1,173 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// helper to open a database file without running any migrations
+func openTestDbWithoutMigrations(t *testing.T, schema string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transactions.db")
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	defer raw.Close()
+	if schema != "" {
+		if _, err := raw.Exec(schema); err != nil {
+			t.Fatalf("Failed to create schema: %v", err)
+		}
+	}
+	return path
+}
+
+func TestRunMigrationsOnNewDb(t *testing.T) {
+	path := openTestDbWithoutMigrations(t, "")
+	if err := initDb(path); err != nil {
+		t.Fatalf("Expected no error initializing a new db, got %v", err)
+	}
+	defer closeDb()
+
+	version, err := currentSchemaVersion()
+	if err != nil || version != latestSchemaVersion() {
+		t.Errorf("Expected schema version %d, got %d (err %v)", latestSchemaVersion(), version, err)
+	}
+
+	// running the migrations again is a no-op
+	if err := runMigrations(nil); err != nil {
+		t.Errorf("Expected no error re-running migrations, got %v", err)
+	}
+	var applied int
+	db.QueryRow(`SELECT COUNT(*) FROM schema_version`).Scan(&applied)
+	if applied != len(migrations) {
+		t.Errorf("Expected every migration to be recorded once, got %d rows", applied)
+	}
+}
+
+func TestMigrateDbFromBeforeVersions(t *testing.T) {
+	// a database with the schema from before versions and transaction dates were tracked
+	path := openTestDbWithoutMigrations(t, `
+		CREATE TABLE transactions (
+			id TEXT PRIMARY KEY, amount NUMERIC(12, 2) NOT NULL, type TEXT NOT NULL, category TEXT NOT NULL,
+			description TEXT, year INTEGER NOT NULL, month TEXT NOT NULL
+		);
+		INSERT INTO transactions VALUES ('a1b2c3d4', 12.5, 'expense', 'food', 'lunch', 2024, 'march');
+		INSERT INTO transactions VALUES ('e5f6a7b8', 1000, 'income', 'salary', 'salary', 2023, 'december');
+	`)
+
+	if err := initDb(path); err != nil {
+		t.Fatalf("Expected no error migrating the database, got %v", err)
+	}
+	defer closeDb()
+
+	transactions, err := loadTransactionsFromDb()
+	if err != nil {
+		t.Fatalf("Expected no error loading migrated transactions, got %v", err)
+	}
+	if got := transactions["2024"]["march"]["expense"][0].Date.Format(TransactionDateFormat); got != "2024-03-01" {
+		t.Errorf("Expected existing transaction to get the first of its month, got %s", got)
+	}
+	if got := transactions["2023"]["december"]["income"][0].Date.Format(TransactionDateFormat); got != "2023-12-01" {
+		t.Errorf("Expected existing transaction to get the first of its month, got %s", got)
+	}
+	if _, err := db.Exec(`INSERT INTO import_profiles (name, profile) VALUES ('bank', '{}')`); err != nil {
+		t.Errorf("Expected import profiles table to be created, got %v", err)
+	}
+}
+
+func TestMigrationsNewerDb(t *testing.T) {
+	path := openTestDbWithoutMigrations(t, fmt.Sprintf(`
+		CREATE TABLE schema_version (version INTEGER PRIMARY KEY, description TEXT NOT NULL, applied_at TEXT NOT NULL);
+		INSERT INTO schema_version VALUES (%d, 'from the future', '2030-01-01T00:00:00Z');
+	`, latestSchemaVersion()+1))
+
+	err := initDb(path)
+	defer closeDb()
+	if !errors.Is(err, ErrDbNewerThanBinary) {
+		t.Errorf("Expected ErrDbNewerThanBinary, got %v", err)
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	original := migrations
+	t.Cleanup(func() { migrations = original })
+	next := latestSchemaVersion() + 1
+	migrations = append(append([]migration{}, original...),
+		migration{next, "add table", func(sqlTx *sql.Tx) error {
+			_, err := sqlTx.Exec(`CREATE TABLE half_done (id TEXT)`)
+			return err
+		}},
+		migration{next + 1, "broken", func(sqlTx *sql.Tx) error {
+			return fmt.Errorf("boom")
+		}},
+	)
+
+	var backups int
+	err := runMigrations(func(from, to int) error {
+		backups++
+		if from != next-1 || to != next+1 {
+			t.Errorf("Unexpected backup versions %d -> %d", from, to)
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("Expected error from broken migration")
+	}
+	if backups != 1 {
+		t.Errorf("Expected exactly one backup before migrating, got %d", backups)
+	}
+
+	if version, _ := currentSchemaVersion(); version != next-1 {
+		t.Errorf("Expected schema version to stay at %d, got %d", next-1, version)
+	}
+	var tables int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_done'`).Scan(&tables)
+	if tables != 0 {
+		t.Errorf("Expected changes of the failed migration run to be rolled back")
+	}
+}
+
+func TestBackupEncryptedDbBeforeMigration(t *testing.T) {
+	dir := t.TempDir()
+	config := &Config{
+		StorageType:       StorageSQLite,
+		UnencryptedDbFile: filepath.Join(dir, "transactions.db"),
+		EncryptedDBFile:   filepath.Join(dir, "transactions.enc"),
+	}
+	original := globalConfig
+	SetGlobalConfig(config)
+	t.Cleanup(func() { SetGlobalConfig(original) })
+
+	backup := backupEncryptedDbBeforeMigration(config.UnencryptedDbFile)
+
+	// nothing to back up for a new database
+	if err := backup(0, 4); err != nil {
+		t.Errorf("Expected no error without an encrypted database, got %v", err)
+	}
+
+	if err := os.WriteFile(config.EncryptedDBFile, []byte("encrypted"), 0600); err != nil {
+		t.Fatalf("Failed to write encrypted file: %v", err)
+	}
+	if err := backup(3, 4); err != nil {
+		t.Fatalf("Expected no error backing up, got %v", err)
+	}
+	data, err := os.ReadFile(config.EncryptedDBFile + ".schema-v3.bak")
+	if err != nil || string(data) != "encrypted" {
+		t.Errorf("Expected a copy of the encrypted database, got %q (err %v)", data, err)
+	}
+
+	// other databases (e.g. in tests) don't touch the configured encrypted file
+	if err := backupEncryptedDbBeforeMigration(filepath.Join(dir, "other.db"))(1, 4); err != nil {
+		t.Errorf("Expected no error for an unrelated database, got %v", err)
+	}
+	if _, err := os.Stat(config.EncryptedDBFile + ".schema-v1.bak"); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup for an unrelated database")
+	}
+}
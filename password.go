@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/rivo/tview"
+)
+
+// re-encrypts the open database with a new password and/or keyfile and a fresh salt, the current password has to match the one used to log in
+// an empty newKeyFilePath removes the keyfile, the data key stays the same so a recovery key keeps working
+func changePassword(currentPasswordText, newPasswordText, newKeyFilePath string) error {
+	if err := requireSQLiteStorage(); err != nil {
+		return err
+	}
+	if err := requireWritableDb(); err != nil {
+		return err
+	}
+
+	currentPassword, newPassword := []byte(currentPasswordText), []byte(newPasswordText)
+	defer wipe(currentPassword)
+	defer wipe(newPassword)
+
+	// a checkpoint with the old password must not overwrite the re-encrypted file, it is stopped before the credentials are held
+	// so a checkpoint that is still running can finish with them
+	if stopCheckpoints() {
+		defer startCheckpoints(globalConfig)
+	}
+
+	var newKeyFile []byte
+	defer func() { wipe(newKeyFile) }()
+	err := withCredentials(func(password, keyFile []byte) error {
+		if subtle.ConstantTimeCompare(currentPassword, password) != 1 {
+			return ErrWrongPassword
+		}
+
+		if newKeyFilePath = strings.TrimSpace(newKeyFilePath); newKeyFilePath != "" {
+			var err error
+			if newKeyFile, err = readKeyFile(newKeyFilePath); err != nil {
+				return err
+			}
+		}
+		if len(newPassword) == 0 && newKeyFile == nil {
+			return fmt.Errorf("new password cannot be empty without a keyfile")
+		}
+		if bytes.Equal(newPassword, currentPassword) && bytes.Equal(newKeyFile, keyFile) {
+			return fmt.Errorf("new password must differ from the current password")
+		}
+
+		image, err := serializeDb()
+		if err != nil {
+			return err
+		}
+
+		header, err := readEncHeader(globalConfig.EncryptedDBFile)
+		if err != nil {
+			return err
+		}
+		var dataKey []byte
+		if header != nil && header.version == encFormatVersion {
+			// only the password slot is replaced, other key slots like the recovery key are kept
+			if dataKey, err = header.dataKey(currentPassword, keyFile); err != nil {
+				return err
+			}
+			header, err = header.rekeyed(newPassword, newKeyFile, dataKey)
+		} else {
+			header, dataKey, err = newEncHeader(newPassword, newKeyFile)
+		}
+		if err != nil {
+			return err
+		}
+		defer wipe(dataKey)
+
+		encryptedData, err := sealDatabaseImage(header, dataKey, image)
+		if err != nil {
+			return err
+		}
+		return replaceEncryptedDatabase(encryptedData, image, newPassword, newKeyFile)
+	})
+	if errors.Is(err, ErrNoCredentials) {
+		return ErrWrongPassword
+	}
+	if err != nil {
+		return err
+	}
+
+	// every later save uses the new password and keyfile
+	replaceUserCredentials(newPassword, newKeyFilePath, newKeyFile)
+	log.Printf("password changed, database re-encrypted with a new salt")
+	return nil
+}
+
+// helper to replace the encrypted database file, the new contents are written next to it first and only replace it
+// once they decrypt back to the same database with the password and keyfile
+func replaceEncryptedDatabase(encryptedData, image, password, keyFile []byte) error {
+	rekeyPath := globalConfig.EncryptedDBFile + ".rekey"
+	if err := writeFileAtomically(rekeyPath, encryptedData, 0600); err != nil {
+		return fmt.Errorf("failed to write re-encrypted database: %w", err)
+	}
+	defer os.Remove(rekeyPath) // no-op after a successful rename
+
+	// check what actually landed on disk before the old file is replaced
+	written, err := os.ReadFile(rekeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read re-encrypted database: %w", err)
+	}
+	decrypted, err := openDatabaseImage(password, keyFile, written)
+	if err != nil {
+		return fmt.Errorf("failed to verify re-encrypted database, the old database is kept: %w", err)
+	}
+	if !bytes.Equal(decrypted, image) {
+		return fmt.Errorf("re-encrypted database doesn't match the open database, the old database is kept")
+	}
+
+	if err := os.Rename(rekeyPath, globalConfig.EncryptedDBFile); err != nil {
+		return fmt.Errorf("failed to replace encrypted database: %w", err)
+	}
+	return nil
+}
+
+// creates the TUI form for changing the password of the encrypted database
+func formChangePassword(selectedMonth, selectedYear, focusTableType string) error {
+	if err := requireSQLiteStorage(); err != nil {
+		return err
+	}
+	if err := requireWritableDb(); err != nil {
+		return err
+	}
+
+	currentPasswordField := styleInputField(tview.NewInputField().
+		SetLabel("Current Password: ").
+		SetMaskCharacter('*'))
+	newPasswordField := styleInputField(tview.NewInputField().
+		SetLabel("New Password: ").
+		SetMaskCharacter('*'))
+	repeatPasswordField := styleInputField(tview.NewInputField().
+		SetLabel("Repeat New Password: ").
+		SetMaskCharacter('*'))
+	// the keyfile stays the same unless the path is changed, clearing it unlocks the database with only the new password
+	keyFileField := styleInputField(tview.NewInputField().
+		SetLabel("Keyfile (optional): ").
+		SetText(currentKeyFilePath()))
+
+	message := styleTextView(tview.NewTextView().
+		SetText("").
+		SetTextAlign(tview.AlignCenter))
+
+	var form *tview.Form
+
+	// back to the list of transactions at the same month and year from where the form was opened
+	backToTransactions := func() {
+		pages.RemovePage("change-password")
+		gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType, true)
+	}
+
+	form = styleForm(tview.NewForm().
+		AddFormItem(currentPasswordField).
+		AddFormItem(newPasswordField).
+		AddFormItem(repeatPasswordField).
+		AddFormItem(keyFileField).
+		AddButton("Change", func() {
+			if newPasswordField.GetText() != repeatPasswordField.GetText() {
+				message.SetText("New passwords do not match. Try again.")
+				newPasswordField.SetText("")
+				repeatPasswordField.SetText("")
+				return
+			}
+
+			if err := changePassword(currentPasswordField.GetText(), newPasswordField.GetText(), keyFileField.GetText()); err != nil {
+				if errors.Is(err, ErrWrongPassword) {
+					message.SetText("Wrong current password. Try again.")
+					currentPasswordField.SetText("")
+					return
+				}
+				showErrorModal(fmt.Sprintf("failed to change password:\n\n%s", err), form)
+				log.Printf("failed to change password: %s", err)
+				return
+			}
+
+			backToTransactions()
+			showInfoModal("Password changed.\n\nThe database is now encrypted with the new password.", tui.GetFocus())
+		}).
+		AddButton("Cancel", backToTransactions))
+
+	form.SetButtonsAlign(tview.AlignCenter)
+
+	formWithMessage := styleFlex(tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(form, 0, 1, true).
+		AddItem(message, 1, 0, false))
+
+	formWithMessage.SetBorder(true).
+		SetTitle("Change Password").
+		SetTitleAlign(tview.AlignCenter)
+
+	// navigation help
+	frame := tview.NewFrame(formWithMessage).
+		AddText(generateCombinedControlsFooter(), false, tview.AlignCenter, theme.FieldTextColor)
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).   // left spacer
+		AddItem(frame, 60, 1, true). // form width fixed to fit the labels
+		AddItem(nil, 0, 1, false))   // right spacer
+
+	// vertical centering
+	centeredModal := styleFlex(tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).   // top spacer
+		AddItem(modal, 16, 1, true). // enough to fit the four fields, the buttons and the message
+		AddItem(nil, 0, 1, false))   // bottom spacer
+
+	pages.AddPage("change-password", centeredModal, true, true)
+	tui.SetFocus(form)
+
+	// back to transactions list on ESC or q key press, q is typed into the fields as usual
+	form.SetInputCapture(exitShortcutsWithPeriod(selectedMonth, selectedYear, focusTableType))
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// helper to open a file backed session database with one transaction that is already encrypted with testpassword
+func setupChangePasswordSession(t *testing.T) *Config {
+	t.Helper()
+	setUserPassword("testpassword")
+
+	dir := t.TempDir()
+	config := &Config{
+		StorageType:       StorageSQLite,
+		UnencryptedDbFile: filepath.Join(dir, "transactions.db"),
+		EncryptedDBFile:   filepath.Join(dir, "transactions.enc"),
+		SaltFile:          filepath.Join(dir, "transactions.salt"),
+	}
+	original, originalDb := globalConfig, db
+	SetGlobalConfig(config)
+	t.Cleanup(func() {
+		closeDb()
+		clearUserPassword()
+		SetGlobalConfig(original)
+		db = originalDb
+	})
+
+	if err := initSessionDb(config, nil); err != nil {
+		t.Fatalf("Failed to open session db: %v", err)
+	}
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "food", Date: "2025-03-14"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	if err := checkpointDb(); err != nil {
+		t.Fatalf("Failed to encrypt db: %v", err)
+	}
+	return config
+}
+
+func TestChangePassword(t *testing.T) {
+	config := setupChangePasswordSession(t)
+	before, _ := os.ReadFile(config.EncryptedDBFile)
+	oldHeader, _ := readEncHeader(config.EncryptedDBFile)
+
+	if err := changePassword("testpassword", "newpassword", ""); err != nil {
+		t.Fatalf("Expected no error changing password, got %v", err)
+	}
+	if string(userPassword) != "newpassword" {
+		t.Errorf("Expected the session to use the new password")
+	}
+
+	newHeader, _ := readEncHeader(config.EncryptedDBFile)
+	if newHeader == nil || bytes.Equal(newHeader.salt, oldHeader.salt) {
+		t.Errorf("Expected the database to be re-encrypted with a fresh salt")
+	}
+	if _, err := os.Stat(config.EncryptedDBFile + ".rekey"); !os.IsNotExist(err) {
+		t.Errorf("Expected no re-encrypted file to be left behind")
+	}
+
+	after, _ := os.ReadFile(config.EncryptedDBFile)
+	if _, err := openDatabaseImage([]byte("testpassword"), nil, after); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected the old password to be rejected, got %v", err)
+	}
+	image, err := openDatabaseImage([]byte("newpassword"), nil, after)
+	if err != nil || countTransactionsInImage(t, image) != 1 {
+		t.Errorf("Expected the new password to decrypt the database (err %v)", err)
+	}
+	if bytes.Equal(before, after) {
+		t.Errorf("Expected the encrypted database to change")
+	}
+
+	// later saves keep using the new password
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "12", Category: "food", Date: "2025-03-15"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	closeAndEncryptDb(config)
+	final, _ := os.ReadFile(config.EncryptedDBFile)
+	image, err = openDatabaseImage([]byte("newpassword"), nil, final)
+	if err != nil || countTransactionsInImage(t, image) != 2 {
+		t.Errorf("Expected the saved database to be encrypted with the new password (err %v)", err)
+	}
+}
+
+func TestChangePasswordRejected(t *testing.T) {
+	cases := []struct {
+		name            string
+		currentPassword string
+		newPassword     string
+		expectedError   error
+	}{
+		{"wrong current password", "wrongpassword", "newpassword", ErrWrongPassword},
+		{"empty new password", "testpassword", "", nil},
+		{"unchanged password", "testpassword", "testpassword", nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := setupChangePasswordSession(t)
+			before, _ := os.ReadFile(config.EncryptedDBFile)
+
+			err := changePassword(c.currentPassword, c.newPassword, "")
+			if err == nil {
+				t.Fatalf("Expected error changing password")
+			}
+			if c.expectedError != nil && !errors.Is(err, c.expectedError) {
+				t.Errorf("Expected %v, got %v", c.expectedError, err)
+			}
+
+			after, _ := os.ReadFile(config.EncryptedDBFile)
+			if !bytes.Equal(before, after) || string(userPassword) != "testpassword" {
+				t.Errorf("Expected the database and password to stay unchanged")
+			}
+		})
+	}
+}
+
+func TestChangePasswordKeepsCheckpointsRunning(t *testing.T) {
+	config := setupCheckpointSession(t, 1, 0)
+
+	if err := changePassword("testpassword", "newpassword", ""); err != nil {
+		t.Fatalf("Expected no error changing password, got %v", err)
+	}
+	if activeCheckpointer == nil {
+		t.Fatalf("Expected checkpoints to run again after changing the password")
+	}
+	rekeyed, _ := os.ReadFile(config.EncryptedDBFile)
+
+	// the next checkpoint is written with the new password
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "food", Date: "2025-03-14"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if current, _ := os.ReadFile(config.EncryptedDBFile); !bytes.Equal(current, rekeyed) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	stopCheckpoints()
+
+	image, err := decryptDatabaseImage()
+	if err != nil || countTransactionsInImage(t, image) != 1 {
+		t.Errorf("Expected a checkpoint encrypted with the new password (err %v)", err)
+	}
+}
+
+func TestChangePasswordInReadOnlySession(t *testing.T) {
+	setupChangePasswordSession(t)
+	readOnlySession = true
+
+	if err := changePassword("testpassword", "newpassword", ""); !errors.Is(err, ErrReadOnlyStore) {
+		t.Errorf("Expected ErrReadOnlyStore, got %v", err)
+	}
+}
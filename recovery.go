@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// how to deal with an unencrypted database that a previous run left behind
+const (
+	RecoverLeftoverDb  = "recover"   // encrypt the leftover database, replacing the older encrypted one
+	DiscardLeftoverDb  = "discard"   // remove the leftover database and keep the encrypted one
+	KeepBothLeftoverDb = "keep-both" // keep the encrypted database and store the leftover one as an encrypted conflict copy
+)
+
+// an unencrypted database left behind by a run that died before re-encrypting it, which differs from the encrypted database
+type leftoverDb struct {
+	path             string
+	plaintextModTime time.Time
+	encryptedModTime time.Time
+	valid            bool // looks like a complete sqlite database, a half written file can only be discarded or kept
+	journal          bool // the run died in the middle of a write, the journal was rolled back into the leftover
+}
+
+// helper to name the rollback journal sqlite keeps next to the database while a write is in progress
+func leftoverJournalPath(config *Config) string {
+	return config.UnencryptedDbFile + "-journal"
+}
+
+// rolls back the write a died run left in its journal, sqlite does this when the database is opened and read
+func rollbackLeftoverJournal(path string) error {
+	plainDb, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("failed to open unencrypted database: %w", err)
+	}
+	defer plainDb.Close()
+
+	var tables int
+	if err := plainDb.QueryRow(`SELECT COUNT(*) FROM sqlite_master`).Scan(&tables); err != nil {
+		return fmt.Errorf("failed to roll back the journal of the unencrypted database: %w", err)
+	}
+	return nil
+}
+
+// checks for an unencrypted database from a previous run, needs the password to compare it with the encrypted database
+// a leftover that is identical to the encrypted database is removed right away and nil is returned
+func detectLeftoverDb(config *Config) (*leftoverDb, error) {
+	_, err := os.Stat(leftoverJournalPath(config))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to check for a database journal: %w", err)
+	}
+	journal := err == nil
+
+	plainInfo, err := os.Stat(config.UnencryptedDbFile)
+	if os.IsNotExist(err) {
+		// a journal without its database can't be applied, but would be rolled back into the next decrypted copy
+		if journal {
+			if err := os.Remove(leftoverJournalPath(config)); err != nil {
+				return nil, fmt.Errorf("failed to remove database journal: %w", err)
+			}
+			log.Printf("removed a database journal without its database")
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for unencrypted database: %w", err)
+	}
+
+	// without an encrypted database the leftover is the only copy, it is opened as is
+	encInfo, err := os.Stat(config.EncryptedDBFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for encrypted database: %w", err)
+	}
+
+	encrypted, err := decryptDatabaseImage()
+	if err != nil {
+		return nil, err
+	}
+
+	// the leftover is only consistent once the interrupted write is rolled back, if that fails it is treated as incomplete
+	rolledBack := false
+	if journal {
+		if err := rollbackLeftoverJournal(config.UnencryptedDbFile); err != nil {
+			log.Printf("warning: %s", err)
+		} else {
+			rolledBack = true
+		}
+	}
+
+	plaintext, err := os.ReadFile(config.UnencryptedDbFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read unencrypted database: %w", err)
+	}
+
+	// with a journal the run died while writing, so it always needs a decision even if the contents match
+	if !journal && bytes.Equal(plaintext, encrypted) {
+		if err := os.Remove(config.UnencryptedDbFile); err != nil {
+			return nil, fmt.Errorf("failed to remove unencrypted database: %w", err)
+		}
+		log.Printf("removed leftover unencrypted database, it matched the encrypted database")
+		return nil, nil
+	}
+
+	return &leftoverDb{
+		path:             config.UnencryptedDbFile,
+		plaintextModTime: plainInfo.ModTime(),
+		encryptedModTime: encInfo.ModTime(),
+		valid:            isSqliteImage(plaintext) && (!journal || rolledBack),
+		journal:          journal,
+	}, nil
+}
+
+// helper to check for the header every sqlite database file starts with
+func isSqliteImage(data []byte) bool {
+	return len(data) >= 100 && bytes.HasPrefix(data, []byte("SQLite format 3\x00"))
+}
+
+// applies the chosen way of dealing with a leftover database, the unencrypted file is gone afterwards in every case
+func resolveLeftoverDb(config *Config, leftover *leftoverDb, choice string) error {
+	switch choice {
+	case RecoverLeftoverDb:
+		if !leftover.valid {
+			return fmt.Errorf("the unencrypted database is incomplete and can't be recovered")
+		}
+		plaintext, err := os.ReadFile(leftover.path)
+		if err != nil {
+			return fmt.Errorf("failed to read unencrypted database: %w", err)
+		}
+		if err := encryptDatabaseImage(plaintext); err != nil {
+			return err
+		}
+		log.Printf("recovered leftover unencrypted database from %s", leftover.plaintextModTime.Format(time.RFC3339))
+
+	case KeepBothLeftoverDb:
+		plaintext, err := os.ReadFile(leftover.path)
+		if err != nil {
+			return fmt.Errorf("failed to read unencrypted database: %w", err)
+		}
+		conflictPath := leftoverConflictPath(config, leftover)
+		if err := encryptDatabaseImageTo(conflictPath, plaintext); err != nil {
+			return err
+		}
+		log.Printf("kept leftover unencrypted database as conflict copy %s", conflictPath)
+
+	case DiscardLeftoverDb:
+		log.Printf("discarded leftover unencrypted database from %s", leftover.plaintextModTime.Format(time.RFC3339))
+
+	default:
+		return fmt.Errorf("unknown choice %q for leftover database", choice)
+	}
+
+	if err := os.Remove(leftover.path); err != nil {
+		return fmt.Errorf("failed to remove unencrypted database: %w", err)
+	}
+	if err := os.Remove(leftoverJournalPath(config)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove database journal: %w", err)
+	}
+	return nil
+}
+
+// helper to name the conflict copy after the time the leftover database was last changed, e.g. transactions.enc.conflict-20250314-093000
+// it is encrypted with the same password, so it can be opened by renaming it to transactions.enc
+func leftoverConflictPath(config *Config, leftover *leftoverDb) string {
+	return fmt.Sprintf("%s.conflict-%s", config.EncryptedDBFile, leftover.plaintextModTime.Format("20060102-150405"))
+}
+
+// asks the user what to do with a leftover unencrypted database, onResolved continues the login once it has been dealt with
+func showLeftoverDbPrompt(leftover *leftoverDb, focus tview.Primitive, onResolved func()) {
+	newer := "older"
+	if leftover.plaintextModTime.After(leftover.encryptedModTime) {
+		newer = "newer"
+	}
+
+	// with a journal the contents may even match, the run died while writing to the unencrypted copy
+	differ := "their contents differ"
+	if leftover.journal {
+		differ = "the run exited in the middle of a change to it"
+	}
+	text := fmt.Sprintf("A previous run exited without encrypting the database.\n\nThe unencrypted copy (%s) is %s than the encrypted one (%s) and %s.",
+		leftover.plaintextModTime.Format("2006-01-02 15:04"), newer, leftover.encryptedModTime.Format("2006-01-02 15:04"), differ)
+
+	buttons := []string{"Keep both", "Discard", "Cancel"}
+	choices := map[string]string{"Recover": RecoverLeftoverDb, "Keep both": KeepBothLeftoverDb, "Discard": DiscardLeftoverDb}
+	if leftover.valid {
+		buttons = append([]string{"Recover"}, buttons...)
+		text += "\n\nRecover replaces the encrypted database with the unencrypted copy, Keep both stores it as an encrypted conflict copy and Discard deletes it."
+	} else {
+		text += "\n\nThe unencrypted copy is incomplete and can't be recovered. Keep both stores it as an encrypted conflict copy and Discard deletes it."
+	}
+
+	closePrompt := func() {
+		pages.RemovePage("leftoverDbPrompt")
+		tui.SetFocus(focus)
+	}
+
+	modal := styleModal(tview.NewModal().
+		SetText(text).
+		AddButtons(buttons).
+		SetDoneFunc(func(_ int, label string) {
+			closePrompt()
+			choice, ok := choices[label]
+			if !ok {
+				clearUserPassword() // cancelled, back to the login prompt
+				return
+			}
+			if err := resolveLeftoverDb(globalConfig, leftover, choice); err != nil {
+				showErrorModal(fmt.Sprintf("failed to resolve leftover database:\n\n%s", err), focus)
+				log.Printf("failed to resolve leftover database: %s", err)
+				clearUserPassword()
+				return
+			}
+			onResolved()
+		}))
+
+	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closePrompt()
+			clearUserPassword()
+			return nil
+		}
+		return event
+	})
+
+	overlay := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(modal, 16, 1, true). // modal height
+		AddItem(nil, 0, 1, false)
+
+	centered := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(overlay, 70, 1, true). // modal width
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage("leftoverDbPrompt", centered, true, true)
+	tui.SetFocus(modal)
+}
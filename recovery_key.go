@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// replaces the recovery key of the open database and returns the new one, it is only shown once
+// the database gets a new data key as well, so the old recovery key no longer opens it
+func regenerateRecoveryKey() (string, error) {
+	if err := requireSQLiteStorage(); err != nil {
+		return "", err
+	}
+	if err := requireWritableDb(); err != nil {
+		return "", err
+	}
+	// a checkpoint must not overwrite the file with the new recovery key
+	if stopCheckpoints() {
+		defer startCheckpoints(globalConfig)
+	}
+
+	image, err := serializeDb()
+	if err != nil {
+		return "", err
+	}
+	recoveryKey, err := generateRecoveryKey()
+	if err != nil {
+		return "", err
+	}
+
+	err = withCredentials(func(password, keyFile []byte) error {
+		header, dataKey, err := newEncHeader(password, keyFile)
+		if err != nil {
+			return err
+		}
+		defer wipe(dataKey)
+		if err := header.setRecoveryKey(recoveryKey, dataKey); err != nil {
+			return err
+		}
+
+		encryptedData, err := sealDatabaseImage(header, dataKey, image)
+		if err != nil {
+			return err
+		}
+		return replaceEncryptedDatabase(encryptedData, image, password, keyFile)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("recovery key regenerated")
+	return recoveryKey, nil
+}
+
+// sets a new password with the recovery key when the password or the keyfile is lost, the recovery key keeps working afterwards
+// the database is unlocked with just the new password from then on, the instance lock has to be held and the login continues as usual
+func recoverWithRecoveryKey(recoveryKey, newPasswordText string) error {
+	if newPasswordText == "" {
+		return fmt.Errorf("new password cannot be empty")
+	}
+	newPassword := []byte(newPasswordText)
+	defer wipe(newPassword)
+
+	encryptedData, err := os.ReadFile(globalConfig.EncryptedDBFile)
+	if err != nil {
+		return fmt.Errorf("failed to read encrypted database: %w", err)
+	}
+	header, sealed, err := parseEncHeader(encryptedData)
+	if err != nil {
+		return fmt.Errorf("failed to read encrypted database header: %w", err)
+	}
+	// files from before the key slots have no recovery key
+	if header == nil || header.version < encFormatVersion {
+		return ErrNoRecoveryKey
+	}
+
+	dataKey, err := header.dataKeyFromRecoveryKey(recoveryKey)
+	if err != nil {
+		return err
+	}
+	defer wipe(dataKey)
+	image, err := openSealedImage(header, dataKey, sealed)
+	if err != nil {
+		if errors.Is(err, ErrWrongPassword) {
+			return fmt.Errorf("the recovery key unlocked the data key but not the database, the file may be damaged")
+		}
+		return err
+	}
+
+	rekeyed, err := header.rekeyed(newPassword, nil, dataKey)
+	if err != nil {
+		return err
+	}
+	newData, err := sealDatabaseImage(rekeyed, dataKey, image)
+	if err != nil {
+		return err
+	}
+	if err := replaceEncryptedDatabase(newData, image, newPassword, nil); err != nil {
+		return err
+	}
+
+	replaceUserCredentials(newPassword, "", nil)
+	log.Printf("password reset with the recovery key")
+	return nil
+}
+
+// shows a new recovery key, there is no way to display it again so it can only be closed with the button
+func showRecoveryKeyModal(recoveryKey string, focus tview.Primitive) {
+	text := fmt.Sprintf("Your recovery key:\n\n%s\n\nWrite it down and keep it somewhere safe. It opens the database if you forget your password and it won't be shown again.", recoveryKey)
+
+	modal := styleModal(tview.NewModal().
+		SetText(text).
+		AddButtons([]string{"I have saved it"}).
+		SetDoneFunc(func(_ int, _ string) {
+			pages.RemovePage("recoveryKeyModal")
+			tui.SetFocus(focus)
+		}))
+
+	overlay := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(modal, 14, 1, true). // modal height
+		AddItem(nil, 0, 1, false)
+
+	centered := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(overlay, 70, 1, true). // modal width
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage("recoveryKeyModal", centered, true, true)
+	tui.SetFocus(modal)
+}
+
+// creates the TUI form for setting a new password with the recovery key, onRecovered continues the login with the new password
+func formRecoverPassword(focus tview.Primitive, onRecovered func()) {
+	recoveryKeyField := styleInputField(tview.NewInputField().
+		SetLabel("Recovery Key: "))
+	newPasswordField := styleInputField(tview.NewInputField().
+		SetLabel("New Password: ").
+		SetMaskCharacter('*'))
+	repeatPasswordField := styleInputField(tview.NewInputField().
+		SetLabel("Repeat New Password: ").
+		SetMaskCharacter('*'))
+
+	message := styleTextView(tview.NewTextView().
+		SetText("").
+		SetTextAlign(tview.AlignCenter))
+
+	closeForm := func() {
+		pages.RemovePage("recoverPassword")
+		tui.SetFocus(focus)
+	}
+
+	var form *tview.Form
+	form = styleForm(tview.NewForm().
+		AddFormItem(recoveryKeyField).
+		AddFormItem(newPasswordField).
+		AddFormItem(repeatPasswordField).
+		AddButton("Reset", func() {
+			if newPasswordField.GetText() != repeatPasswordField.GetText() {
+				message.SetText("New passwords do not match. Try again.")
+				newPasswordField.SetText("")
+				repeatPasswordField.SetText("")
+				return
+			}
+
+			// the password can't be reset underneath an instance that has the database open
+			if _, err := acquireInstanceLock(globalConfig); err != nil {
+				showErrorModal(fmt.Sprintf("failed to lock the data directory, close the other instance first:\n\n%s", err), form)
+				log.Printf("failed to lock the data directory: %s", err)
+				return
+			}
+
+			if err := recoverWithRecoveryKey(recoveryKeyField.GetText(), newPasswordField.GetText()); err != nil {
+				switch {
+				case errors.Is(err, ErrWrongRecoveryKey):
+					message.SetText("Wrong recovery key. Try again.")
+					recoveryKeyField.SetText("")
+				case errors.Is(err, ErrNoRecoveryKey):
+					message.SetText("This database has no recovery key.")
+				default:
+					showErrorModal(fmt.Sprintf("failed to reset password:\n\n%s", err), form)
+					log.Printf("failed to reset password: %s", err)
+				}
+				return
+			}
+
+			closeForm()
+			onRecovered()
+		}).
+		AddButton("Cancel", closeForm))
+
+	form.SetButtonsAlign(tview.AlignCenter)
+
+	formWithMessage := styleFlex(tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(form, 0, 1, true).
+		AddItem(message, 1, 0, false))
+
+	formWithMessage.SetBorder(true).
+		SetTitle("Reset Password").
+		SetTitleAlign(tview.AlignCenter)
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).             // left spacer
+		AddItem(formWithMessage, 70, 1, true). // wide enough for the recovery key
+		AddItem(nil, 0, 1, false))             // right spacer
+
+	// vertical centering
+	centeredModal := styleFlex(tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).   // top spacer
+		AddItem(modal, 12, 1, true). // enough to fit the three fields, the buttons and the message
+		AddItem(nil, 0, 1, false))   // bottom spacer
+
+	// back to the login prompt on ESC, q is typed into the fields as usual
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeForm()
+			return nil
+		}
+		return event
+	})
+
+	pages.AddPage("recoverPassword", centeredModal, true, true)
+	tui.SetFocus(form)
+}
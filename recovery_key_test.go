@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerateRecoveryKey(t *testing.T) {
+	key, err := generateRecoveryKey()
+	if err != nil {
+		t.Fatalf("Failed to generate recovery key: %v", err)
+	}
+	if groups := strings.Split(key, "-"); len(groups) != 8 || len(groups[0]) != 4 {
+		t.Errorf("Expected eight groups of four characters, got %q", key)
+	}
+
+	// typed in lowercase, without dashes or with spaces it is still the same key
+	expected, _ := recoveryKeyEncryptionKey(key)
+	for _, typed := range []string{strings.ToLower(key), strings.ReplaceAll(key, "-", ""), strings.ReplaceAll(key, "-", " ")} {
+		kek, err := recoveryKeyEncryptionKey(typed)
+		if err != nil || string(kek) != string(expected) {
+			t.Errorf("Expected %q to be accepted as the recovery key (err %v)", typed, err)
+		}
+	}
+
+	if _, err := recoveryKeyEncryptionKey("ABCD-EFGH"); !errors.Is(err, ErrWrongRecoveryKey) {
+		t.Errorf("Expected a short recovery key to be rejected, got %v", err)
+	}
+}
+
+func TestRecoverWithRecoveryKey(t *testing.T) {
+	config := setupChangePasswordSession(t)
+
+	recoveryKey, err := regenerateRecoveryKey()
+	if err != nil {
+		t.Fatalf("Expected no error generating recovery key, got %v", err)
+	}
+
+	// both the password and the recovery key open the database
+	data, _ := os.ReadFile(config.EncryptedDBFile)
+	if _, err := openDatabaseImage([]byte("testpassword"), nil, data); err != nil {
+		t.Errorf("Expected the password to decrypt the database, got %v", err)
+	}
+	header, _, _ := parseEncHeader(data)
+	if _, err := header.dataKeyFromRecoveryKey(recoveryKey); err != nil {
+		t.Errorf("Expected the recovery key to unlock the database, got %v", err)
+	}
+
+	// regular saves keep the recovery key
+	if err := checkpointDb(); err != nil {
+		t.Fatalf("Failed to encrypt db: %v", err)
+	}
+
+	// the password is forgotten, the database is closed like on exit
+	closeDb()
+	clearUserPassword()
+
+	other, _ := generateRecoveryKey()
+	if err := recoverWithRecoveryKey(other, "newpassword"); !errors.Is(err, ErrWrongRecoveryKey) {
+		t.Errorf("Expected ErrWrongRecoveryKey, got %v", err)
+	}
+	if err := recoverWithRecoveryKey(recoveryKey, "newpassword"); err != nil {
+		t.Fatalf("Expected no error recovering, got %v", err)
+	}
+	if string(userPassword) != "newpassword" {
+		t.Errorf("Expected the new password to be set")
+	}
+
+	data, _ = os.ReadFile(config.EncryptedDBFile)
+	if _, err := openDatabaseImage([]byte("testpassword"), nil, data); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected the old password to be rejected, got %v", err)
+	}
+	image, err := openDatabaseImage([]byte("newpassword"), nil, data)
+	if err != nil || countTransactionsInImage(t, image) != 1 {
+		t.Errorf("Expected the new password to decrypt the database (err %v)", err)
+	}
+
+	// the recovery key keeps working after it was used
+	header, _, _ = parseEncHeader(data)
+	if _, err := header.dataKeyFromRecoveryKey(recoveryKey); err != nil {
+		t.Errorf("Expected the recovery key to still unlock the database, got %v", err)
+	}
+}
+
+func TestRegenerateRecoveryKeyInvalidatesOldKey(t *testing.T) {
+	config := setupChangePasswordSession(t)
+
+	oldKey, err := regenerateRecoveryKey()
+	if err != nil {
+		t.Fatalf("Failed to generate recovery key: %v", err)
+	}
+	newKey, err := regenerateRecoveryKey()
+	if err != nil {
+		t.Fatalf("Failed to regenerate recovery key: %v", err)
+	}
+
+	header, err := readEncHeader(config.EncryptedDBFile)
+	if err != nil {
+		t.Fatalf("Failed to read header: %v", err)
+	}
+	if _, err := header.dataKeyFromRecoveryKey(oldKey); !errors.Is(err, ErrWrongRecoveryKey) {
+		t.Errorf("Expected the old recovery key to be rejected, got %v", err)
+	}
+	if _, err := header.dataKeyFromRecoveryKey(newKey); err != nil {
+		t.Errorf("Expected the new recovery key to unlock the database, got %v", err)
+	}
+}
+
+func TestChangePasswordKeepsRecoveryKey(t *testing.T) {
+	config := setupChangePasswordSession(t)
+
+	recoveryKey, err := regenerateRecoveryKey()
+	if err != nil {
+		t.Fatalf("Failed to generate recovery key: %v", err)
+	}
+	if err := changePassword("testpassword", "newpassword", ""); err != nil {
+		t.Fatalf("Failed to change password: %v", err)
+	}
+
+	header, _ := readEncHeader(config.EncryptedDBFile)
+	if _, err := header.dataKeyFromRecoveryKey(recoveryKey); err != nil {
+		t.Errorf("Expected the recovery key to survive a password change, got %v", err)
+	}
+}
+
+func TestRecoverWithoutRecoveryKey(t *testing.T) {
+	setupEncHeaderTestConfig(t)
+
+	// a database that was only ever saved with the password has no recovery slot
+	if err := encryptDatabaseImage([]byte("test database content")); err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	key, _ := generateRecoveryKey()
+	if err := recoverWithRecoveryKey(key, "newpassword"); !errors.Is(err, ErrNoRecoveryKey) {
+		t.Errorf("Expected ErrNoRecoveryKey, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// helper to create an encrypted database and a leftover unencrypted copy that has one more transaction
+func setupLeftoverDb(t *testing.T) *Config {
+	t.Helper()
+	setUserPassword("testpassword")
+
+	dir := t.TempDir()
+	config := &Config{
+		StorageType:       StorageSQLite,
+		UnencryptedDbFile: filepath.Join(dir, "transactions.db"),
+		EncryptedDBFile:   filepath.Join(dir, "transactions.enc"),
+		SaltFile:          filepath.Join(dir, "transactions.salt"),
+	}
+	original, originalDb := globalConfig, db
+	SetGlobalConfig(config)
+	t.Cleanup(func() {
+		clearUserPassword()
+		SetGlobalConfig(original)
+		db = originalDb
+	})
+
+	if err := initDb(config.UnencryptedDbFile); err != nil {
+		t.Fatalf("Failed to create db: %v", err)
+	}
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "food", Date: "2025-03-14"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	closeDb()
+	if err := encryptDatabase(config.UnencryptedDbFile); err != nil {
+		t.Fatalf("Failed to encrypt db: %v", err)
+	}
+
+	// the run that died made one more change after the last encryption
+	if err := initDb(config.UnencryptedDbFile); err != nil {
+		t.Fatalf("Failed to open db: %v", err)
+	}
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "12", Category: "food", Date: "2025-03-15"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	closeDb()
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(config.UnencryptedDbFile, later, later)
+
+	return config
+}
+
+func TestDetectLeftoverDb(t *testing.T) {
+	config := setupLeftoverDb(t)
+
+	leftover, err := detectLeftoverDb(config)
+	if err != nil || leftover == nil {
+		t.Fatalf("Expected a leftover database, got %+v (err %v)", leftover, err)
+	}
+	if !leftover.valid || !leftover.plaintextModTime.After(leftover.encryptedModTime) {
+		t.Errorf("Expected a valid newer leftover database, got %+v", leftover)
+	}
+
+	setUserPassword("wrongpassword")
+	if _, err := detectLeftoverDb(config); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("Expected ErrWrongPassword, got %v", err)
+	}
+	setUserPassword("testpassword")
+
+	// a leftover identical to the encrypted database is simply removed
+	if err := encryptDatabase(config.UnencryptedDbFile); err != nil {
+		t.Fatalf("Failed to encrypt db: %v", err)
+	}
+	if leftover, err := detectLeftoverDb(config); err != nil || leftover != nil {
+		t.Errorf("Expected no leftover for an identical copy, got %+v (err %v)", leftover, err)
+	}
+	if _, err := os.Stat(config.UnencryptedDbFile); !os.IsNotExist(err) {
+		t.Errorf("Expected identical leftover to be removed")
+	}
+
+	// nothing left behind
+	if leftover, err := detectLeftoverDb(config); err != nil || leftover != nil {
+		t.Errorf("Expected no leftover, got %+v (err %v)", leftover, err)
+	}
+}
+
+func TestDetectLeftoverDbWithJournal(t *testing.T) {
+	config := setupLeftoverDb(t)
+
+	// identical contents, but the run died while writing to it
+	if err := encryptDatabase(config.UnencryptedDbFile); err != nil {
+		t.Fatalf("Failed to encrypt db: %v", err)
+	}
+	if err := os.WriteFile(leftoverJournalPath(config), nil, 0600); err != nil {
+		t.Fatalf("Failed to create journal: %v", err)
+	}
+
+	leftover, err := detectLeftoverDb(config)
+	if err != nil || leftover == nil {
+		t.Fatalf("Expected a journal to need recovery, got %+v (err %v)", leftover, err)
+	}
+	if !leftover.journal || !leftover.valid {
+		t.Errorf("Expected a valid leftover with a journal, got %+v", leftover)
+	}
+	if _, err := os.Stat(config.UnencryptedDbFile); err != nil {
+		t.Errorf("Expected the leftover to stay until it is resolved")
+	}
+
+	if err := resolveLeftoverDb(config, leftover, RecoverLeftoverDb); err != nil {
+		t.Fatalf("Expected no error recovering, got %v", err)
+	}
+	for _, path := range []string{config.UnencryptedDbFile, leftoverJournalPath(config)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed after recovering", path)
+		}
+	}
+
+	// a journal without its database would be rolled back into the next decrypted copy
+	if err := os.WriteFile(leftoverJournalPath(config), []byte("stale"), 0600); err != nil {
+		t.Fatalf("Failed to create journal: %v", err)
+	}
+	if leftover, err := detectLeftoverDb(config); err != nil || leftover != nil {
+		t.Errorf("Expected no leftover for a journal alone, got %+v (err %v)", leftover, err)
+	}
+	if _, err := os.Stat(leftoverJournalPath(config)); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale journal to be removed")
+	}
+}
+
+func TestResolveLeftoverDb(t *testing.T) {
+	cases := []struct {
+		choice          string
+		expectRecovered bool
+		expectConflict  bool
+	}{
+		{RecoverLeftoverDb, true, false},
+		{DiscardLeftoverDb, false, false},
+		{KeepBothLeftoverDb, false, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.choice, func(t *testing.T) {
+			config := setupLeftoverDb(t)
+			plaintext, _ := os.ReadFile(config.UnencryptedDbFile)
+			encryptedBefore, _ := decryptDatabaseImage()
+
+			leftover, err := detectLeftoverDb(config)
+			if err != nil || leftover == nil {
+				t.Fatalf("Expected a leftover database, got %v", err)
+			}
+			if err := resolveLeftoverDb(config, leftover, c.choice); err != nil {
+				t.Fatalf("Expected no error resolving, got %v", err)
+			}
+
+			if _, err := os.Stat(config.UnencryptedDbFile); !os.IsNotExist(err) {
+				t.Errorf("Expected the unencrypted database to be removed")
+			}
+
+			encryptedAfter, _ := decryptDatabaseImage()
+			if recovered := bytes.Equal(encryptedAfter, plaintext); recovered != c.expectRecovered {
+				t.Errorf("Expected recovered %v, got %v", c.expectRecovered, recovered)
+			}
+			if !c.expectRecovered && !bytes.Equal(encryptedAfter, encryptedBefore) {
+				t.Errorf("Expected the encrypted database to stay untouched")
+			}
+
+			conflicts, _ := filepath.Glob(config.EncryptedDBFile + ".conflict-*")
+			if (len(conflicts) == 1) != c.expectConflict {
+				t.Fatalf("Expected conflict copy %v, got %v", c.expectConflict, conflicts)
+			}
+			if c.expectConflict {
+				// the conflict copy opens with the same password once it is renamed to the encrypted database file
+				config.EncryptedDBFile = conflicts[0]
+				conflict, err := decryptDatabaseImage()
+				if err != nil || !bytes.Equal(conflict, plaintext) {
+					t.Errorf("Expected the conflict copy to hold the leftover database (err %v)", err)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveIncompleteLeftoverDb(t *testing.T) {
+	config := setupLeftoverDb(t)
+	if err := os.WriteFile(config.UnencryptedDbFile, []byte("SQLite for"), 0600); err != nil {
+		t.Fatalf("Failed to truncate db: %v", err)
+	}
+
+	leftover, err := detectLeftoverDb(config)
+	if err != nil || leftover == nil || leftover.valid {
+		t.Fatalf("Expected an incomplete leftover database, got %+v (err %v)", leftover, err)
+	}
+	if err := resolveLeftoverDb(config, leftover, RecoverLeftoverDb); err == nil {
+		t.Errorf("Expected error recovering an incomplete database")
+	}
+	if _, err := os.Stat(config.UnencryptedDbFile); err != nil {
+		t.Errorf("Expected the unencrypted database to stay after a failed recovery")
+	}
+}
+
+func TestUnlockHeadlessWithLeftoverDb(t *testing.T) {
+	config := setupLeftoverDb(t)
+	before, _ := os.ReadFile(config.UnencryptedDbFile)
+
+	err := unlockHeadless("testpassword", "")
+	if err == nil || !strings.Contains(err.Error(), "start the TUI") {
+		t.Fatalf("Expected headless unlock to refuse a leftover database, got %v", err)
+	}
+
+	after, _ := os.ReadFile(config.UnencryptedDbFile)
+	if !bytes.Equal(before, after) {
+		t.Errorf("Expected the leftover database to stay untouched")
+	}
+}
@@ -0,0 +1,906 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// a transaction that repeats on a schedule, like rent, a salary or an ETF savings plan
+type RecurringRule struct {
+	Id          int64
+	Type        string
+	Amount      float64
+	Category    string
+	Description string
+	Tags        []string
+	Frequency   string    // monthly, quarterly or yearly
+	Start       time.Time // date of the first occurrence, the day of the month is kept for the following ones
+	End         time.Time // no occurrences after it, zero when the rule runs until it is deleted
+	AutoPost    bool      // due occurrences are added right away instead of waiting for confirmation
+	Paused      bool
+	LastDate    time.Time // the latest occurrence that was posted or queued, zero before the first one
+}
+
+// the fields of the recurring rule form, Id is 0 for a new rule
+type RecurringRuleRequest struct {
+	Id          int64
+	Type        string
+	Amount      string
+	Category    string
+	Description string
+	Tags        string // comma separated, e.g. "rent, home"
+	Frequency   string
+	Start       string // YYYY-MM-DD
+	End         string // YYYY-MM-DD, optional
+	AutoPost    bool
+}
+
+// an occurrence of a rule that waits for confirmation before it is added as a transaction
+type QueuedOccurrence struct {
+	Rule RecurringRule
+	Date time.Time
+}
+
+// number of months between two occurrences of each frequency
+var recurringFrequencies = map[string]int{
+	"monthly":   1,
+	"quarterly": 3,
+	"yearly":    12,
+}
+
+// frequencies in the order they are offered in the form
+var recurringFrequencyOrder = []string{"monthly", "quarterly", "yearly"}
+
+var ErrRecurringRuleNotFound = errors.New("recurring rule not found")
+
+// helper to get the n-th occurrence of a rule, a day that doesn't exist in a month is moved to the last day of it, e.g. 31st to 28th of February
+func occurrenceDate(start time.Time, frequency string, n int) time.Time {
+	first := time.Date(start.Year(), start.Month()+time.Month(n*recurringFrequencies[frequency]), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(start.Day(), lastDay)-1)
+}
+
+// lists the occurrences of a rule after the last handled one, up to and including today
+func dueOccurrences(rule RecurringRule, today time.Time) []time.Time {
+	var due []time.Time
+	for n := 0; ; n++ {
+		date := occurrenceDate(rule.Start, rule.Frequency, n)
+		if date.After(today) || (!rule.End.IsZero() && date.After(rule.End)) {
+			return due
+		}
+		if rule.LastDate.IsZero() || date.After(rule.LastDate) {
+			due = append(due, date)
+		}
+	}
+}
+
+// the next occurrence that hasn't been handled yet, zero once the rule has ended
+func nextOccurrence(rule RecurringRule) time.Time {
+	for n := 0; ; n++ {
+		date := occurrenceDate(rule.Start, rule.Frequency, n)
+		if !rule.End.IsZero() && date.After(rule.End) {
+			return time.Time{}
+		}
+		if rule.LastDate.IsZero() || date.After(rule.LastDate) {
+			return date
+		}
+	}
+}
+
+// helper to get the current date without the time, the same way transaction dates are stored
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// helper to turn an occurrence of a rule into the request that adds it as a transaction
+func (r RecurringRule) transactionRequest(date time.Time) AddTransactionRequest {
+	month, year := periodOfDate(date)
+	return AddTransactionRequest{
+		Type:        r.Type,
+		Amount:      strconv.FormatFloat(r.Amount, 'f', 2, 64),
+		Category:    r.Category,
+		Description: r.Description,
+		Month:       month,
+		Year:        year,
+		Date:        date.Format(TransactionDateFormat),
+		Tags:        formatTags(r.Tags),
+	}
+}
+
+// helper to read the optional dates of a rule, they are stored as YYYY-MM-DD or NULL
+func parseOptionalDate(value sql.NullString) (time.Time, error) {
+	if !value.Valid || value.String == "" {
+		return time.Time{}, nil
+	}
+	return parseTransactionDate(value.String)
+}
+
+// helper to store an optional date of a rule, a zero date is stored as NULL
+func formatOptionalDate(date time.Time) any {
+	if date.IsZero() {
+		return nil
+	}
+	return date.Format(TransactionDateFormat)
+}
+
+// lists every recurring rule sorted by type, category and description, rules are only stored in the sqlite database
+func listRecurringRules() ([]RecurringRule, error) {
+	if !categoriesInDb() {
+		return nil, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT id, type, amount, category, description, tags, frequency, start_date, end_date, auto_post, paused, last_date
+		FROM recurring_rules
+		ORDER BY type, category COLLATE NOCASE, description COLLATE NOCASE, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recurring rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []RecurringRule
+	for rows.Next() {
+		var r RecurringRule
+		var tags, start string
+		var end, last sql.NullString
+		if err := rows.Scan(&r.Id, &r.Type, &r.Amount, &r.Category, &r.Description, &tags, &r.Frequency, &start, &end, &r.AutoPost, &r.Paused, &last); err != nil {
+			return nil, fmt.Errorf("failed to read recurring rule: %w", err)
+		}
+		if r.Tags, err = parseTags(tags); err != nil {
+			return nil, fmt.Errorf("invalid tags of recurring rule %d: %w", r.Id, err)
+		}
+		if r.Start, err = parseTransactionDate(start); err != nil {
+			return nil, fmt.Errorf("invalid start of recurring rule %d: %w", r.Id, err)
+		}
+		if r.End, err = parseOptionalDate(end); err != nil {
+			return nil, fmt.Errorf("invalid end of recurring rule %d: %w", r.Id, err)
+		}
+		if r.LastDate, err = parseOptionalDate(last); err != nil {
+			return nil, fmt.Errorf("invalid last occurrence of recurring rule %d: %w", r.Id, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// helper to find a single rule by its id
+func getRecurringRule(id int64) (RecurringRule, error) {
+	rules, err := listRecurringRules()
+	if err != nil {
+		return RecurringRule{}, err
+	}
+	for _, r := range rules {
+		if r.Id == id {
+			return r, nil
+		}
+	}
+	return RecurringRule{}, fmt.Errorf("%w: %d", ErrRecurringRuleNotFound, id)
+}
+
+// adds a new recurring rule or changes an existing one and returns its id, occurrences that were already handled are not posted again
+func saveRecurringRule(req RecurringRuleRequest) (int64, error) {
+	txType, err := normalizeTransactionType(req.Type)
+	if err != nil {
+		return 0, fmt.Errorf("transaction type error: %w", err)
+	}
+	amount, err := strconv.ParseFloat(strings.TrimSpace(req.Amount), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount: %w", err)
+	}
+	if amount <= 0 {
+		return 0, fmt.Errorf("amount has to be more than 0")
+	}
+	if !isAllowedCategory(txType, req.Category) {
+		return 0, fmt.Errorf("invalid category: %s", req.Category)
+	}
+	if len(req.Description) > DescriptionMaxCharLength {
+		return 0, fmt.Errorf("description can have at most %d characters", DescriptionMaxCharLength)
+	}
+	tags, err := parseTags(req.Tags)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tags: %w", err)
+	}
+	if _, ok := recurringFrequencies[req.Frequency]; !ok {
+		return 0, fmt.Errorf("invalid frequency %q, expected one of %s", req.Frequency, strings.Join(recurringFrequencyOrder, ", "))
+	}
+	start, err := parseTransactionDate(req.Start)
+	if err != nil {
+		return 0, fmt.Errorf("invalid start: %w", err)
+	}
+	var end time.Time
+	if strings.TrimSpace(req.End) != "" {
+		if end, err = parseTransactionDate(req.End); err != nil {
+			return 0, fmt.Errorf("invalid end: %w", err)
+		}
+		if end.Before(start) {
+			return 0, fmt.Errorf("end can't be before the start")
+		}
+	}
+
+	id := req.Id
+	err = runDbChange(func(sqlTx *sql.Tx) error {
+		if id == 0 {
+			result, err := sqlTx.Exec(`
+				INSERT INTO recurring_rules (type, amount, category, description, tags, frequency, start_date, end_date, auto_post)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, txType, amount, req.Category, req.Description, strings.Join(tags, TagSeparator), req.Frequency, start.Format(TransactionDateFormat), formatOptionalDate(end), req.AutoPost)
+			if err != nil {
+				return fmt.Errorf("failed to add recurring rule: %w", err)
+			}
+			id, err = result.LastInsertId()
+			return err
+		}
+
+		result, err := sqlTx.Exec(`
+			UPDATE recurring_rules
+			SET type = ?, amount = ?, category = ?, description = ?, tags = ?, frequency = ?, start_date = ?, end_date = ?, auto_post = ?
+			WHERE id = ?
+		`, txType, amount, req.Category, req.Description, strings.Join(tags, TagSeparator), req.Frequency, start.Format(TransactionDateFormat), formatOptionalDate(end), req.AutoPost, id)
+		if err != nil {
+			return fmt.Errorf("failed to update recurring rule %d: %w", id, err)
+		}
+		if changed, _ := result.RowsAffected(); changed == 0 {
+			return fmt.Errorf("%w: %d", ErrRecurringRuleNotFound, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("saved %s recurring rule %d for %s", req.Frequency, id, req.Category)
+	return id, nil
+}
+
+// pauses a rule or lets it run again, the occurrences that were due while it was paused are skipped
+func setRecurringRulePaused(id int64, paused bool) error {
+	return runDbChange(func(sqlTx *sql.Tx) error {
+		query := `UPDATE recurring_rules SET paused = 1 WHERE id = ?`
+		args := []any{id}
+		if !paused {
+			// occurrences from today on are posted again
+			query = `UPDATE recurring_rules SET paused = 0, last_date = MAX(COALESCE(last_date, ''), ?) WHERE id = ?`
+			args = []any{today().AddDate(0, 0, -1).Format(TransactionDateFormat), id}
+		}
+
+		result, err := sqlTx.Exec(query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to change recurring rule %d: %w", id, err)
+		}
+		if changed, _ := result.RowsAffected(); changed == 0 {
+			return fmt.Errorf("%w: %d", ErrRecurringRuleNotFound, id)
+		}
+		return nil
+	})
+}
+
+// removes a rule together with its occurrences that wait for confirmation, transactions it already added are kept
+func deleteRecurringRule(id int64) error {
+	return runDbChange(func(sqlTx *sql.Tx) error {
+		if _, err := sqlTx.Exec(`DELETE FROM recurring_queue WHERE rule_id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete queued occurrences of recurring rule %d: %w", id, err)
+		}
+		result, err := sqlTx.Exec(`DELETE FROM recurring_rules WHERE id = ?`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete recurring rule %d: %w", id, err)
+		}
+		if removed, _ := result.RowsAffected(); removed == 0 {
+			return fmt.Errorf("%w: %d", ErrRecurringRuleNotFound, id)
+		}
+		return nil
+	})
+}
+
+// helper to keep the recurring rules of a category when the category is renamed
+func renameRecurringRulesCategory(sqlTx *sql.Tx, txType, oldName, newName string) error {
+	if _, err := sqlTx.Exec(`UPDATE recurring_rules SET category = ? WHERE type = ? AND category = ?`, newName, txType, oldName); err != nil {
+		return fmt.Errorf("failed to rename category of recurring rules from %s: %w", oldName, err)
+	}
+	return nil
+}
+
+// posts the occurrences of all active rules that are due since the last run, rules without auto posting queue them for confirmation instead
+// an occurrence that can't be posted, e.g. because its category was archived, is queued so it isn't lost
+// a failed write stops the run and leaves the rule at its last handled occurrence, so the next login picks it up again
+func postDueRecurringTransactions(today time.Time) (posted, queued int, err error) {
+	if !categoriesInDb() || readOnlySession {
+		return 0, 0, nil
+	}
+
+	rules, err := listRecurringRules()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, rule := range rules {
+		if rule.Paused {
+			continue
+		}
+		for _, date := range dueOccurrences(rule, today) {
+			queue := !rule.AutoPost
+			var txType string
+			var tx Transaction
+			if rule.AutoPost {
+				var err error
+				if txType, tx, err = newTransactionFromRequest(rule.transactionRequest(date)); err != nil {
+					log.Printf("failed to post recurring rule %d on %s, queued for confirmation instead: %s", rule.Id, date.Format(TransactionDateFormat), err)
+					queue = true
+				}
+			}
+
+			// the transaction is written in the same db transaction that moves the last date along, so an occurrence is never posted twice
+			err := runDbChange(func(sqlTx *sql.Tx) error {
+				if queue {
+					if _, err := sqlTx.Exec(`INSERT OR IGNORE INTO recurring_queue (rule_id, date) VALUES (?, ?)`, rule.Id, date.Format(TransactionDateFormat)); err != nil {
+						return fmt.Errorf("failed to queue recurring rule %d: %w", rule.Id, err)
+					}
+				} else if err := insertTransactionInTx(sqlTx, txType, tx); err != nil {
+					return fmt.Errorf("failed to post recurring rule %d: %w", rule.Id, err)
+				}
+				if _, err := sqlTx.Exec(`UPDATE recurring_rules SET last_date = ? WHERE id = ?`, date.Format(TransactionDateFormat), rule.Id); err != nil {
+					return fmt.Errorf("failed to update recurring rule %d: %w", rule.Id, err)
+				}
+				return nil
+			})
+			if err != nil {
+				return posted, queued, err
+			}
+
+			if queue {
+				queued++
+			} else {
+				posted++
+			}
+		}
+	}
+
+	if posted > 0 || queued > 0 {
+		log.Printf("recurring rules: posted %d transactions, queued %d for confirmation", posted, queued)
+	}
+	return posted, queued, nil
+}
+
+// lists the occurrences that wait for confirmation, oldest first
+func listQueuedOccurrences() ([]QueuedOccurrence, error) {
+	if !categoriesInDb() {
+		return nil, nil
+	}
+
+	rules, err := listRecurringRules()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT rule_id, date FROM recurring_queue ORDER BY date, rule_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load queued occurrences: %w", err)
+	}
+	defer rows.Close()
+
+	var queued []QueuedOccurrence
+	for rows.Next() {
+		var ruleId int64
+		var date string
+		if err := rows.Scan(&ruleId, &date); err != nil {
+			return nil, fmt.Errorf("failed to read queued occurrence: %w", err)
+		}
+		i := slices.IndexFunc(rules, func(r RecurringRule) bool { return r.Id == ruleId })
+		if i < 0 {
+			continue // the rule is gone, its queue is removed along with it
+		}
+		q := QueuedOccurrence{Rule: rules[i]}
+		if q.Date, err = parseTransactionDate(date); err != nil {
+			return nil, fmt.Errorf("invalid date of queued occurrence of recurring rule %d: %w", ruleId, err)
+		}
+		queued = append(queued, q)
+	}
+	return queued, rows.Err()
+}
+
+// adds a queued occurrence as a transaction, or drops it without adding anything when it is skipped
+// the transaction is added in the same db transaction that removes it from the queue, so it can't be posted twice
+func resolveQueuedOccurrence(q QueuedOccurrence, post bool) error {
+	var txType string
+	var tx Transaction
+	if post {
+		var err error
+		if txType, tx, err = newTransactionFromRequest(q.Rule.transactionRequest(q.Date)); err != nil {
+			return fmt.Errorf("failed to post recurring transaction: %w", err)
+		}
+	}
+	return runDbChange(func(sqlTx *sql.Tx) error {
+		if post {
+			if err := insertTransactionInTx(sqlTx, txType, tx); err != nil {
+				return fmt.Errorf("failed to post recurring transaction: %w", err)
+			}
+		}
+		if _, err := sqlTx.Exec(`DELETE FROM recurring_queue WHERE rule_id = ? AND date = ?`, q.Rule.Id, q.Date.Format(TransactionDateFormat)); err != nil {
+			return fmt.Errorf("failed to remove queued occurrence of recurring rule %d: %w", q.Rule.Id, err)
+		}
+		return nil
+	})
+}
+
+// runs the recurring rules after logging in, the transactions are posted before the grid is drawn so they show up right away
+// returns the number of occurrences that wait for confirmation, a failure is only logged so it never blocks a login
+func runRecurringRulesAtLogin() int {
+	_, _, err := postDueRecurringTransactions(today())
+	if err != nil {
+		log.Printf("failed to run recurring rules: %s", err)
+	}
+
+	waiting, err := listQueuedOccurrences()
+	if err != nil {
+		log.Printf("failed to list queued recurring transactions: %s", err)
+		return 0
+	}
+	return len(waiting)
+}
+
+// helper to describe when a rule posts its transactions
+func describeRecurringSchedule(r RecurringRule) string {
+	schedule := fmt.Sprintf("%s from %s", r.Frequency, r.Start.Format(TransactionDateFormat))
+	if !r.End.IsZero() {
+		schedule += " until " + r.End.Format(TransactionDateFormat)
+	}
+	return schedule
+}
+
+func generateRecurringRulesFooter() string {
+	return Green + "a" + Reset + ": add  " +
+		Yellow + "e" + Reset + ": edit  " +
+		Yellow + "p" + Reset + ": pause/resume  " +
+		Red + "d" + Reset + ": delete  " +
+		Green + "c" + Reset + ": confirm queued  " +
+		Yellow + "ESC" + Reset + "/" + Yellow + "q" + Reset + ": back"
+}
+
+// creates a TUI window to list, add, edit, pause and delete the recurring rules
+func showRecurringRules(selectedMonth, selectedYear, focusTableType string) error {
+	if err := requireWritableSQLiteDb(); err != nil {
+		return err
+	}
+
+	table := styleTable(tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0))
+	table.SetTitleAlign(tview.AlignCenter).
+		SetBorder(true)
+
+	// fills the table again after a change and keeps the selection on the changed rule where possible
+	refresh := func(selectId int64) error {
+		rules, err := listRecurringRules()
+		if err != nil {
+			return err
+		}
+		queued, err := listQueuedOccurrences()
+		if err != nil {
+			return err
+		}
+
+		title := "Recurring Transactions"
+		if len(queued) > 0 {
+			title = fmt.Sprintf("Recurring Transactions - %d waiting for confirmation", len(queued))
+		}
+		table.SetTitle(title)
+
+		table.Clear()
+		for c, h := range []string{"Type", "Amount", "Category", "Description", "Schedule", "Next", "Posting", "Status"} {
+			table.SetCell(0, c, tview.NewTableCell(h).SetSelectable(false))
+		}
+		if len(rules) == 0 {
+			table.SetCell(1, 0, tview.NewTableCell("no recurring transactions yet"))
+			return nil
+		}
+
+		for i, r := range rules {
+			row := i + 1
+			next := "ended"
+			if date := nextOccurrence(r); !date.IsZero() {
+				next = date.Format(TransactionDateFormat)
+			}
+			posting := "confirm"
+			if r.AutoPost {
+				posting = "auto"
+			}
+			status := Green + "active" + Reset
+			if r.Paused {
+				status = Yellow + "paused" + Reset
+			}
+			table.SetCell(row, 0, tview.NewTableCell(r.Type).SetReference(r))
+			table.SetCell(row, 1, tview.NewTableCell(fmt.Sprintf("€%.2f", r.Amount)).SetAlign(tview.AlignRight))
+			table.SetCell(row, 2, tview.NewTableCell(r.Category))
+			table.SetCell(row, 3, tview.NewTableCell(r.Description).SetMaxWidth(30))
+			table.SetCell(row, 4, tview.NewTableCell(describeRecurringSchedule(r)))
+			table.SetCell(row, 5, tview.NewTableCell(next))
+			table.SetCell(row, 6, tview.NewTableCell(posting))
+			table.SetCell(row, 7, tview.NewTableCell(status))
+			if r.Id == selectId {
+				table.Select(row, 0)
+			}
+		}
+		if r, _ := table.GetSelection(); r < 1 {
+			table.Select(1, 0)
+		}
+		return nil
+	}
+	if err := refresh(0); err != nil {
+		return err
+	}
+
+	// helper to get the rule on the selected row
+	selected := func() (RecurringRule, bool) {
+		r, _ := table.GetSelection()
+		rule, ok := table.GetCell(r, 0).GetReference().(RecurringRule)
+		return rule, ok
+	}
+
+	backToTransactions := func() {
+		pages.RemovePage("recurringRules")
+		gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType, true)
+	}
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if ev := exitShortcuts(event); ev == nil {
+			backToTransactions()
+			return nil
+		}
+		if event.Key() != tcell.KeyRune {
+			return vimMotions(event)
+		}
+
+		switch event.Rune() {
+		case 'a':
+			formRecurringRule(nil, focusTableType, table, refresh)
+			return nil
+		case 'e':
+			if r, ok := selected(); ok {
+				formRecurringRule(&r, focusTableType, table, refresh)
+			}
+			return nil
+		case 'p':
+			if r, ok := selected(); ok {
+				if err := setRecurringRulePaused(r.Id, !r.Paused); err != nil {
+					showErrorModal(fmt.Sprintf("failed to pause recurring transaction:\n\n%s", err), table)
+					log.Printf("failed to pause recurring rule: %s", err)
+					return nil
+				}
+				if err := refresh(r.Id); err != nil {
+					showErrorModal(fmt.Sprintf("failed to list recurring transactions:\n\n%s", err), table)
+				}
+			}
+			return nil
+		case 'd':
+			if r, ok := selected(); ok {
+				if err := deleteRecurringRule(r.Id); err != nil {
+					showErrorModal(fmt.Sprintf("failed to delete recurring transaction:\n\n%s", err), table)
+					log.Printf("failed to delete recurring rule: %s", err)
+					return nil
+				}
+				if err := refresh(0); err != nil {
+					showErrorModal(fmt.Sprintf("failed to list recurring transactions:\n\n%s", err), table)
+				}
+			}
+			return nil
+		case 'c':
+			showQueuedOccurrences(table, func() {
+				if err := refresh(0); err != nil {
+					showErrorModal(fmt.Sprintf("failed to list recurring transactions:\n\n%s", err), table)
+				}
+			})
+			return nil
+		}
+		return vimMotions(event)
+	})
+
+	// navigation help
+	frame := tview.NewFrame(table).
+		AddText(generateRecurringRulesFooter(), false, tview.AlignCenter, theme.FieldTextColor)
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).    // left spacer
+		AddItem(frame, 140, 1, true). // wide enough for the schedule
+		AddItem(nil, 0, 1, false))    // right spacer
+
+	pages.AddPage("recurringRules", modal, true, true)
+	tui.SetFocus(table)
+	return nil
+}
+
+// creates a TUI form to add a recurring rule or to change an existing one
+func formRecurringRule(existing *RecurringRule, focusTableType string, focus tview.Primitive, refresh func(selectId int64) error) {
+	req := RecurringRuleRequest{
+		Type:      focusTableType,
+		Frequency: recurringFrequencyOrder[0],
+		Start:     today().Format(TransactionDateFormat),
+		AutoPost:  true,
+	}
+	title := "Add Recurring Transaction"
+	var tags []string
+	if existing != nil {
+		tags = existing.Tags
+		req = RecurringRuleRequest{
+			Id:          existing.Id,
+			Type:        existing.Type,
+			Amount:      strconv.FormatFloat(existing.Amount, 'f', 2, 64),
+			Category:    existing.Category,
+			Description: existing.Description,
+			Tags:        formatTags(existing.Tags),
+			Frequency:   existing.Frequency,
+			Start:       existing.Start.Format(TransactionDateFormat),
+			AutoPost:    existing.AutoPost,
+		}
+		if !existing.End.IsZero() {
+			req.End = existing.End.Format(TransactionDateFormat)
+		}
+		title = "Edit Recurring Transaction"
+	}
+
+	var form *tview.Form
+	closeForm := func() {
+		pages.RemovePage("recurringRuleForm")
+		tui.SetFocus(focus)
+	}
+
+	allowedTransactionTypes, err := listOfAllowedTransactionTypes()
+	if err != nil {
+		showErrorModal(fmt.Sprintf("failed to list transaction types:\n\n%s", err), focus)
+		return
+	}
+	if !slices.Contains(allowedTransactionTypes, req.Type) {
+		req.Type = allowedTransactionTypes[0]
+	}
+
+	categoryDropdown := styleDropdown(tview.NewDropDown().
+		SetLabel("Category"))
+	categoryDropdown.SetInputCapture(vimMotions)
+
+	// the categories depend on the type, the current category is kept selected when it is still allowed
+	setCategoryOptions := func(txType string) {
+		opts, err := listOfAllowedCategories(txType)
+		if err != nil {
+			showErrorModal(fmt.Sprintf("failed to list categories:\n\n%s", err), focus)
+			log.Printf("failed to list categories of %s: %s", txType, err)
+			return
+		}
+		categoryDropdown.SetOptions(opts, func(selectedOption string, _ int) {
+			req.Category = selectedOption
+		})
+		if i := slices.Index(opts, req.Category); i >= 0 {
+			categoryDropdown.SetCurrentOption(i)
+		} else if len(opts) > 0 {
+			categoryDropdown.SetCurrentOption(0)
+		}
+	}
+
+	typeDropdown := styleDropdown(tview.NewDropDown().
+		SetLabel("Transaction Type"))
+	typeDropdown.SetOptions(allowedTransactionTypes, func(selectedOption string, _ int) {
+		req.Type = selectedOption
+		setCategoryOptions(selectedOption)
+	})
+	typeDropdown.SetCurrentOption(slices.Index(allowedTransactionTypes, req.Type))
+	typeDropdown.SetInputCapture(vimMotions)
+
+	amountField := styleInputField(tview.NewInputField().
+		SetLabel("Amount").
+		SetText(req.Amount))
+	descriptionField := styleInputField(tview.NewInputField().
+		SetLabel("Description").
+		SetText(req.Description).
+		SetAcceptanceFunc(enforceCharLimit))
+	tagsField := newTagsInputField(tags)
+
+	frequencyDropdown := styleDropdown(tview.NewDropDown().
+		SetLabel("Repeats").
+		SetOptions(recurringFrequencyOrder, func(selectedOption string, _ int) {
+			req.Frequency = selectedOption
+		}))
+	frequencyDropdown.SetCurrentOption(max(0, slices.Index(recurringFrequencyOrder, req.Frequency)))
+	frequencyDropdown.SetInputCapture(vimMotions)
+
+	startField := styleInputField(tview.NewInputField().
+		SetLabel("First date (YYYY-MM-DD)").
+		SetText(req.Start))
+	endField := styleInputField(tview.NewInputField().
+		SetLabel("Last date (optional)").
+		SetText(req.End))
+	autoPostCheckbox := tview.NewCheckbox().
+		SetLabel("Post without confirmation").
+		SetChecked(req.AutoPost)
+
+	form = styleForm(tview.NewForm().
+		AddFormItem(typeDropdown).
+		AddFormItem(amountField).
+		AddFormItem(categoryDropdown).
+		AddFormItem(descriptionField).
+		AddFormItem(tagsField).
+		AddFormItem(frequencyDropdown).
+		AddFormItem(startField).
+		AddFormItem(endField).
+		AddFormItem(autoPostCheckbox).
+		AddButton("Save", func() {
+			req.Amount = amountField.GetText()
+			req.Description = descriptionField.GetText()
+			req.Tags = tagsField.GetText()
+			req.Start = startField.GetText()
+			req.End = endField.GetText()
+			req.AutoPost = autoPostCheckbox.IsChecked()
+
+			id, err := saveRecurringRule(req)
+			if err != nil {
+				showErrorModal(fmt.Sprintf("failed to save recurring transaction:\n\n%s", err), form)
+				log.Printf("failed to save recurring rule: %s", err)
+				return
+			}
+
+			closeForm()
+			if err := refresh(id); err != nil {
+				showErrorModal(fmt.Sprintf("failed to list recurring transactions:\n\n%s", err), focus)
+			}
+		}).
+		AddButton("Cancel", closeForm))
+
+	form.SetButtonsAlign(tview.AlignCenter)
+	form.SetBorder(true).SetTitle(title).SetTitleAlign(tview.AlignCenter)
+
+	form.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closeForm()
+			return nil
+		}
+		return event
+	})
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).  // left spacer
+		AddItem(form, 70, 1, true). // wide enough for the date labels
+		AddItem(nil, 0, 1, false))  // right spacer
+
+	// vertical centering
+	centeredModal := styleFlex(tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).   // top spacer
+		AddItem(modal, 23, 1, true). // enough to fit the fields and the buttons
+		AddItem(nil, 0, 1, false))   // bottom spacer
+
+	pages.AddPage("recurringRuleForm", centeredModal, true, true)
+	tui.SetFocus(form)
+}
+
+func generateQueuedOccurrencesFooter() string {
+	return Green + "ENTER" + Reset + ": post  " +
+		Green + "a" + Reset + ": post all  " +
+		Red + "d" + Reset + ": skip  " +
+		Yellow + "ESC" + Reset + "/" + Yellow + "q" + Reset + ": back"
+}
+
+// creates a TUI window with the recurring transactions that wait for confirmation, each one is either posted or skipped
+func showQueuedOccurrences(focus tview.Primitive, back func()) {
+	table := styleTable(tview.NewTable().
+		SetSelectable(true, false).
+		SetFixed(1, 0))
+	table.SetTitle("Recurring Transactions Waiting for Confirmation").
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true)
+
+	closeQueue := func() {
+		pages.RemovePage("recurringQueue")
+		tui.SetFocus(focus)
+		back()
+	}
+
+	// fills the table again after an occurrence was posted or skipped
+	refresh := func() error {
+		queued, err := listQueuedOccurrences()
+		if err != nil {
+			return err
+		}
+
+		table.Clear()
+		for c, h := range []string{"Date", "Type", "Amount", "Category", "Description", "Tags"} {
+			table.SetCell(0, c, tview.NewTableCell(h).SetSelectable(false))
+		}
+		if len(queued) == 0 {
+			table.SetCell(1, 0, tview.NewTableCell("nothing waiting for confirmation"))
+			return nil
+		}
+
+		for i, q := range queued {
+			row := i + 1
+			table.SetCell(row, 0, tview.NewTableCell(q.Date.Format(TransactionDateFormat)).SetReference(q))
+			table.SetCell(row, 1, tview.NewTableCell(q.Rule.Type))
+			table.SetCell(row, 2, tview.NewTableCell(fmt.Sprintf("€%.2f", q.Rule.Amount)).SetAlign(tview.AlignRight))
+			table.SetCell(row, 3, tview.NewTableCell(q.Rule.Category))
+			table.SetCell(row, 4, tview.NewTableCell(q.Rule.Description).SetMaxWidth(30))
+			table.SetCell(row, 5, tview.NewTableCell(formatTags(q.Rule.Tags)))
+		}
+		if r, _ := table.GetSelection(); r < 1 || r > len(queued) {
+			table.Select(min(max(r, 1), len(queued)), 0)
+		}
+		return nil
+	}
+
+	// helper to post or skip the occurrences, the table is filled again either way so it shows what is still waiting
+	resolve := func(occurrences []QueuedOccurrence, post bool) {
+		for _, q := range occurrences {
+			if err := resolveQueuedOccurrence(q, post); err != nil {
+				showErrorModal(fmt.Sprintf("failed to confirm recurring transaction:\n\n%s", err), table)
+				log.Printf("failed to resolve queued occurrence of recurring rule %d: %s", q.Rule.Id, err)
+				break
+			}
+		}
+		if err := refresh(); err != nil {
+			showErrorModal(fmt.Sprintf("failed to list recurring transactions:\n\n%s", err), table)
+		}
+	}
+
+	// helper to get the occurrence on the selected row
+	selected := func() []QueuedOccurrence {
+		r, _ := table.GetSelection()
+		if q, ok := table.GetCell(r, 0).GetReference().(QueuedOccurrence); ok {
+			return []QueuedOccurrence{q}
+		}
+		return nil
+	}
+
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if ev := exitShortcuts(event); ev == nil {
+			closeQueue()
+			return nil
+		}
+		if event.Key() == tcell.KeyEnter {
+			resolve(selected(), true)
+			return nil
+		}
+		if event.Key() != tcell.KeyRune {
+			return vimMotions(event)
+		}
+
+		switch event.Rune() {
+		case 'a':
+			queued, err := listQueuedOccurrences()
+			if err != nil {
+				showErrorModal(fmt.Sprintf("failed to list recurring transactions:\n\n%s", err), table)
+				return nil
+			}
+			resolve(queued, true)
+			return nil
+		case 'd':
+			resolve(selected(), false)
+			return nil
+		}
+		return vimMotions(event)
+	})
+
+	if err := refresh(); err != nil {
+		showErrorModal(fmt.Sprintf("failed to list recurring transactions:\n\n%s", err), focus)
+		return
+	}
+
+	// navigation help
+	frame := tview.NewFrame(table).
+		AddText(generateQueuedOccurrencesFooter(), false, tview.AlignCenter, theme.FieldTextColor)
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).    // left spacer
+		AddItem(frame, 110, 1, true). // same width as the budgets
+		AddItem(nil, 0, 1, false))    // right spacer
+
+	pages.AddPage("recurringQueue", modal, true, true)
+	tui.SetFocus(table)
+}
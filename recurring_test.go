@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+// helper to build a date for the recurring rule tests
+func testDate(t *testing.T, raw string) time.Time {
+	t.Helper()
+	date, err := parseTransactionDate(raw)
+	if err != nil {
+		t.Fatalf("Invalid test date %q: %v", raw, err)
+	}
+	return date
+}
+
+// helper to list the dates of the transactions stored for a type, sorted by date
+func storedTransactionDates(t *testing.T, txType string) []string {
+	t.Helper()
+	transactions, err := LoadTransactionsInRange(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to load transactions: %v", err)
+	}
+	var dates []string
+	for _, months := range transactions {
+		for _, types := range months {
+			for _, tx := range types[txType] {
+				dates = append(dates, tx.Date.Format(TransactionDateFormat))
+			}
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+func TestOccurrenceDate(t *testing.T) {
+	tests := []struct {
+		start     string
+		frequency string
+		n         int
+		expected  string
+	}{
+		{"2025-01-15", "monthly", 0, "2025-01-15"},
+		{"2025-01-15", "monthly", 13, "2026-02-15"},
+		{"2025-01-31", "monthly", 1, "2025-02-28"},
+		{"2025-01-31", "monthly", 2, "2025-03-31"},
+		{"2024-01-31", "monthly", 1, "2024-02-29"},
+		{"2025-11-30", "quarterly", 1, "2026-02-28"},
+		{"2024-02-29", "yearly", 1, "2025-02-28"},
+		{"2024-02-29", "yearly", 4, "2028-02-29"},
+	}
+
+	for _, tt := range tests {
+		got := occurrenceDate(testDate(t, tt.start), tt.frequency, tt.n).Format(TransactionDateFormat)
+		if got != tt.expected {
+			t.Errorf("occurrence %d of %s from %s: expected %s, got %s", tt.n, tt.frequency, tt.start, tt.expected, got)
+		}
+	}
+}
+
+func TestDueOccurrences(t *testing.T) {
+	rule := RecurringRule{Frequency: "monthly", Start: testDate(t, "2025-01-31"), End: testDate(t, "2025-05-01")}
+
+	due := dueOccurrences(rule, testDate(t, "2025-03-31"))
+	if len(due) != 3 || due[1].Format(TransactionDateFormat) != "2025-02-28" {
+		t.Errorf("Expected 3 occurrences up to today, got %v", due)
+	}
+
+	rule.LastDate = testDate(t, "2025-03-31")
+	if due := dueOccurrences(rule, testDate(t, "2025-12-01")); len(due) != 1 || due[0].Format(TransactionDateFormat) != "2025-04-30" {
+		t.Errorf("Expected only the occurrence before the end, got %v", due)
+	}
+	if next := nextOccurrence(rule); next.Format(TransactionDateFormat) != "2025-04-30" {
+		t.Errorf("Expected next occurrence 2025-04-30, got %s", next)
+	}
+
+	rule.LastDate = testDate(t, "2025-04-30")
+	if next := nextOccurrence(rule); !next.IsZero() {
+		t.Errorf("Expected no next occurrence after the end, got %s", next)
+	}
+}
+
+func TestSaveRecurringRule(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	req := RecurringRuleRequest{
+		Type:        "expense",
+		Amount:      "850",
+		Category:    "housing",
+		Description: "rent",
+		Tags:        "Home, rent",
+		Frequency:   "monthly",
+		Start:       "2025-01-01",
+		AutoPost:    true,
+	}
+	id, err := saveRecurringRule(req)
+	if err != nil {
+		t.Fatalf("Expected no error adding a rule, got %v", err)
+	}
+
+	req.Id = id
+	req.Amount = "900"
+	req.End = "2025-12-31"
+	if _, err := saveRecurringRule(req); err != nil {
+		t.Fatalf("Expected no error changing a rule, got %v", err)
+	}
+
+	rule, err := getRecurringRule(id)
+	if err != nil {
+		t.Fatalf("Expected to find the rule, got %v", err)
+	}
+	if rule.Amount != 900 || rule.End.Format(TransactionDateFormat) != "2025-12-31" || formatTags(rule.Tags) != "home, rent" || !rule.AutoPost {
+		t.Errorf("Unexpected rule %+v", rule)
+	}
+
+	invalid := []RecurringRuleRequest{
+		{Type: "expense", Amount: "0", Category: "housing", Frequency: "monthly", Start: "2025-01-01"},
+		{Type: "expense", Amount: "10", Category: "salary", Frequency: "monthly", Start: "2025-01-01"},
+		{Type: "expense", Amount: "10", Category: "housing", Frequency: "weekly", Start: "2025-01-01"},
+		{Type: "expense", Amount: "10", Category: "housing", Frequency: "monthly", Start: "01.01.2025"},
+		{Type: "expense", Amount: "10", Category: "housing", Frequency: "monthly", Start: "2025-02-01", End: "2025-01-01"},
+	}
+	for _, r := range invalid {
+		if _, err := saveRecurringRule(r); err == nil {
+			t.Errorf("Expected rule %+v to be rejected", r)
+		}
+	}
+
+	if err := deleteRecurringRule(id); err != nil {
+		t.Fatalf("Expected no error deleting a rule, got %v", err)
+	}
+	if err := deleteRecurringRule(id); !errors.Is(err, ErrRecurringRuleNotFound) {
+		t.Errorf("Expected ErrRecurringRuleNotFound, got %v", err)
+	}
+}
+
+func TestPostDueRecurringTransactions(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	autoId, err := saveRecurringRule(RecurringRuleRequest{Type: "expense", Amount: "850", Category: "housing", Tags: "rent", Frequency: "monthly", Start: "2025-01-31", AutoPost: true})
+	if err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	confirmId, err := saveRecurringRule(RecurringRuleRequest{Type: "investment", Amount: "200", Category: "funds", Frequency: "quarterly", Start: "2025-01-05"})
+	if err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	posted, queued, err := postDueRecurringTransactions(testDate(t, "2025-04-10"))
+	if err != nil {
+		t.Fatalf("Expected no error posting, got %v", err)
+	}
+	if posted != 3 || queued != 2 {
+		t.Errorf("Expected 3 posted and 2 queued, got %d and %d", posted, queued)
+	}
+	expected := []string{"2025-01-31", "2025-02-28", "2025-03-31"}
+	if dates := storedTransactionDates(t, "expense"); len(dates) != len(expected) || dates[1] != expected[1] {
+		t.Errorf("Expected expenses on %v, got %v", expected, dates)
+	}
+
+	// running again the same day posts nothing twice
+	if posted, queued, err := postDueRecurringTransactions(testDate(t, "2025-04-10")); err != nil || posted != 0 || queued != 0 {
+		t.Errorf("Expected nothing to post on a second run, got %d posted, %d queued (err %v)", posted, queued, err)
+	}
+
+	waiting, err := listQueuedOccurrences()
+	if err != nil || len(waiting) != 2 {
+		t.Fatalf("Expected 2 queued occurrences, got %+v (err %v)", waiting, err)
+	}
+	if waiting[0].Rule.Id != confirmId || waiting[0].Date.Format(TransactionDateFormat) != "2025-01-05" {
+		t.Errorf("Unexpected first queued occurrence %+v", waiting[0])
+	}
+	if err := resolveQueuedOccurrence(waiting[0], true); err != nil {
+		t.Fatalf("Expected no error posting a queued occurrence, got %v", err)
+	}
+	if err := resolveQueuedOccurrence(waiting[1], false); err != nil {
+		t.Fatalf("Expected no error skipping a queued occurrence, got %v", err)
+	}
+	if dates := storedTransactionDates(t, "investment"); len(dates) != 1 || dates[0] != "2025-01-05" {
+		t.Errorf("Expected only the confirmed investment, got %v", dates)
+	}
+	if waiting, _ := listQueuedOccurrences(); len(waiting) != 0 {
+		t.Errorf("Expected an empty queue, got %+v", waiting)
+	}
+
+	// a paused rule posts nothing and skips what came due while it was paused
+	if err := setRecurringRulePaused(autoId, true); err != nil {
+		t.Fatalf("Failed to pause rule: %v", err)
+	}
+	if posted, _, _ := postDueRecurringTransactions(testDate(t, "2025-06-10")); posted != 0 {
+		t.Errorf("Expected a paused rule to post nothing, got %d", posted)
+	}
+	if err := setRecurringRulePaused(autoId, false); err != nil {
+		t.Fatalf("Failed to resume rule: %v", err)
+	}
+	rule, err := getRecurringRule(autoId)
+	if err != nil {
+		t.Fatalf("Failed to load rule: %v", err)
+	}
+	if next := nextOccurrence(rule); next.Before(today()) {
+		t.Errorf("Expected the next occurrence after resuming to not be in the past, got %s", next)
+	}
+}
+
+func TestRecurringRulesFollowCategoryChanges(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	id, err := saveRecurringRule(RecurringRuleRequest{Type: "expense", Amount: "12.99", Category: "entertainment", Frequency: "monthly", Start: "2025-01-01"})
+	if err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	if err := editCategory("expense", "entertainment", "subscriptions", ""); err != nil {
+		t.Fatalf("Failed to rename category: %v", err)
+	}
+	if rule, _ := getRecurringRule(id); rule.Category != "subscriptions" {
+		t.Errorf("Expected the rule to follow the renamed category, got %s", rule.Category)
+	}
+
+	if err := mergeCategory("expense", "subscriptions", "bills"); err != nil {
+		t.Fatalf("Failed to merge category: %v", err)
+	}
+	if rule, _ := getRecurringRule(id); rule.Category != "bills" {
+		t.Errorf("Expected the rule to follow the merged category, got %s", rule.Category)
+	}
+}
+
+func TestPostingIsAtomicWithLastDate(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	if _, err := saveRecurringRule(RecurringRuleRequest{Type: "income", Amount: "3000", Category: "salary", Frequency: "monthly", Start: "2025-01-25", AutoPost: true}); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	// the rule can't be moved along, so the transaction is not posted either
+	if _, err := db.Exec(`CREATE TRIGGER fail_last_date BEFORE UPDATE ON recurring_rules BEGIN SELECT RAISE(ABORT, 'update failed'); END`); err != nil {
+		t.Fatalf("Failed to create trigger: %v", err)
+	}
+	if _, _, err := postDueRecurringTransactions(testDate(t, "2025-02-01")); err == nil {
+		t.Fatalf("Expected posting to fail when the rule can't be updated")
+	}
+	if dates := storedTransactionDates(t, "income"); len(dates) != 0 {
+		t.Errorf("Expected nothing to be posted, got %v", dates)
+	}
+
+	// the next run posts every occurrence exactly once
+	if _, err := db.Exec(`DROP TRIGGER fail_last_date`); err != nil {
+		t.Fatalf("Failed to drop trigger: %v", err)
+	}
+	if posted, _, err := postDueRecurringTransactions(testDate(t, "2025-02-01")); err != nil || posted != 1 {
+		t.Errorf("Expected 1 posted transaction, got %d (err %v)", posted, err)
+	}
+	if dates := storedTransactionDates(t, "income"); len(dates) != 1 || dates[0] != "2025-01-25" {
+		t.Errorf("Expected a single salary on 2025-01-25, got %v", dates)
+	}
+}
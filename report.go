@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// machine readable representation of a PnLResult used for json and csv reports
+type pnlReportTotals struct {
+	Income         float64 `json:"income"`
+	Expense        float64 `json:"expense"`
+	Investment     float64 `json:"investment"`
+	SavingsAmount  float64 `json:"savingsAmount"`
+	SavingsPercent float64 `json:"savingsPercent"`
+}
+
+type monthReport struct {
+	Month string `json:"month"`
+	pnlReportTotals
+}
+
+type yearReport struct {
+	Year   string          `json:"year"`
+	Tag    string          `json:"tag,omitempty"` // only transactions with the tag are counted
+	Total  pnlReportTotals `json:"total"`
+	Months []monthReport   `json:"months"`
+}
+
+var reportCsvHeader = []string{"year", "month", "period", "income", "expense", "investment", "savings_amount", "savings_percent"}
+
+// helper to convert the calculated p&l into the report representation, amounts are rounded to cents
+func newPnLReportTotals(pnl PnLResult) pnlReportTotals {
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	return pnlReportTotals{
+		Income:         round(pnl.incomeTotal),
+		Expense:        round(pnl.expenseTotal),
+		Investment:     round(pnl.investmentTotal),
+		SavingsAmount:  round(pnl.pnlAmount),
+		SavingsPercent: round(pnl.pnlPercent),
+	}
+}
+
+// builds the per month and per year p&l reports, empty year or month means all of them
+// with a tag only the transactions with that tag are counted, and months and years without any of them are left out
+func buildPnLReport(year, month, tag string) ([]yearReport, error) {
+	var years []string
+	if year != "" {
+		years = []string{year}
+	} else {
+		var err error
+		if years, err = getYearsWithTransactions(); err != nil {
+			return nil, fmt.Errorf("unable to get years with transactions: %w", err)
+		}
+		sort.Strings(years) // oldest first
+	}
+
+	var reports []yearReport
+	for _, y := range years {
+		yearPnL, err := calculateYearPnL(y)
+		if err != nil {
+			return nil, fmt.Errorf("unable to calculate year pnl for %s: %w", y, err)
+		}
+
+		monthlyPnL, err := calculateYearMonthlyPnL(y)
+		if err != nil {
+			return nil, fmt.Errorf("unable to calculate monthly pnl for %s: %w", y, err)
+		}
+
+		var months []string
+		for m := range monthlyPnL {
+			if month == "" || m == month {
+				months = append(months, m)
+			}
+		}
+		sort.Slice(months, func(i, j int) bool { return monthOrder[months[i]] < monthOrder[months[j]] })
+
+		if tag != "" {
+			if yearPnL, err = calculateTagPnL(tag, "", y); err != nil {
+				return nil, fmt.Errorf("unable to calculate pnl of tag %s for %s: %w", tag, y, err)
+			}
+			var tagged []string
+			for _, m := range months {
+				pnl, err := calculateTagPnL(tag, m, y)
+				if err != nil {
+					return nil, fmt.Errorf("unable to calculate pnl of tag %s for %s %s: %w", tag, m, y, err)
+				}
+				if pnl == (PnLResult{}) {
+					continue
+				}
+				monthlyPnL[m] = pnl
+				tagged = append(tagged, m)
+			}
+			if len(tagged) == 0 {
+				continue
+			}
+			months = tagged
+		}
+
+		report := yearReport{Year: y, Tag: tag, Total: newPnLReportTotals(yearPnL), Months: []monthReport{}}
+		for _, m := range months {
+			report.Months = append(report.Months, monthReport{Month: m, pnlReportTotals: newPnLReportTotals(monthlyPnL[m])})
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// writes the reports as indented json
+func writePnLReportJson(out io.Writer, reports []yearReport) error {
+	if reports == nil {
+		reports = []yearReport{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reports)
+}
+
+// writes the reports as csv, one row per month followed by a row with the year total
+func writePnLReportCsv(out io.Writer, reports []yearReport) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(reportCsvHeader); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+
+	formatRow := func(year, month, period string, totals pnlReportTotals) []string {
+		return []string{
+			year, month, period,
+			strconv.FormatFloat(totals.Income, 'f', 2, 64),
+			strconv.FormatFloat(totals.Expense, 'f', 2, 64),
+			strconv.FormatFloat(totals.Investment, 'f', 2, 64),
+			strconv.FormatFloat(totals.SavingsAmount, 'f', 2, 64),
+			strconv.FormatFloat(totals.SavingsPercent, 'f', 2, 64),
+		}
+	}
+
+	for _, r := range reports {
+		for _, m := range r.Months {
+			if err := w.Write(formatRow(r.Year, m.Month, "month", m.pnlReportTotals)); err != nil {
+				return fmt.Errorf("failed to write csv row: %w", err)
+			}
+		}
+		if err := w.Write(formatRow(r.Year, "", "year", r.Total)); err != nil {
+			return fmt.Errorf("failed to write csv row: %w", err)
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func cliReportSetup(fs *flag.FlagSet) func(out io.Writer) error {
+	format := fs.String("format", "json", "output format - json or csv")
+	year := fs.String("year", "", "only report this year (default all years)")
+	month := fs.String("month", "", "only report this month")
+	tag := fs.String("tag", "", "only count transactions with this tag, e.g. vacation-2025")
+
+	return func(out io.Writer) error {
+		m := strings.ToLower(*month)
+		if m != "" {
+			if _, ok := monthOrder[m]; !ok {
+				return fmt.Errorf("invalid month %q", *month)
+			}
+		}
+
+		var filterTag string
+		if *tag != "" {
+			tags, err := parseTags(*tag)
+			if err != nil {
+				return err
+			}
+			if len(tags) != 1 {
+				return fmt.Errorf("--tag takes a single tag")
+			}
+			filterTag = tags[0]
+		}
+
+		reports, err := buildPnLReport(*year, m, filterTag)
+		if err != nil {
+			return err
+		}
+
+		switch strings.ToLower(*format) {
+		case "json":
+			return writePnLReportJson(out, reports)
+		case "csv":
+			return writePnLReportCsv(out, reports)
+		default:
+			return fmt.Errorf("unsupported report format %q, supported formats are json and csv", *format)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func seedReportTransactions(t *testing.T) {
+	t.Helper()
+	testTransactions := TransactionHistory{
+		"2024": {
+			"january": {
+				"income":  {{Id: "1", Amount: 1000.00, Category: "salary", Description: "salary"}},
+				"expense": {{Id: "2", Amount: 250.00, Category: "food", Description: "groceries"}},
+			},
+			"february": {
+				"income":     {{Id: "3", Amount: 1000.00, Category: "salary", Description: "salary"}},
+				"expense":    {{Id: "4", Amount: 500.00, Category: "housing", Description: "rent"}},
+				"investment": {{Id: "5", Amount: 100.00, Category: "funds", Description: "etf"}},
+			},
+		},
+		"2023": {
+			"december": {
+				"expense": {{Id: "6", Amount: 80.00, Category: "food", Description: "dinner"}},
+			},
+		},
+	}
+	if err := saveTransactionsToTestStorage(testTransactions); err != nil {
+		t.Fatalf("Failed to initialize test storage: %v", err)
+	}
+}
+
+func TestBuildPnLReport(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	seedReportTransactions(t)
+
+	reports, err := buildPnLReport("", "", "")
+	if err != nil {
+		t.Fatalf("Expected no error building report, got %v", err)
+	}
+	if len(reports) != 2 || reports[0].Year != "2023" || reports[1].Year != "2024" {
+		t.Fatalf("Expected reports for 2023 and 2024 in order, got %+v", reports)
+	}
+
+	year := reports[1]
+	if len(year.Months) != 2 || year.Months[0].Month != "january" || year.Months[1].Month != "february" {
+		t.Fatalf("Expected january and february in chronological order, got %+v", year.Months)
+	}
+	if year.Total.Income != 2000 || year.Total.Expense != 750 || year.Total.Investment != 100 {
+		t.Errorf("Unexpected year totals %+v", year.Total)
+	}
+	if year.Total.SavingsAmount != 1250 || year.Total.SavingsPercent != 62.5 {
+		t.Errorf("Unexpected year savings %+v", year.Total)
+	}
+	if year.Months[1].SavingsPercent != 50 {
+		t.Errorf("Expected 50%% savings in february, got %v", year.Months[1].SavingsPercent)
+	}
+
+	reports, err = buildPnLReport("2024", "february", "")
+	if err != nil {
+		t.Fatalf("Expected no error building filtered report, got %v", err)
+	}
+	if len(reports) != 1 || len(reports[0].Months) != 1 || reports[0].Months[0].Month != "february" {
+		t.Errorf("Expected only february 2024, got %+v", reports)
+	}
+}
+
+func TestCliReportFormats(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	seedReportTransactions(t)
+
+	out, err := runTestCliCommand(t, cliReportSetup, "--format", "json", "--year", "2024")
+	if err != nil {
+		t.Fatalf("Expected no error for json report, got %v", err)
+	}
+	var reports []yearReport
+	if err := json.Unmarshal([]byte(out), &reports); err != nil {
+		t.Fatalf("Expected valid json, got %v: %s", err, out)
+	}
+	if len(reports) != 1 || reports[0].Total.Income != 2000 {
+		t.Errorf("Unexpected json report %+v", reports)
+	}
+	if !strings.Contains(out, `"savingsPercent"`) {
+		t.Errorf("Expected json field names in output, got %s", out)
+	}
+
+	out, err = runTestCliCommand(t, cliReportSetup, "--format", "csv")
+	if err != nil {
+		t.Fatalf("Expected no error for csv report, got %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid csv, got %v: %s", err, out)
+	}
+	// header + (december + 2023 total) + (january + february + 2024 total)
+	if len(rows) != 6 {
+		t.Fatalf("Expected 6 csv rows, got %d: %v", len(rows), rows)
+	}
+	if strings.Join(rows[0], ",") != strings.Join(reportCsvHeader, ",") {
+		t.Errorf("Unexpected csv header %v", rows[0])
+	}
+	if rows[5][0] != "2024" || rows[5][2] != "year" || rows[5][3] != "2000.00" {
+		t.Errorf("Unexpected year total row %v", rows[5])
+	}
+
+	if _, err := runTestCliCommand(t, cliReportSetup, "--format", "xml"); err == nil {
+		t.Errorf("Expected error for unsupported format")
+	}
+	if _, err := runTestCliCommand(t, cliReportSetup, "--month", "smarch"); err == nil {
+		t.Errorf("Expected error for invalid month")
+	}
+}
+
+func TestBuildPnLReportForTag(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+	seedReportTransactions(t)
+	for _, req := range []AddTransactionRequest{
+		{Type: "expense", Amount: "300", Category: "travel", Date: "2024-02-10", Tags: "vacation-2024"},
+		{Type: "expense", Amount: "40", Category: "food", Date: "2024-02-11", Tags: "vacation-2024, reimbursable"},
+		{Type: "income", Amount: "40", Category: "refunds", Date: "2024-03-02", Tags: "reimbursable"},
+	} {
+		if _, err := addTransaction(req); err != nil {
+			t.Fatalf("Failed to add transaction: %v", err)
+		}
+	}
+
+	reports, err := buildPnLReport("", "", "vacation-2024")
+	if err != nil {
+		t.Fatalf("Expected no error building report, got %v", err)
+	}
+	if len(reports) != 1 || reports[0].Year != "2024" || reports[0].Tag != "vacation-2024" {
+		t.Fatalf("Expected only 2024 to have vacation transactions, got %+v", reports)
+	}
+	if months := reports[0].Months; len(months) != 1 || months[0].Month != "february" || months[0].Expense != 340 {
+		t.Errorf("Expected only the tagged february expenses, got %+v", months)
+	}
+	if total := reports[0].Total; total.Expense != 340 || total.Income != 0 {
+		t.Errorf("Unexpected tag totals %+v", total)
+	}
+
+	reports, _ = buildPnLReport("2024", "", "reimbursable")
+	if total := reports[0].Total; total.Expense != 40 || total.Income != 40 || total.SavingsAmount != 0 {
+		t.Errorf("Expected the reimbursable expense to be paid back, got %+v", total)
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+// passwords and keys are kept in byte slices instead of strings, a string can't be overwritten and stays in memory
+// until the garbage collector happens to reuse it - a byte slice is wiped as soon as the secret is no longer needed
+
+// whether secrets that are kept for the whole session are locked into memory, set from the config on start
+var lockSecretMemory bool
+
+// overwrites a password or key with zeros
+func wipe(b []byte) {
+	clear(b)
+}
+
+// copies a password or key that is kept for the whole session into memory of its own, nil for an empty secret
+// it has to be released with freeSecret, which wipes it
+func newSecret(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+	s := allocSecret(len(b))
+	copy(s, b)
+	return s
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package main
+
+import (
+	"log"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	mappedSecretsMu sync.Mutex
+	mappedSecrets   = map[*byte]bool{} // secrets in pages of their own, anything else was allocated on the go heap
+	memoryLockOnce  sync.Once
+)
+
+// locks secrets into memory if the config asks for it and disables core dumps, so neither swap nor a crash writes a password to disk
+func protectSecretMemory(config *Config) {
+	lockSecretMemory = config.LockMemory
+	if !config.LockMemory {
+		return
+	}
+	if err := unix.Setrlimit(unix.RLIMIT_CORE, &unix.Rlimit{}); err != nil {
+		log.Printf("warning: failed to disable core dumps: %s\n", err)
+	}
+}
+
+// allocates a secret in pages of its own that are locked with mlock and left out of core dumps
+// locking is best effort, e.g. RLIMIT_MEMLOCK can be too low, the secret is still usable and wiped then
+func allocSecret(n int) []byte {
+	if !lockSecretMemory {
+		return make([]byte, n)
+	}
+
+	b, err := unix.Mmap(-1, 0, n, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		warnMemoryLock(err)
+		return make([]byte, n)
+	}
+	if err := unix.Mlock(b); err != nil {
+		warnMemoryLock(err)
+	}
+	if err := unix.Madvise(b, unix.MADV_DONTDUMP); err != nil {
+		log.Printf("warning: failed to exclude secret from core dumps: %s\n", err)
+	}
+
+	mappedSecretsMu.Lock()
+	mappedSecrets[&b[0]] = true
+	mappedSecretsMu.Unlock()
+	return b
+}
+
+// wipes a secret from newSecret and gives its pages back
+func freeSecret(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	wipe(b)
+
+	mappedSecretsMu.Lock()
+	mapped := mappedSecrets[&b[0]]
+	delete(mappedSecrets, &b[0])
+	mappedSecretsMu.Unlock()
+	if !mapped {
+		return
+	}
+	unix.Munlock(b) // munmap unlocks as well, errors only mean it was never locked
+	if err := unix.Munmap(b); err != nil {
+		log.Printf("warning: failed to unmap secret: %s\n", err)
+	}
+}
+
+// helper to log a failed memory lock once instead of for every secret
+func warnMemoryLock(err error) {
+	memoryLockOnce.Do(func() {
+		log.Printf("warning: failed to lock secrets into memory, they may be swapped out: %s\n", err)
+	})
+}
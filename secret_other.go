@@ -0,0 +1,18 @@
+//go:build !linux
+
+package main
+
+// memory locking is only implemented on linux, elsewhere secrets live on the go heap and are only wiped after use
+func protectSecretMemory(config *Config) {
+	lockSecretMemory = config.LockMemory
+}
+
+// allocates a secret on the go heap
+func allocSecret(n int) []byte {
+	return make([]byte, n)
+}
+
+// wipes a secret from newSecret
+func freeSecret(b []byte) {
+	wipe(b)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSecretLifecycle(t *testing.T) {
+	for _, locked := range []bool{false, true} {
+		original := lockSecretMemory
+		lockSecretMemory = locked
+		t.Cleanup(func() { lockSecretMemory = original })
+
+		password := []byte("testpassword")
+		setUserPasswordBytes(password)
+		if !bytes.Equal(userPassword, password) {
+			t.Fatalf("Expected the stored password to match (locked %v)", locked)
+		}
+
+		// the caller can wipe its copy without touching the stored one
+		wipe(password)
+		if string(userPassword) != "testpassword" {
+			t.Errorf("Expected the stored password to be a copy (locked %v)", locked)
+		}
+
+		clearUserPassword()
+		if userPassword != nil || haveCredentials() {
+			t.Errorf("Expected the password to be cleared (locked %v)", locked)
+		}
+	}
+
+	if newSecret(nil) != nil {
+		t.Errorf("Expected an empty secret to be nil")
+	}
+}
+
+func TestDataKeyKeptForOpenDb(t *testing.T) {
+	t.Cleanup(forgetDataKey)
+
+	header, dataKey, err := newEncHeader([]byte("testpassword"), nil)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+	rememberDataKey(header, dataKey)
+
+	// wiping a key after use must not wipe the remembered one
+	expected := append([]byte(nil), dataKey...)
+	wipe(dataKey)
+	key := rememberedDataKey(header)
+	if !bytes.Equal(key, expected) {
+		t.Errorf("Expected the remembered data key to survive wiping a copy")
+	}
+
+	// a re-keyed file has a new header and has to be unlocked with the password again
+	other, _, err := newEncHeader([]byte("testpassword"), nil)
+	if err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+	if rememberedDataKey(other) != nil {
+		t.Errorf("Expected no data key for another header")
+	}
+
+	clearUserPassword()
+	if rememberedDataKey(header) != nil {
+		t.Errorf("Expected the data key to be wiped together with the password")
+	}
+}
+
+func TestLockMemoryFromEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	config, err := loadConfigFromEnvVars()
+	if err != nil || !config.LockMemory {
+		t.Errorf("Expected memory locking to be on by default (err %v)", err)
+	}
+
+	t.Setenv("EXPENSE_LOCK_MEMORY", "false")
+	if config, err = loadConfigFromEnvVars(); err != nil || config.LockMemory {
+		t.Errorf("Expected EXPENSE_LOCK_MEMORY=false to turn it off (err %v)", err)
+	}
+
+	t.Setenv("EXPENSE_LOCK_MEMORY", "maybe")
+	if config, err = loadConfigFromEnvVars(); err != nil || !config.LockMemory {
+		t.Errorf("Expected an invalid value to keep memory locking on (err %v)", err)
+	}
+}
+
+func TestClearPasswordWaitsForUsers(t *testing.T) {
+	original := lockSecretMemory
+	lockSecretMemory = true
+	t.Cleanup(func() { lockSecretMemory = original })
+
+	setUserPasswordBytes([]byte("testpassword"))
+	inUse, release := make(chan struct{}), make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- withCredentials(func(password, _ []byte) error {
+			close(inUse)
+			<-release
+			// the password has to stay readable until the user is done with it, freed memory would crash the test
+			if string(password) != "testpassword" {
+				t.Errorf("Expected the password to stay intact while in use, got %q", password)
+			}
+			return nil
+		})
+	}()
+
+	<-inUse
+	cleared := make(chan struct{})
+	go func() {
+		clearUserPassword()
+		close(cleared)
+	}()
+	select {
+	case <-cleared:
+		t.Fatalf("Expected clearing the password to wait until it is no longer in use")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected no error using the password, got %v", err)
+	}
+	<-cleared
+	if haveCredentials() {
+		t.Errorf("Expected the password to be cleared")
+	}
+	if err := withCredentials(func(_, _ []byte) error { return nil }); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials after clearing, got %v", err)
+	}
+}
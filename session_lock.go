@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// the month, year and table on screen, the login after a lock continues there
+type sessionView struct {
+	month     string
+	year      string
+	tableType string
+}
+
+var (
+	currentView sessionView  // updated by the transactions grid
+	resumeView  *sessionView // set while the session is locked, nil otherwise
+)
+
+// helper to keep track of the view on screen, table is the index of the focused table in the grid
+func rememberView(month, year string, table int) {
+	tableTypes := []string{"income", "expense", "investment"}
+	currentView = sessionView{month: month, year: year}
+	if table >= 0 && table < len(tableTypes) {
+		currentView.tableType = tableTypes[table]
+	}
+}
+
+// returns the view to open after logging in, the latest month unless the session was locked
+func takeResumeView() (month, year, tableType string) {
+	if resumeView == nil {
+		return "", "", ""
+	}
+	view := *resumeView
+	resumeView = nil
+	return view.month, view.year, view.tableType
+}
+
+// encrypts and closes the database and wipes the password and keyfile from memory, the instance lock is kept
+// the database is encrypted while it is still open, so a failure leaves the session as it was
+func lockSessionDb(config *Config) error {
+	if db == nil {
+		return fmt.Errorf("no database is open")
+	}
+
+	checkpointsRunning := stopCheckpoints()
+	if !readOnlySession {
+		if err := checkpointDb(); err != nil {
+			if checkpointsRunning {
+				startCheckpoints(config)
+			}
+			return fmt.Errorf("failed to encrypt database: %w", err)
+		}
+	}
+
+	closeDb()
+	if !config.InMemoryDb {
+		if err := os.Remove(config.UnencryptedDbFile); err != nil && !os.IsNotExist(err) {
+			log.Printf("warning: failed to remove plaintext database: %s\n", err)
+		}
+	}
+	clearUserPassword()
+	return nil
+}
+
+// locks the TUI session and goes back to the login prompt, logging in again returns to the same month and table
+func lockSession() {
+	if globalConfig.StorageType != StorageSQLite || db == nil {
+		return // nothing is decrypted, e.g. already locked
+	}
+
+	view := currentView
+	if err := lockSessionDb(globalConfig); err != nil {
+		showErrorModal(fmt.Sprintf("failed to lock the session:\n\n%s", err), tui.GetFocus())
+		log.Printf("failed to lock the session: %s", err)
+		return
+	}
+	resumeView = &view
+	disarmIdleLock()
+
+	// every page shows decrypted transactions, they are built again after the login
+	for _, name := range pages.GetPageNames(false) {
+		pages.RemovePage(name)
+	}
+	if err := loginForm(); err != nil {
+		log.Printf("login form failed to start: %s\n", err)
+		tui.Stop()
+		return
+	}
+	log.Printf("session locked")
+}
+
+// locks the session after the configured time without a key press, only armed while the session is unlocked
+type idleLocker struct {
+	mu           sync.Mutex
+	lastActivity time.Time
+	timeout      time.Duration
+	armed        bool
+	rearm        chan struct{} // wakes the stopped ticker after a login
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+var activeIdleLocker *idleLocker
+
+// starts watching for inactivity, every key press has to be reported with recordActivity
+// nothing is decrypted before the login, so it stays disarmed until armIdleLock is called
+func startIdleLock(config *Config) {
+	if config.StorageType != StorageSQLite || config.IdleLockTimeout <= 0 || activeIdleLocker != nil {
+		return
+	}
+
+	l := &idleLocker{
+		lastActivity: time.Now(),
+		timeout:      config.IdleLockTimeout,
+		rearm:        make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	activeIdleLocker = l
+	go l.run()
+}
+
+// stops watching for inactivity, called before the TUI exits
+func stopIdleLock() {
+	l := activeIdleLocker
+	activeIdleLocker = nil
+	if l == nil {
+		return
+	}
+	close(l.stop)
+	<-l.done
+}
+
+// starts counting the idle time again, called once the session is unlocked
+func armIdleLock() {
+	l := activeIdleLocker
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.lastActivity = time.Now()
+	l.armed = true
+	l.mu.Unlock()
+
+	select {
+	case l.rearm <- struct{}{}:
+	default: // already woken up
+	}
+}
+
+// stops counting the idle time, called once the session is locked
+func disarmIdleLock() {
+	if l := activeIdleLocker; l != nil {
+		l.disarm()
+	}
+}
+
+func (l *idleLocker) disarm() {
+	l.mu.Lock()
+	l.armed = false
+	l.mu.Unlock()
+}
+
+// resets the idle timer, called for every key press
+func recordActivity() {
+	if l := activeIdleLocker; l != nil {
+		l.mu.Lock()
+		l.lastActivity = time.Now()
+		l.mu.Unlock()
+	}
+}
+
+// reports if there was no key press for the whole timeout
+func (l *idleLocker) idle(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return now.Sub(l.lastActivity) >= l.timeout
+}
+
+// reports if the session is unlocked
+func (l *idleLocker) isArmed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.armed
+}
+
+func (l *idleLocker) run() {
+	defer close(l.done)
+
+	// checking a few times per timeout is precise enough, long timeouts are checked every five seconds
+	interval := min(l.timeout/4, 5*time.Second)
+	if interval <= 0 {
+		interval = l.timeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	if !l.isArmed() {
+		ticker.Stop()
+	}
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-l.rearm:
+			ticker.Reset(interval)
+		case now := <-ticker.C:
+			// the ticker only runs while the session is unlocked, a lock from the menu stops it at the next tick
+			if !l.isArmed() {
+				ticker.Stop()
+				continue
+			}
+			if !l.idle(now) {
+				continue
+			}
+
+			// disarmed before queueing, so a single lock is queued however long the TUI takes to run it
+			l.disarm()
+			ticker.Stop()
+
+			// the session is locked on the TUI goroutine, which owns the database and the pages
+			tui.QueueUpdateDraw(func() {
+				if l.idle(time.Now()) {
+					lockSession()
+				}
+				// a key press in the meantime or a failed lock keeps the session unlocked, so the timer starts over
+				if db != nil {
+					armIdleLock()
+				}
+			})
+		}
+	}
+}
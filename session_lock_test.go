@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLockSessionDb(t *testing.T) {
+	setupCheckpointSession(t, 100, 0)
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "food", Date: "2025-03-14"}); err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+
+	if err := lockSessionDb(globalConfig); err != nil {
+		t.Fatalf("Expected no error locking the session, got %v", err)
+	}
+	if db != nil || haveCredentials() || activeCheckpointer != nil {
+		t.Errorf("Expected the database to be closed, the password wiped and checkpoints stopped")
+	}
+
+	// the change made before the lock was encrypted
+	setUserPassword("testpassword")
+	image, err := decryptDatabaseImage()
+	if err != nil || countTransactionsInImage(t, image) != 1 {
+		t.Errorf("Expected the locked database to contain the transaction (err %v)", err)
+	}
+
+	if err := lockSessionDb(globalConfig); err == nil {
+		t.Errorf("Expected an error locking without an open database")
+	}
+}
+
+func TestLockSessionDbRemovesPlaintext(t *testing.T) {
+	config := setupChangePasswordSession(t)
+
+	if err := lockSessionDb(config); err != nil {
+		t.Fatalf("Expected no error locking the session, got %v", err)
+	}
+	if _, err := os.Stat(config.UnencryptedDbFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the plaintext database to be removed")
+	}
+
+	setUserPassword("testpassword")
+	image, err := decryptDatabaseImage()
+	if err != nil || countTransactionsInImage(t, image) != 1 {
+		t.Errorf("Expected the locked database to contain the transaction (err %v)", err)
+	}
+}
+
+func TestResumeView(t *testing.T) {
+	t.Cleanup(func() { currentView, resumeView = sessionView{}, nil })
+
+	if month, year, tableType := takeResumeView(); month != "" || year != "" || tableType != "" {
+		t.Errorf("Expected the latest month without a locked session, got %s %s %s", month, year, tableType)
+	}
+
+	rememberView("march", "2025", 1)
+	view := currentView
+	resumeView = &view
+
+	month, year, tableType := takeResumeView()
+	if month != "march" || year != "2025" || tableType != "expense" {
+		t.Errorf("Expected march 2025 expense, got %s %s %s", month, year, tableType)
+	}
+	if resumeView != nil {
+		t.Errorf("Expected the view to only be resumed once")
+	}
+}
+
+func TestIdleLocker(t *testing.T) {
+	l := &idleLocker{lastActivity: time.Now().Add(-2 * time.Minute), timeout: time.Minute}
+	if !l.idle(time.Now()) {
+		t.Errorf("Expected the session to be idle after the timeout")
+	}
+
+	original := activeIdleLocker
+	activeIdleLocker = l
+	t.Cleanup(func() { activeIdleLocker = original })
+
+	recordActivity()
+	if l.idle(time.Now()) {
+		t.Errorf("Expected a key press to reset the idle timer")
+	}
+}
+
+func TestIdleLockOnlyArmedWhileUnlocked(t *testing.T) {
+	originalLocker, originalTui := activeIdleLocker, tui
+	t.Cleanup(func() { activeIdleLocker, tui = originalLocker, originalTui })
+	activeIdleLocker = nil
+	tui = nil // queueing a lock would panic, so this also checks nothing is queued while disarmed
+
+	startIdleLock(&Config{StorageType: StorageSQLite, IdleLockTimeout: 10 * time.Millisecond})
+	l := activeIdleLocker
+	if l == nil || l.isArmed() {
+		t.Fatalf("Expected a disarmed idle locker before the login")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	armIdleLock()
+	if !l.isArmed() || l.idle(time.Now()) {
+		t.Errorf("Expected arming to start the idle timer over")
+	}
+	disarmIdleLock()
+	if l.isArmed() {
+		t.Errorf("Expected the idle locker to be disarmed after a lock")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	stopIdleLock()
+	if activeIdleLocker != nil {
+		t.Errorf("Expected the idle locker to be stopped")
+	}
+}
+
+func TestIdleLockTimeoutFromEnv(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	config, err := loadConfigFromEnvVars()
+	if err != nil || config.IdleLockTimeout != defaultIdleLockTimeout {
+		t.Errorf("Expected the default idle timeout, got %v (err %v)", config.IdleLockTimeout, err)
+	}
+
+	t.Setenv("EXPENSE_IDLE_LOCK_MINUTES", "0")
+	if config, err = loadConfigFromEnvVars(); err != nil || config.IdleLockTimeout != 0 {
+		t.Errorf("Expected 0 to disable the idle lock, got %v (err %v)", config.IdleLockTimeout, err)
+	}
+
+	t.Setenv("EXPENSE_IDLE_LOCK_MINUTES", "soon")
+	if config, err = loadConfigFromEnvVars(); err != nil || config.IdleLockTimeout != defaultIdleLockTimeout {
+		t.Errorf("Expected an invalid idle timeout to keep the default, got %v (err %v)", config.IdleLockTimeout, err)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// creates a TUI window with the settings of the encrypted database
+func showSettings(selectedMonth, selectedYear, focusTableType string) error {
+	if err := requireSQLiteStorage(); err != nil {
+		return err
+	}
+	if err := requireWritableDb(); err != nil {
+		return err
+	}
+
+	// back to the list of transactions at the same month and year from where the settings were opened
+	backToTransactions := func() {
+		pages.RemovePage("settings")
+		gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType, true)
+	}
+
+	list := styleList(tview.NewList())
+	list.AddItem("Change password", "", 0, func() {
+		pages.RemovePage("settings")
+		if err := formChangePassword(selectedMonth, selectedYear, focusTableType); err != nil {
+			showErrorModal(fmt.Sprintf("change password error:\n\n%s", err), tui.GetFocus())
+		}
+	})
+	list.AddItem("Manage categories", "", 0, func() {
+		pages.RemovePage("settings")
+		if err := showCategories(selectedMonth, selectedYear, focusTableType); err != nil {
+			showErrorModal(fmt.Sprintf("categories error:\n\n%s", err), tui.GetFocus())
+		}
+	})
+	list.AddItem("Manage budgets", "", 0, func() {
+		pages.RemovePage("settings")
+		if err := showBudgets(selectedMonth, selectedYear, focusTableType); err != nil {
+			showErrorModal(fmt.Sprintf("budgets error:\n\n%s", err), tui.GetFocus())
+		}
+	})
+	list.AddItem("Recurring transactions", "", 0, func() {
+		pages.RemovePage("settings")
+		if err := showRecurringRules(selectedMonth, selectedYear, focusTableType); err != nil {
+			showErrorModal(fmt.Sprintf("recurring transactions error:\n\n%s", err), tui.GetFocus())
+		}
+	})
+	list.AddItem("Regenerate recovery key", "", 0, func() {
+		confirmRegenerateRecoveryKey(list)
+	})
+	list.AddItem("Back", "", 0, backToTransactions)
+
+	list.SetTitle("Settings").
+		SetTitleAlign(tview.AlignCenter).
+		SetBorder(true)
+
+	// navigation help
+	frame := tview.NewFrame(list).
+		AddText(generateCombinedControlsFooter(), false, tview.AlignCenter, theme.FieldTextColor)
+
+	// horizontal centering
+	modal := styleFlex(tview.NewFlex().
+		AddItem(nil, 0, 1, false).   // left spacer
+		AddItem(frame, 60, 1, true). // form width fixed to fit text
+		AddItem(nil, 0, 1, false))   // right spacer
+
+	// vertical centering
+	centeredModal := styleFlex(tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).   // top spacer
+		AddItem(modal, 16, 1, true). // enough to fit the settings and the footer
+		AddItem(nil, 0, 1, false))   // bottom spacer
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// handle exit events
+		if ev := exitShortcuts(event); ev == nil {
+			backToTransactions()
+			return nil // key event consumed
+		}
+		// handle j/k events to navigate up or down
+		return vimMotions(event)
+	})
+
+	pages.AddPage("settings", centeredModal, true, true)
+	tui.SetFocus(list)
+	return nil
+}
+
+// asks before replacing the recovery key, the old one stops working once a new one is generated
+func confirmRegenerateRecoveryKey(focus tview.Primitive) {
+	closePrompt := func() {
+		pages.RemovePage("regenerateRecoveryKeyPrompt")
+		tui.SetFocus(focus)
+	}
+
+	modal := styleModal(tview.NewModal().
+		SetText("Generate a new recovery key?\n\nThe current recovery key will no longer open the database. Older copies of the encrypted database still open with the key they were saved with.").
+		AddButtons([]string{"Regenerate", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			closePrompt()
+			if label != "Regenerate" {
+				return
+			}
+
+			recoveryKey, err := regenerateRecoveryKey()
+			if err != nil {
+				showErrorModal(fmt.Sprintf("failed to regenerate recovery key:\n\n%s", err), focus)
+				log.Printf("failed to regenerate recovery key: %s", err)
+				return
+			}
+			showRecoveryKeyModal(recoveryKey, focus)
+		}))
+
+	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			closePrompt()
+			return nil
+		}
+		return event
+	})
+
+	overlay := tview.NewFlex().
+		SetDirection(tview.FlexRow).
+		AddItem(nil, 0, 1, false).
+		AddItem(modal, 12, 1, true). // modal height
+		AddItem(nil, 0, 1, false)
+
+	centered := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(overlay, 70, 1, true). // modal width
+		AddItem(nil, 0, 1, false)
+
+	pages.AddPage("regenerateRecoveryKeyPrompt", centered, true, true)
+	tui.SetFocus(modal)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// a storage backend for transactions, the handlers only talk to storage through this interface
+type Store interface {
+	// every stored transaction
+	LoadAll() (TransactionHistory, error)
+	// only the transactions of one year, month and/or type - empty values match everything
+	LoadPeriod(year, month, txType string) (TransactionHistory, error)
+	// only the transactions dated between from and to (both inclusive), a zero time leaves that side of the range open
+	LoadRange(from, to time.Time) (TransactionHistory, error)
+
+	Get(txType, id string) (Transaction, error)
+	IdExists(id string) (bool, error)
+	// the month a transaction is stored under is decided by its date
+	Insert(txType string, tx Transaction) error
+	Update(txType string, tx Transaction) error
+	Delete(txType, id string) error
+	// replaces everything that is stored, only meant for bulk changes
+	ReplaceAll(transactions TransactionHistory) error
+
+	Metadata() (StoreMetadata, error)
+	// every tag that is used by at least one transaction, sorted by name
+	Tags() ([]string, error)
+}
+
+// describes a store and what is in it
+type StoreMetadata struct {
+	Backend          string
+	ReadOnly         bool
+	SchemaVersion    int // only the sqlite database has versioned migrations
+	TransactionCount int
+	FirstDate        time.Time
+	LastDate         time.Time
+}
+
+var ErrReadOnlyStore = errors.New("storage is read-only")
+
+// overrides the store picked from the config, used by tests to run against an in-memory store
+var activeStore Store
+
+// helper to get the store for the configured storage type
+func currentStore() (Store, error) {
+	if activeStore != nil {
+		return activeStore, nil
+	}
+	if err := ensureStorageConfig(); err != nil {
+		return nil, fmt.Errorf("failed to load storage config, err: %w", err)
+	}
+
+	switch globalConfig.StorageType {
+	case StorageSQLite:
+		return sqliteStore{}, nil
+	case StorageJSON:
+		return newJsonFileStore(globalConfig.JsonFile), nil
+	case StorageLegacyJSON:
+		return newLegacyJsonStore(globalConfig.JsonFile), nil
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", globalConfig.StorageType)
+	}
+}
+
+// helper for features that are only backed by the sqlite database, like statement imports and their profiles
+func requireSQLiteStorage() error {
+	if globalConfig != nil && globalConfig.StorageType != StorageSQLite {
+		return fmt.Errorf("only supported with %s storage, current storage is %s", StorageSQLite, globalConfig.StorageType)
+	}
+	return nil
+}
+
+// helper to refuse changes while the database is only open for reading because another instance holds the lock
+func requireWritableDb() error {
+	if readOnlySession {
+		return fmt.Errorf("%w: the database is open in another instance", ErrReadOnlyStore)
+	}
+	return nil
+}
+
+// helper to refuse changes to data that is only kept in the sqlite database, like categories, budgets and recurring rules
+func requireWritableSQLiteDb() error {
+	if err := requireSQLiteStorage(); err != nil {
+		return err
+	}
+	if err := requireWritableDb(); err != nil {
+		return err
+	}
+	if db == nil {
+		return fmt.Errorf("database is not open")
+	}
+	return nil
+}
+
+// the encrypted sqlite database, uses the connection opened by initDb after login
+type sqliteStore struct{}
+
+func (sqliteStore) LoadAll() (TransactionHistory, error) {
+	return loadTransactionsFromDb()
+}
+
+func (sqliteStore) LoadPeriod(year, month, txType string) (TransactionHistory, error) {
+	return loadTransactionsForPeriodFromDb(year, month, txType)
+}
+
+func (sqliteStore) LoadRange(from, to time.Time) (TransactionHistory, error) {
+	return loadTransactionsInRangeFromDb(from, to)
+}
+
+func (sqliteStore) Get(txType, id string) (Transaction, error) {
+	return getTransactionFromDb(txType, id)
+}
+
+func (sqliteStore) IdExists(id string) (bool, error) {
+	return transactionIdExistsInDb(id)
+}
+
+func (sqliteStore) Insert(txType string, tx Transaction) error {
+	if err := requireWritableDb(); err != nil {
+		return err
+	}
+	return insertTransactionToDb(txType, tx)
+}
+
+func (sqliteStore) Update(txType string, tx Transaction) error {
+	if err := requireWritableDb(); err != nil {
+		return err
+	}
+	return updateTransactionInDb(txType, tx)
+}
+
+func (sqliteStore) Delete(txType, id string) error {
+	if err := requireWritableDb(); err != nil {
+		return err
+	}
+	return deleteTransactionFromDb(txType, id)
+}
+
+func (sqliteStore) ReplaceAll(transactions TransactionHistory) error {
+	if err := requireWritableDb(); err != nil {
+		return err
+	}
+	return saveTransactionsToDb(transactions)
+}
+
+func (sqliteStore) Metadata() (StoreMetadata, error) {
+	return transactionsMetadataFromDb()
+}
+
+func (sqliteStore) Tags() ([]string, error) {
+	return transactionTagsFromDb()
+}
+
+// helper to copy the matching transactions into a new history, so callers can't change what a store holds
+func filterTransactionHistory(transactions TransactionHistory, keep func(year, month, txType string, tx Transaction) bool) TransactionHistory {
+	result := make(TransactionHistory)
+	for year, months := range transactions {
+		for month, types := range months {
+			for txType, list := range types {
+				for _, tx := range list {
+					if keep != nil && !keep(year, month, txType, tx) {
+						continue
+					}
+					tx.Tags = slices.Clone(tx.Tags)
+					if _, ok := result[year]; !ok {
+						result[year] = make(map[string]map[string][]Transaction)
+					}
+					if _, ok := result[year][month]; !ok {
+						result[year][month] = make(map[string][]Transaction)
+					}
+					result[year][month][txType] = append(result[year][month][txType], tx)
+				}
+			}
+		}
+	}
+
+	// same order as the sqlite store returns them in
+	for _, months := range result {
+		for _, types := range months {
+			for txType, list := range types {
+				types[txType] = sortTransactionsByDate(list)
+			}
+		}
+	}
+	return result
+}
+
+// helper to give every transaction a date within the month it is stored under, like the sqlite store does on save
+func normalizeTransactionHistory(transactions TransactionHistory) (TransactionHistory, error) {
+	result := make(TransactionHistory)
+	for year, months := range transactions {
+		for month, types := range months {
+			for txType, list := range types {
+				if _, err := normalizeTransactionType(txType); err != nil {
+					return nil, fmt.Errorf("invalid type %q in %s %s: %w", txType, month, year, err)
+				}
+				for _, tx := range list {
+					date, err := transactionDateInPeriod(tx, month, year)
+					if err != nil {
+						return nil, err
+					}
+					if tx.Date, err = parseTransactionDate(date); err != nil {
+						return nil, err
+					}
+					if _, ok := result[year]; !ok {
+						result[year] = make(map[string]map[string][]Transaction)
+					}
+					if _, ok := result[year][month]; !ok {
+						result[year][month] = make(map[string][]Transaction)
+					}
+					result[year][month][txType] = append(result[year][month][txType], tx)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// helper to describe a transaction history that is fully loaded in memory
+func transactionHistoryMetadata(transactions TransactionHistory) StoreMetadata {
+	var meta StoreMetadata
+	for _, months := range transactions {
+		for _, types := range months {
+			for _, list := range types {
+				for _, tx := range list {
+					meta.TransactionCount++
+					if meta.FirstDate.IsZero() || tx.Date.Before(meta.FirstDate) {
+						meta.FirstDate = tx.Date
+					}
+					if tx.Date.After(meta.LastDate) {
+						meta.LastDate = tx.Date
+					}
+				}
+			}
+		}
+	}
+	return meta
+}
+
+// helper to list the tags of a transaction history that is fully loaded in memory, sorted by name
+func transactionHistoryTags(transactions TransactionHistory) []string {
+	var tags []string
+	for _, months := range transactions {
+		for _, types := range months {
+			for _, list := range types {
+				for _, tx := range list {
+					for _, tag := range tx.Tags {
+						if !slices.Contains(tags, tag) {
+							tags = append(tags, tag)
+						}
+					}
+				}
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// a transaction as stored in the json file, the layout is the one of the old transactions.json (year -> month -> type -> transactions)
+// with an added date, files from before dates existed are read with every transaction dated the first of its month
+type jsonTransaction struct {
+	Id          string   `json:"id"`
+	Amount      float64  `json:"amount"`
+	Category    string   `json:"category"`
+	Description string   `json:"description"`
+	Date        string   `json:"date,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+type jsonTransactionHistory map[string]map[string]map[string][]jsonTransaction
+
+// keeps all transactions in a single plaintext json file, every change rewrites the file
+// the file is not encrypted, it is meant for fixtures, development and moving data around
+type jsonFileStore struct {
+	path     string
+	readOnly bool
+}
+
+func newJsonFileStore(path string) *jsonFileStore {
+	return &jsonFileStore{path: path}
+}
+
+// read-only access to a transactions.json file in the layout that was used before sqlite, e.g. test_data/transactions.json
+func newLegacyJsonStore(path string) *jsonFileStore {
+	return &jsonFileStore{path: path, readOnly: true}
+}
+
+func (s *jsonFileStore) LoadAll() (TransactionHistory, error) {
+	mem, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return mem.LoadAll()
+}
+
+func (s *jsonFileStore) LoadPeriod(year, month, txType string) (TransactionHistory, error) {
+	mem, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return mem.LoadPeriod(year, month, txType)
+}
+
+func (s *jsonFileStore) LoadRange(from, to time.Time) (TransactionHistory, error) {
+	mem, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return mem.LoadRange(from, to)
+}
+
+func (s *jsonFileStore) Get(txType, id string) (Transaction, error) {
+	mem, err := s.load()
+	if err != nil {
+		return Transaction{}, err
+	}
+	return mem.Get(txType, id)
+}
+
+func (s *jsonFileStore) IdExists(id string) (bool, error) {
+	mem, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	return mem.IdExists(id)
+}
+
+func (s *jsonFileStore) Insert(txType string, tx Transaction) error {
+	return s.change(func(mem *memoryStore) error { return mem.Insert(txType, tx) })
+}
+
+func (s *jsonFileStore) Update(txType string, tx Transaction) error {
+	return s.change(func(mem *memoryStore) error { return mem.Update(txType, tx) })
+}
+
+func (s *jsonFileStore) Delete(txType, id string) error {
+	return s.change(func(mem *memoryStore) error { return mem.Delete(txType, id) })
+}
+
+func (s *jsonFileStore) ReplaceAll(transactions TransactionHistory) error {
+	return s.change(func(mem *memoryStore) error { return mem.ReplaceAll(transactions) })
+}
+
+func (s *jsonFileStore) Metadata() (StoreMetadata, error) {
+	mem, err := s.load()
+	if err != nil {
+		return StoreMetadata{}, err
+	}
+	meta, err := mem.Metadata()
+	if err != nil {
+		return meta, err
+	}
+	meta.Backend = string(StorageJSON)
+	if s.readOnly {
+		meta.Backend = string(StorageLegacyJSON)
+	}
+	meta.ReadOnly = s.readOnly
+	return meta, nil
+}
+
+func (s *jsonFileStore) Tags() ([]string, error) {
+	mem, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return mem.Tags()
+}
+
+// helper to read the whole file into an in-memory store, a missing file is an empty store unless it is read-only
+func (s *jsonFileStore) load() (*memoryStore, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) && !s.readOnly {
+		return newMemoryStore(nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read json transactions file: %w", err)
+	}
+
+	var stored jsonTransactionHistory
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse json transactions file %s: %w", s.path, err)
+	}
+
+	transactions := make(TransactionHistory)
+	for year, months := range stored {
+		for month, types := range months {
+			if _, err := firstOfMonth(month, year); err != nil {
+				return nil, fmt.Errorf("invalid period %s %s in %s: %w", month, year, s.path, err)
+			}
+			for txType, list := range types {
+				for _, jt := range list {
+					tx := Transaction{Id: jt.Id, Amount: jt.Amount, Category: jt.Category, Description: jt.Description, Tags: jt.Tags}
+					if jt.Date != "" {
+						if tx.Date, err = parseTransactionDate(jt.Date); err != nil {
+							return nil, fmt.Errorf("invalid date for transaction %s: %w", jt.Id, err)
+						}
+					}
+					if _, ok := transactions[year]; !ok {
+						transactions[year] = make(map[string]map[string][]Transaction)
+					}
+					if _, ok := transactions[year][month]; !ok {
+						transactions[year][month] = make(map[string][]Transaction)
+					}
+					transactions[year][month][txType] = append(transactions[year][month][txType], tx)
+				}
+			}
+		}
+	}
+
+	return newMemoryStore(transactions)
+}
+
+// helper to apply a change to the file contents and write them back
+func (s *jsonFileStore) change(apply func(mem *memoryStore) error) error {
+	if s.readOnly {
+		return fmt.Errorf("%w: %s", ErrReadOnlyStore, s.path)
+	}
+
+	mem, err := s.load()
+	if err != nil {
+		return err
+	}
+	if err := apply(mem); err != nil {
+		return err
+	}
+
+	transactions, err := mem.LoadAll()
+	if err != nil {
+		return err
+	}
+	return s.write(transactions)
+}
+
+// writes the whole file atomically, so a failed write never leaves a half written file behind
+func (s *jsonFileStore) write(transactions TransactionHistory) error {
+	stored := make(jsonTransactionHistory)
+	for year, months := range transactions {
+		stored[year] = make(map[string]map[string][]jsonTransaction)
+		for month, types := range months {
+			stored[year][month] = make(map[string][]jsonTransaction)
+			for txType, list := range types {
+				for _, tx := range list {
+					stored[year][month][txType] = append(stored[year][month][txType], jsonTransaction{
+						Id:          tx.Id,
+						Amount:      tx.Amount,
+						Category:    tx.Category,
+						Description: tx.Description,
+						Date:        tx.Date.Format(TransactionDateFormat),
+						Tags:        tx.Tags,
+					})
+				}
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode json transactions: %w", err)
+	}
+
+	if err := writeFileAtomically(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write json transactions file: %w", err)
+	}
+	return nil
+}
+
+// copies every transaction of a legacy transactions.json into the current store, transactions whose id is already in use are skipped
+// returns the number of copied and skipped transactions, without commit nothing is written
+func importLegacyJsonFile(path string, commit bool) (imported, skipped int, err error) {
+	legacy, err := newLegacyJsonStore(path).LoadAll()
+	if err != nil {
+		return 0, 0, err
+	}
+	target, err := currentStore()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, months := range legacy {
+		for _, types := range months {
+			for txType, list := range types {
+				for _, tx := range list {
+					exists, err := target.IdExists(tx.Id)
+					if err != nil {
+						return imported, skipped, err
+					}
+					if exists {
+						skipped++
+						continue
+					}
+					if commit {
+						if err := target.Insert(txType, tx); err != nil {
+							return imported, skipped, fmt.Errorf("failed to import transaction %s: %w", tx.Id, err)
+						}
+					}
+					imported++
+				}
+			}
+		}
+	}
+	return imported, skipped, nil
+}
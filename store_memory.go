@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// keeps transactions in memory only, used for tests and as the working copy of the json file store
+type memoryStore struct {
+	mu           sync.Mutex
+	transactions TransactionHistory
+}
+
+// creates an in-memory store, optionally prefilled with transactions
+func newMemoryStore(transactions TransactionHistory) (*memoryStore, error) {
+	normalized, err := normalizeTransactionHistory(transactions)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryStore{transactions: normalized}, nil
+}
+
+func (s *memoryStore) LoadAll() (TransactionHistory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return filterTransactionHistory(s.transactions, nil), nil
+}
+
+func (s *memoryStore) LoadPeriod(year, month, txType string) (TransactionHistory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return filterTransactionHistory(s.transactions, func(y, m, t string, _ Transaction) bool {
+		return (year == "" || y == year) && (month == "" || m == month) && (txType == "" || t == txType)
+	}), nil
+}
+
+func (s *memoryStore) LoadRange(from, to time.Time) (TransactionHistory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return filterTransactionHistory(s.transactions, func(_, _, _ string, tx Transaction) bool {
+		return (from.IsZero() || !tx.Date.Before(from)) && (to.IsZero() || !tx.Date.After(to))
+	}), nil
+}
+
+func (s *memoryStore) Get(txType, id string) (Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	year, month, i, ok := s.find(txType, id)
+	if !ok {
+		return Transaction{}, fmt.Errorf("%w: %s transaction with id %s", ErrTransactionNotFound, txType, id)
+	}
+	tx := s.transactions[year][month][txType][i]
+	tx.Tags = slices.Clone(tx.Tags)
+	return tx, nil
+}
+
+func (s *memoryStore) IdExists(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hasId(id), nil
+}
+
+func (s *memoryStore) Insert(txType string, tx Transaction) error {
+	if tx.Date.IsZero() {
+		return fmt.Errorf("insert failed for transaction %s: missing date", tx.Id)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// ids are unique across all types, same as the primary key of the sqlite table
+	if s.hasId(tx.Id) {
+		return fmt.Errorf("insert failed for transaction %s: id already in use", tx.Id)
+	}
+	s.add(txType, tx)
+	return nil
+}
+
+func (s *memoryStore) Update(txType string, tx Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	year, month, i, ok := s.find(txType, tx.Id)
+	if !ok {
+		return fmt.Errorf("%w: %s transaction with id %s", ErrTransactionNotFound, txType, tx.Id)
+	}
+
+	// a date in another month moves the transaction over to that month
+	if newMonth, newYear := periodOfDate(tx.Date); newMonth != month || newYear != year {
+		s.remove(year, month, txType, i)
+		s.add(txType, tx)
+		return nil
+	}
+	s.transactions[year][month][txType][i] = tx
+	return nil
+}
+
+func (s *memoryStore) Delete(txType, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	year, month, i, ok := s.find(txType, id)
+	if !ok {
+		return fmt.Errorf("%w: %s transaction with id %s", ErrTransactionNotFound, txType, id)
+	}
+	s.remove(year, month, txType, i)
+	return nil
+}
+
+func (s *memoryStore) ReplaceAll(transactions TransactionHistory) error {
+	normalized, err := normalizeTransactionHistory(transactions)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transactions = normalized
+	return nil
+}
+
+func (s *memoryStore) Metadata() (StoreMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta := transactionHistoryMetadata(s.transactions)
+	meta.Backend = string(StorageMemory)
+	return meta, nil
+}
+
+func (s *memoryStore) Tags() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return transactionHistoryTags(s.transactions), nil
+}
+
+// helper to locate a transaction of the given type, the caller has to hold the lock
+func (s *memoryStore) find(txType, id string) (year, month string, index int, ok bool) {
+	for y, months := range s.transactions {
+		for m, types := range months {
+			for i, tx := range types[txType] {
+				if tx.Id == id {
+					return y, m, i, true
+				}
+			}
+		}
+	}
+	return "", "", 0, false
+}
+
+// helper to check if an id is used by a transaction of any type, the caller has to hold the lock
+func (s *memoryStore) hasId(id string) bool {
+	for _, txType := range []string{"income", "expense", "investment"} {
+		if _, _, _, ok := s.find(txType, id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// helper to add a transaction to the month of its date, the caller has to hold the lock
+func (s *memoryStore) add(txType string, tx Transaction) {
+	month, year := periodOfDate(tx.Date)
+	if s.transactions == nil {
+		s.transactions = make(TransactionHistory)
+	}
+	if _, ok := s.transactions[year]; !ok {
+		s.transactions[year] = make(map[string]map[string][]Transaction)
+	}
+	if _, ok := s.transactions[year][month]; !ok {
+		s.transactions[year][month] = make(map[string][]Transaction)
+	}
+	s.transactions[year][month][txType] = append(s.transactions[year][month][txType], tx)
+}
+
+// helper to remove a transaction at a specific index, the caller has to hold the lock
+func (s *memoryStore) remove(year, month, txType string, index int) {
+	list := s.transactions[year][month][txType]
+	s.transactions[year][month][txType] = append(list[:index:index], list[index+1:]...)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// runs the same checks against every store implementation
+func TestStoreImplementations(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"sqlite": func(t *testing.T) Store {
+			setupTestStorage(t, StorageSQLite)
+			return sqliteStore{}
+		},
+		"memory": func(t *testing.T) Store {
+			store, err := newMemoryStore(nil)
+			if err != nil {
+				t.Fatalf("Failed to create memory store: %v", err)
+			}
+			return store
+		},
+		"json": func(t *testing.T) Store {
+			return newJsonFileStore(filepath.Join(t.TempDir(), "transactions.json"))
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t)
+
+			lunch := Transaction{Id: "aaaa1111", Amount: 12.5, Category: "food", Description: "lunch", Date: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)}
+			salary := Transaction{Id: "bbbb2222", Amount: 3000, Category: "salary", Description: "march", Date: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}
+			rent := Transaction{Id: "cccc3333", Amount: 900, Category: "rent", Description: "april", Date: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)}
+
+			for _, insert := range []struct {
+				txType string
+				tx     Transaction
+			}{{"expense", lunch}, {"income", salary}, {"expense", rent}} {
+				if err := store.Insert(insert.txType, insert.tx); err != nil {
+					t.Fatalf("Expected no error inserting %s, got %v", insert.tx.Id, err)
+				}
+			}
+			if err := store.Insert("investment", lunch); err == nil {
+				t.Errorf("Expected error inserting a duplicate id")
+			}
+
+			march, err := store.LoadPeriod("2025", "march", "expense")
+			if err != nil || len(march["2025"]["march"]["expense"]) != 1 || len(march["2025"]["march"]["income"]) != 0 {
+				t.Errorf("Expected only the march expense, got %+v (err %v)", march, err)
+			}
+
+			inRange, err := store.LoadRange(time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC))
+			if err != nil || len(inRange["2025"]["march"]["expense"]) != 1 || len(inRange["2025"]["march"]["income"]) != 0 || len(inRange["2025"]["april"]["expense"]) != 1 {
+				t.Errorf("Expected lunch and rent within the range, got %+v (err %v)", inRange, err)
+			}
+
+			// a new date in another month moves the transaction
+			lunch.Amount = 14
+			lunch.Date = time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC)
+			if err := store.Update("expense", lunch); err != nil {
+				t.Fatalf("Expected no error updating, got %v", err)
+			}
+			got, err := store.Get("expense", lunch.Id)
+			if err != nil || got.Amount != 14 || !got.Date.Equal(lunch.Date) {
+				t.Errorf("Expected updated transaction, got %+v (err %v)", got, err)
+			}
+			all, err := store.LoadAll()
+			if err != nil || len(all["2025"]["march"]["expense"]) != 0 || len(all["2025"]["april"]["expense"]) != 2 {
+				t.Errorf("Expected both expenses in april, got %+v (err %v)", all, err)
+			}
+			if all["2025"]["april"]["expense"][0].Id != rent.Id {
+				t.Errorf("Expected transactions sorted by date, got %+v", all["2025"]["april"]["expense"])
+			}
+
+			if _, err := store.Get("income", lunch.Id); !errors.Is(err, ErrTransactionNotFound) {
+				t.Errorf("Expected ErrTransactionNotFound for another type, got %v", err)
+			}
+			if err := store.Delete("expense", rent.Id); err != nil {
+				t.Fatalf("Expected no error deleting, got %v", err)
+			}
+			if err := store.Delete("expense", rent.Id); !errors.Is(err, ErrTransactionNotFound) {
+				t.Errorf("Expected ErrTransactionNotFound deleting twice, got %v", err)
+			}
+			if exists, _ := store.IdExists(rent.Id); exists {
+				t.Errorf("Expected deleted id to be free again")
+			}
+
+			meta, err := store.Metadata()
+			if err != nil {
+				t.Fatalf("Expected no error reading metadata, got %v", err)
+			}
+			if meta.Backend != name || meta.TransactionCount != 2 ||
+				meta.FirstDate.Format(TransactionDateFormat) != "2025-03-01" || meta.LastDate.Format(TransactionDateFormat) != "2025-04-02" {
+				t.Errorf("Unexpected metadata %+v", meta)
+			}
+		})
+	}
+}
+
+func TestJsonFileStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.json")
+	tx := Transaction{Id: "aaaa1111", Amount: 12.5, Category: "food", Description: "lunch", Date: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)}
+	if err := newJsonFileStore(path).Insert("expense", tx); err != nil {
+		t.Fatalf("Expected no error inserting, got %v", err)
+	}
+
+	// a new store on the same file sees the change, and the file can be read as a legacy file as well
+	for _, store := range []Store{newJsonFileStore(path), newLegacyJsonStore(path)} {
+		got, err := store.Get("expense", tx.Id)
+		if err != nil || got.Description != "lunch" || !got.Date.Equal(tx.Date) {
+			t.Errorf("Expected stored transaction, got %+v (err %v)", got, err)
+		}
+	}
+}
+
+func TestLegacyJsonStore(t *testing.T) {
+	store := newLegacyJsonStore(filepath.Join("test_data", "transactions.json"))
+
+	transactions, err := store.LoadAll()
+	if err != nil {
+		t.Fatalf("Expected no error reading legacy file, got %v", err)
+	}
+	expenses := transactions["2025"]["august"]["expense"]
+	if len(expenses) == 0 {
+		t.Fatalf("Expected august 2025 expenses in legacy file")
+	}
+	for _, tx := range expenses {
+		if tx.Date.Format(TransactionDateFormat) != "2025-08-01" {
+			t.Errorf("Expected legacy transaction %s to be dated the first of its month, got %s", tx.Id, tx.Date.Format(TransactionDateFormat))
+		}
+	}
+
+	if err := store.Delete("expense", expenses[0].Id); !errors.Is(err, ErrReadOnlyStore) {
+		t.Errorf("Expected ErrReadOnlyStore, got %v", err)
+	}
+	if meta, _ := store.Metadata(); !meta.ReadOnly || meta.Backend != string(StorageLegacyJSON) {
+		t.Errorf("Expected read-only legacy metadata, got %+v", meta)
+	}
+
+	if _, err := newLegacyJsonStore(filepath.Join(t.TempDir(), "missing.json")).LoadAll(); err == nil {
+		t.Errorf("Expected error for a missing legacy file")
+	}
+}
+
+func TestImportLegacyJsonFile(t *testing.T) {
+	setupTestStorage(t, StorageMemory)
+	path := filepath.Join("test_data", "transactions.json")
+
+	legacy, err := newLegacyJsonStore(path).Metadata()
+	if err != nil {
+		t.Fatalf("Expected no error reading legacy file, got %v", err)
+	}
+
+	imported, skipped, err := importLegacyJsonFile(path, false)
+	if err != nil || imported != legacy.TransactionCount || skipped != 0 {
+		t.Fatalf("Expected preview of %d transactions, got %d imported %d skipped (err %v)", legacy.TransactionCount, imported, skipped, err)
+	}
+	if meta, _ := activeStore.Metadata(); meta.TransactionCount != 0 {
+		t.Errorf("Expected preview not to write anything, got %d transactions", meta.TransactionCount)
+	}
+
+	if _, _, err := importLegacyJsonFile(path, true); err != nil {
+		t.Fatalf("Expected no error importing, got %v", err)
+	}
+	// a second import skips everything that is already there
+	imported, skipped, err = importLegacyJsonFile(path, true)
+	if err != nil || imported != 0 || skipped != legacy.TransactionCount {
+		t.Errorf("Expected everything to be skipped, got %d imported %d skipped (err %v)", imported, skipped, err)
+	}
+}
+
+func TestHandlersWithMemoryStore(t *testing.T) {
+	setupTestStorage(t, StorageMemory)
+
+	id, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "10", Category: "food", Description: "lunch", Date: "2025-03-14"})
+	if err != nil {
+		t.Fatalf("Expected no error adding, got %v", err)
+	}
+	if err := handleUpdateTransaction(UpdateTransactionRequest{Type: "expense", Id: id, Amount: "11", Category: "food", Description: "lunch", Date: "2025-04-01"}); err != nil {
+		t.Fatalf("Expected no error updating, got %v", err)
+	}
+
+	transactions, _ := loadTransactionsFromTestStorage()
+	if got := transactions["2025"]["april"]["expense"]; len(got) != 1 || got[0].Amount != 11 {
+		t.Errorf("Expected updated transaction in april, got %+v", transactions)
+	}
+
+	if err := handleDeleteTransaction("expense", id); err != nil {
+		t.Fatalf("Expected no error deleting, got %v", err)
+	}
+	if exists, _ := TransactionIdExists(id); exists {
+		t.Errorf("Expected transaction to be deleted")
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/tview"
+)
+
+// tags are free-form labels like vacation-2025 or reimbursable, a transaction of any type can have several of them
+const TagMaxLength = 30
+
+// tags are entered and stored as a comma separated list, so a tag can't contain a comma
+const TagSeparator = ","
+
+// a search that starts with the prefix only matches transactions with that exact tag, e.g. #vacation-2025
+const TagSearchPrefix = "#"
+
+// helper to turn a comma separated list of tags into clean tags - lowercased, without duplicates and sorted
+// an optional # in front of a tag is dropped, so tags can be typed the same way they are searched for
+func parseTags(input string) ([]string, error) {
+	var tags []string
+	for _, part := range strings.Split(input, TagSeparator) {
+		tag := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(part), TagSearchPrefix))
+		if tag == "" {
+			continue
+		}
+		if strings.ContainsFunc(tag, unicode.IsSpace) {
+			return nil, fmt.Errorf("tag %q can't contain spaces, use e.g. a dash instead", tag)
+		}
+		if utf8.RuneCountInString(tag) > TagMaxLength {
+			return nil, fmt.Errorf("tag %q can have at most %d characters", tag, TagMaxLength)
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// helper to show tags the way they are entered in the forms
+func formatTags(tags []string) string {
+	return strings.Join(tags, TagSeparator+" ")
+}
+
+// helper to check if a transaction has a tag, tags are stored lowercased
+func hasTag(tx Transaction, tag string) bool {
+	return slices.Contains(tx.Tags, strings.ToLower(strings.TrimPrefix(tag, TagSearchPrefix)))
+}
+
+// lists every tag that is used by at least one transaction, sorted by name
+func listTags() ([]string, error) {
+	store, err := currentStore()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := store.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list tags: %w", err)
+	}
+	return tags, nil
+}
+
+// helper to autocomplete the tag that is being typed from the known tags, every entry is the whole input with the tag completed
+func autocompleteTags(known []string) func(currentText string) []string {
+	return func(currentText string) []string {
+		// only the tag after the last comma is completed, the ones before it are kept as typed
+		done, typing := "", currentText
+		if i := strings.LastIndex(currentText, TagSeparator); i >= 0 {
+			done, typing = currentText[:i+1]+" ", currentText[i+1:]
+		}
+		typing = strings.ToLower(strings.TrimSpace(typing))
+		if typing == "" {
+			return nil
+		}
+
+		var entries []string
+		for _, tag := range known {
+			if strings.HasPrefix(tag, typing) && tag != typing {
+				entries = append(entries, done+tag)
+			}
+		}
+		return entries
+	}
+}
+
+// creates the tags input of the add and update forms, the tag that is being typed is completed from the tags already in use
+func newTagsInputField(tags []string) *tview.InputField {
+	field := styleInputField(tview.NewInputField().
+		SetLabel("Tags (comma separated)").
+		SetText(formatTags(tags)))
+
+	// autocomplete is only a convenience, the form still works without it
+	known, err := listTags()
+	if err != nil {
+		log.Printf("failed to list tags for autocomplete: %s", err)
+	}
+	field.SetAutocompleteFunc(autocompleteTags(known))
+	return field
+}
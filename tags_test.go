@@ -0,0 +1,158 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseTags(t *testing.T) {
+	tags, err := parseTags(" Vacation-2025, #reimbursable,,vacation-2025 ")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !slices.Equal(tags, []string{"reimbursable", "vacation-2025"}) {
+		t.Errorf("Expected lowercased, deduplicated and sorted tags, got %v", tags)
+	}
+
+	if tags, err := parseTags(" "); err != nil || tags != nil {
+		t.Errorf("Expected no tags for an empty input, got %v (err %v)", tags, err)
+	}
+	if _, err := parseTags("summer trip"); err == nil {
+		t.Errorf("Expected a tag with a space to be rejected")
+	}
+	if _, err := parseTags("a-tag-that-is-way-too-long-to-be-useful"); err == nil {
+		t.Errorf("Expected a tag over %d characters to be rejected", TagMaxLength)
+	}
+}
+
+func TestAutocompleteTags(t *testing.T) {
+	complete := autocompleteTags([]string{"reimbursable", "vacation-2024", "vacation-2025", "wedding"})
+
+	if entries := complete("Vac"); !slices.Equal(entries, []string{"vacation-2024", "vacation-2025"}) {
+		t.Errorf("Expected the matching tags, got %v", entries)
+	}
+	// only the last tag is completed, the ones before it are kept
+	if entries := complete("wedding, re"); !slices.Equal(entries, []string{"wedding, reimbursable"}) {
+		t.Errorf("Expected the last tag to be completed, got %v", entries)
+	}
+	if entries := complete("wedding, "); entries != nil {
+		t.Errorf("Expected no suggestions before a tag is started, got %v", entries)
+	}
+}
+
+func TestTransactionTags(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	id, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "120", Category: "travel", Date: "2025-07-14", Tags: "vacation-2025, reimbursable"})
+	if err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+	incomeId, err := addTransaction(AddTransactionRequest{Type: "income", Amount: "120", Category: "refunds", Date: "2025-08-01", Tags: "reimbursable"})
+	if err != nil {
+		t.Fatalf("Failed to add transaction: %v", err)
+	}
+
+	tx, _ := GetTransaction("expense", id)
+	if !slices.Equal(tx.Tags, []string{"reimbursable", "vacation-2025"}) {
+		t.Errorf("Expected the tags to be stored, got %v", tx.Tags)
+	}
+	transactions, _ := LoadTransactionsForPeriod("2025", "august", "income")
+	if got := transactions["2025"]["august"]["income"]; len(got) != 1 || !slices.Equal(got[0].Tags, []string{"reimbursable"}) {
+		t.Errorf("Expected the tags to be loaded with the transactions, got %+v", got)
+	}
+	if tags, _ := listTags(); !slices.Equal(tags, []string{"reimbursable", "vacation-2025"}) {
+		t.Errorf("Expected every tag in use once, got %v", tags)
+	}
+
+	if err := handleUpdateTransaction(UpdateTransactionRequest{Type: "expense", Id: id, Amount: "120", Category: "travel", Tags: "vacation-2025"}); err != nil {
+		t.Fatalf("Failed to update transaction: %v", err)
+	}
+	if tx, _ := GetTransaction("expense", id); !slices.Equal(tx.Tags, []string{"vacation-2025"}) {
+		t.Errorf("Expected the update to replace the tags, got %v", tx.Tags)
+	}
+
+	if err := DeleteTransaction("income", incomeId); err != nil {
+		t.Fatalf("Failed to delete transaction: %v", err)
+	}
+	var left int
+	db.QueryRow(`SELECT COUNT(*) FROM transaction_tags WHERE transaction_id = ?`, incomeId).Scan(&left)
+	if left != 0 {
+		t.Errorf("Expected the tags of a deleted transaction to be removed, %d left", left)
+	}
+
+	if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "1", Category: "food", Date: "2025-07-14", Tags: "two words"}); err == nil {
+		t.Errorf("Expected invalid tags to be rejected")
+	}
+}
+
+func TestTransactionTagsJsonStore(t *testing.T) {
+	store := newJsonFileStore(filepath.Join(t.TempDir(), "transactions.json"))
+	tx := Transaction{Id: "a1b2c3d4", Amount: 10, Category: "food", Date: time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC), Tags: []string{"wedding"}}
+	if err := store.Insert("expense", tx); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+
+	got, err := store.Get("expense", tx.Id)
+	if err != nil || !slices.Equal(got.Tags, tx.Tags) {
+		t.Errorf("Expected the tags to be kept in the json file, got %v (err %v)", got.Tags, err)
+	}
+}
+
+func TestTransactionMatchesSearch(t *testing.T) {
+	tx := Transaction{Id: "a1b2c3d4", Amount: 10, Category: "travel", Description: "hotel", Tags: []string{"vacation-2025"}}
+
+	for filter, want := range map[string]bool{
+		"#vacation-2025": true,
+		"#Vacation-2025": true,
+		"#vacation":      false, // tag searches are exact
+		"#":              true,
+		"vacation":       true, // a plain search also looks at the tags
+		"hotel":          true,
+		"wedding":        false,
+	} {
+		if got := transactionMatchesSearch(tx, filter); got != want {
+			t.Errorf("Expected search %q to be %v, got %v", filter, want, got)
+		}
+	}
+
+	if title := searchResultsTitle("expense", []Transaction{tx, tx}); title != "Expense - 2 matches, €20.00" {
+		t.Errorf("Unexpected search title %q", title)
+	}
+}
+
+func TestListTags(t *testing.T) {
+	testCases := []struct {
+		name        string
+		storageType StorageType
+	}{
+		{"SQLite", StorageSQLite},
+		{"Memory", StorageMemory},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupTestStorage(t, tc.storageType)
+
+			if _, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "40", Category: "food", Date: "2025-07-14", Tags: "wedding, gifts"}); err != nil {
+				t.Fatalf("Failed to add transaction: %v", err)
+			}
+			id, err := addTransaction(AddTransactionRequest{Type: "expense", Amount: "15", Category: "food", Date: "2025-07-15", Tags: "gifts, birthday"})
+			if err != nil {
+				t.Fatalf("Failed to add transaction: %v", err)
+			}
+			if tags, err := listTags(); err != nil || !slices.Equal(tags, []string{"birthday", "gifts", "wedding"}) {
+				t.Errorf("Expected every tag in use once and sorted, got %v (err %v)", tags, err)
+			}
+
+			// a tag is gone once no transaction uses it anymore
+			if err := DeleteTransaction("expense", id); err != nil {
+				t.Fatalf("Failed to delete transaction: %v", err)
+			}
+			if tags, err := listTags(); err != nil || !slices.Equal(tags, []string{"gifts", "wedding"}) {
+				t.Errorf("Expected the tags of the deleted transaction to be gone, got %v (err %v)", tags, err)
+			}
+		})
+	}
+}
@@ -24,6 +24,8 @@ func setupTestStorage(t *testing.T, storageType StorageType) {
 	switch storageType {
 	case StorageSQLite:
 		setupTestDb(t)
+	case StorageMemory:
+		setupTestMemoryStore(t)
 	default:
 		t.Fatalf("Unsupported storage type for testing: %s", storageType)
 	}
@@ -61,7 +63,8 @@ func setupTestDb(t *testing.T) {
             'january','february','march','april','may','june',
             'july','august','september','october','november','december'
 				)
-			)
+			),
+			date				TEXT
 		);
 	`
 
@@ -70,6 +73,18 @@ func setupTestDb(t *testing.T) {
 		t.Fatalf("Failed to create test schema: %v", err)
 	}
 
+	// Create imported transactions table used by statement imports
+	_, err = testDb.Exec(`
+		CREATE TABLE imported_transactions (
+			transaction_id TEXT PRIMARY KEY,
+			ref            TEXT UNIQUE,
+			needs_review   INTEGER NOT NULL DEFAULT 0
+		);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create imported transactions test schema: %v", err)
+	}
+
 	// Set up test config for SQLite
 	testConfig := &Config{
 		StorageType:       StorageSQLite,
@@ -88,6 +103,27 @@ func setupTestDb(t *testing.T) {
 		os.Remove(testDbFilePath)
 		SetGlobalConfig(originalConfig)
 	})
+
+	// Bring the test schema up to the latest version so tables added by later migrations exist as well
+	if err := runMigrations(nil); err != nil {
+		t.Fatalf("Failed to migrate test schema: %v", err)
+	}
+}
+
+// setupTestMemoryStore runs the test against an empty in-memory store instead of a database
+func setupTestMemoryStore(t *testing.T) {
+	store, err := newMemoryStore(nil)
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+
+	SetGlobalConfig(&Config{StorageType: StorageMemory})
+	activeStore = store
+
+	t.Cleanup(func() {
+		activeStore = nil
+		SetGlobalConfig(originalConfig)
+	})
 }
 
 // loadTransactionsFromTestStorage loads transactions from the current test storage
@@ -95,6 +131,8 @@ func loadTransactionsFromTestStorage() (TransactionHistory, error) {
 	switch globalConfig.StorageType {
 	case StorageSQLite:
 		return loadTransactionsFromTestDb()
+	case StorageMemory:
+		return activeStore.LoadAll()
 	default:
 		return nil, fmt.Errorf("unsupported storage type for testing: %s", globalConfig.StorageType)
 	}
@@ -103,7 +141,7 @@ func loadTransactionsFromTestStorage() (TransactionHistory, error) {
 // loadTransactionsFromTestDb loads transactions from the transactions table (in test db)
 func loadTransactionsFromTestDb() (TransactionHistory, error) {
 	rows, err := db.Query(`
-			SELECT id, amount, type, category, description, year, month
+			SELECT id, amount, type, category, description, year, month, date
 			FROM transactions
 		`)
 	if err != nil {
@@ -118,14 +156,20 @@ func loadTransactionsFromTestDb() (TransactionHistory, error) {
 			id, txType, category, description, month string
 			amount                                   float64
 			year                                     int
+			date                                     sql.NullString
 		)
 
-		if err := rows.Scan(&id, &amount, &txType, &category, &description, &year, &month); err != nil {
+		if err := rows.Scan(&id, &amount, &txType, &category, &description, &year, &month, &date); err != nil {
 			return nil, fmt.Errorf("scan failed: %w", err)
 		}
 
 		y := fmt.Sprintf("%d", year)
 
+		txDate, err := storedTransactionDate(date.String, month, y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date for transaction %s: %w", id, err)
+		}
+
 		if _, ok := transactions[y]; !ok {
 			transactions[y] = make(map[string]map[string][]Transaction)
 		}
@@ -138,6 +182,7 @@ func loadTransactionsFromTestDb() (TransactionHistory, error) {
 			Amount:      amount,
 			Category:    category,
 			Description: description,
+			Date:        txDate,
 		})
 	}
 
@@ -153,6 +198,8 @@ func saveTransactionsToTestStorage(transactions TransactionHistory) error {
 	switch globalConfig.StorageType {
 	case StorageSQLite:
 		return saveTransactionsToTestDb(transactions)
+	case StorageMemory:
+		return activeStore.ReplaceAll(transactions)
 	default:
 		return fmt.Errorf("unsupported storage type for testing: %s", globalConfig.StorageType)
 	}
@@ -173,8 +220,8 @@ func saveTransactionsToTestDb(transactions TransactionHistory) error {
 
 	sqlStatement, err := tx.Prepare(`
 			INSERT INTO transactions
-			(id, amount, type, category, description, year, month)
-			VALUES (?, ?, ?, ?, ?, ?, ?)
+			(id, amount, type, category, description, year, month, date)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		`)
 	if err != nil {
 		tx.Rollback()
@@ -194,6 +241,12 @@ func saveTransactionsToTestDb(transactions TransactionHistory) error {
 			for txType, list := range types {
 
 				for _, tr := range list {
+					date, err := transactionDateInPeriod(tr, month, year)
+					if err != nil {
+						tx.Rollback()
+						return err
+					}
+
 					_, err = sqlStatement.Exec(
 						tr.Id,
 						tr.Amount,
@@ -202,6 +255,7 @@ func saveTransactionsToTestDb(transactions TransactionHistory) error {
 						tr.Description,
 						y,     // integer, e.g. 2025
 						month, // string, e.g. August
+						date,  // string, e.g. 2025-08-14
 					)
 					if err != nil {
 						tx.Rollback()
@@ -241,7 +295,7 @@ func setupTestEncryption(t *testing.T) (string, string) {
 
 // testEncryptDatabase encrypts a database file using test-specific paths
 func testEncryptDatabase(_ *testing.T, dbPath, testEncFile, testSaltFile string) error {
-	if userPassword == "" {
+	if userPassword == nil {
 		return fmt.Errorf("user password not set")
 	}
 
@@ -256,7 +310,7 @@ func testEncryptDatabase(_ *testing.T, dbPath, testEncFile, testSaltFile string)
 		return fmt.Errorf("failed to get salt: %w", err)
 	}
 
-	key := pbkdf2.Key([]byte(userPassword), salt, iterations, keyLen, sha256.New)
+	key := pbkdf2.Key(userPassword, salt, iterations, keyLen, sha256.New)
 
 	encryptedData, err := encryptTransactions(key, dbData)
 	if err != nil {
@@ -273,7 +327,7 @@ func testEncryptDatabase(_ *testing.T, dbPath, testEncFile, testSaltFile string)
 
 // testDecryptDatabase decrypts a database file using test-specific paths
 func testDecryptDatabase(_ *testing.T, dbPath, testEncFile, testSaltFile string) error {
-	if userPassword == "" {
+	if userPassword == nil {
 		return fmt.Errorf("user password not set")
 	}
 
@@ -293,7 +347,7 @@ func testDecryptDatabase(_ *testing.T, dbPath, testEncFile, testSaltFile string)
 		return fmt.Errorf("failed to load salt: %w", err)
 	}
 
-	key := pbkdf2.Key([]byte(userPassword), salt, iterations, keyLen, sha256.New)
+	key := pbkdf2.Key(userPassword, salt, iterations, keyLen, sha256.New)
 
 	decryptedData, err := decryptTransactions(key, encryptedData)
 	if err != nil {
@@ -85,6 +85,16 @@ func styleTable(table *tview.Table) *tview.Table {
 	return table
 }
 
+// helper to style tree views in the TUI
+func styleTreeView(tree *tview.TreeView) *tview.TreeView {
+	tree.SetBackgroundColor(theme.BackgroundColor)
+	tree.SetBorderColor(theme.BorderColor)
+	tree.SetTitleColor(theme.TitleColor)
+	tree.SetGraphicsColor(theme.BorderColor)
+
+	return tree
+}
+
 // helper to style lists in the TUI
 func styleList(list *tview.List) *tview.List {
 	list.SetBackgroundColor(theme.BackgroundColor)
@@ -18,6 +18,11 @@ func generateWindowNavigationFooter() string {
 		Yellow + "q" + Reset + ": back  " +
 		Yellow + "m" + Reset + ": select month  " +
 		Yellow + "y" + Reset + ": select year  " +
+		Yellow + "c" + Reset + ": category totals  " +
+		Yellow + "b" + Reset + ": budgets  " +
+		Yellow + "p" + Reset + ": change password  " +
+		Yellow + "s" + Reset + ": settings  " +
+		Yellow + "L" + Reset + ": lock  " +
 		Yellow + "TAB" + Reset + ": next table"
 }
 
@@ -35,6 +40,16 @@ func generateTransactionNavigationFooter() string {
 
 // handles creating a pop-up for error messages in the TUI
 func showErrorModal(msg string, focus tview.Primitive) {
+	showMessageModal("errorModal", msg, focus)
+}
+
+// handles creating a pop-up for messages that confirm an action in the TUI
+func showInfoModal(msg string, focus tview.Primitive) {
+	showMessageModal("infoModal", msg, focus)
+}
+
+// helper to overlay a pop-up with a message and an OK button on top of the current screen
+func showMessageModal(pageName, msg string, focus tview.Primitive) {
 	modal := styleModal(tview.NewModal().
 		SetText(msg).
 		AddButtons([]string{"OK"}))
@@ -42,7 +57,7 @@ func showErrorModal(msg string, focus tview.Primitive) {
 	// handle closing (OK, ESC, or 'q')
 	closeModal := func() {
 		// remove the modal page and go back to previous
-		pages.RemovePage("errorModal")
+		pages.RemovePage(pageName)
 		tui.SetFocus(focus)
 	}
 
@@ -54,7 +69,7 @@ func showErrorModal(msg string, focus tview.Primitive) {
 	modal.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		if event.Key() == tcell.KeyEsc || (event.Key() == tcell.KeyRune && (event.Rune() == 'q' || event.Rune() == 'Q')) {
 			// go back to previous screen
-			pages.RemovePage("errorModal")
+			pages.RemovePage(pageName)
 			tui.SetFocus(focus)
 			return nil
 		}
@@ -74,6 +89,6 @@ func showErrorModal(msg string, focus tview.Primitive) {
 		AddItem(nil, 0, 1, false)
 
 	// add modal as a page to overlay on top of existing content
-	pages.AddPage("errorModal", centered, true, true)
+	pages.AddPage(pageName, centered, true, true)
 	tui.SetFocus(modal)
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/rivo/tview"
 )
@@ -14,6 +17,8 @@ type UpdateTransactionRequest struct {
 	Amount      string
 	Category    string
 	Description string
+	Date        string // YYYY-MM-DD, the transaction moves to another month if the date is outside of its current one
+	Tags        string // comma separated, replaces the current tags - empty removes them
 }
 
 // creates a TUI form with required fields to update an existing transaction
@@ -68,6 +73,10 @@ func formUpdateTransaction(transactionId, transactionType, selectedMonth, select
 			log.Printf("failed to list categories err:\n\n%s", err)
 			return err
 		}
+		// an archived category isn't offered for new transactions but this one still has it
+		if !slices.Contains(opts, tx.Category) {
+			opts = append(opts, tx.Category)
+		}
 		categoryDropdown.SetOptions(opts, func(selectedOption string, index int) {
 			tx.Category = selectedOption
 		})
@@ -91,21 +100,40 @@ func formUpdateTransaction(transactionId, transactionType, selectedMonth, select
 		descriptionField.SetLabel(fmt.Sprintf("Description (%d/%d)", len(text), DescriptionMaxCharLength))
 	})
 
+	// date field (pre-populated with current date)
+	dateField := styleInputField(tview.NewInputField().
+		SetLabel("Date (YYYY-MM-DD)").
+		SetText(tx.Date.Format(TransactionDateFormat)))
+
+	// tags field (pre-populated with current tags)
+	tagsField := newTagsInputField(tx.Tags)
+
 	form = styleForm(tview.NewForm().
 		AddFormItem(typeDropdown).
 		AddFormItem(amountField).
 		AddFormItem(categoryDropdown).
 		AddFormItem(descriptionField).
+		AddFormItem(dateField).
+		AddFormItem(tagsField).
 		AddButton("Update", func() {
 			amount := amountField.GetText()
 			description := descriptionField.GetText()
 
+			date, err := parseTransactionDate(dateField.GetText())
+			if err != nil {
+				showErrorModal(err.Error(), form)
+				log.Printf("invalid transaction date: %s", err)
+				return
+			}
+
 			var updateReq = UpdateTransactionRequest{
 				Type:        transactionType,
 				Id:          transactionId,
 				Amount:      amount,
 				Category:    tx.Category,
 				Description: description,
+				Date:        date.Format(TransactionDateFormat),
+				Tags:        tagsField.GetText(),
 			}
 
 			if err := handleUpdateTransaction(updateReq); err != nil {
@@ -114,7 +142,9 @@ func formUpdateTransaction(transactionId, transactionType, selectedMonth, select
 				return
 			}
 
-			gridVisualizeTransactions(selectedMonth, selectedYear, transactionType, true) // go back to the list of transactions (at the same month and year from where formDeleteTransaction was triggered)
+			// go back to the list of transactions of the month the transaction is in now
+			month, year := periodOfDate(date)
+			gridVisualizeTransactions(month, year, transactionType, true)
 
 		}).
 		AddButton("Clear", func() {
@@ -122,6 +152,8 @@ func formUpdateTransaction(transactionId, transactionType, selectedMonth, select
 			amountField.SetText("")
 			categoryDropdown.SetCurrentOption(0)
 			descriptionField.SetText("")
+			dateField.SetText(tx.Date.Format(TransactionDateFormat))
+			tagsField.SetText(formatTags(tx.Tags))
 		}).
 		AddButton("Cancel", func() {
 			gridVisualizeTransactions(selectedMonth, selectedYear, transactionType, true) // go back to list of transactions
@@ -144,7 +176,7 @@ func formUpdateTransaction(transactionId, transactionType, selectedMonth, select
 	centeredModal = styleFlex(tview.NewFlex().
 		SetDirection(tview.FlexRow).
 		AddItem(nil, 0, 1, false).
-		AddItem(modal, 17, 1, true). // enough to fit all the fields of the form on the screen
+		AddItem(modal, 21, 1, true). // enough to fit all the fields of the form on the screen
 		AddItem(nil, 0, 1, false))
 
 	pages.AddPage("update-transaction", centeredModal, true, true)
@@ -169,40 +201,41 @@ func handleUpdateTransaction(req UpdateTransactionRequest) error {
 		return fmt.Errorf("\ninvalid amount: %w\n", err)
 	}
 
-	if _, ok := allowedTransactionCategories[txType][req.Category]; !ok {
-		return fmt.Errorf("\n\ninvalid transaction category: %s", req.Category)
+	var updatedDate time.Time
+	if req.Date != "" {
+		if updatedDate, err = parseTransactionDate(req.Date); err != nil {
+			return err
+		}
 	}
 
-	transactions, loadFileErr := LoadTransactions()
-	if loadFileErr != nil {
-		return fmt.Errorf("unable to load transactions file: %w", loadFileErr)
+	tx, err := GetTransaction(txType, req.Id)
+	if err != nil {
+		if errors.Is(err, ErrTransactionNotFound) {
+			return fmt.Errorf("transaction with id %s not found", req.Id)
+		}
+		return fmt.Errorf("unable to load transaction: %w", err)
 	}
 
-	// years
-	var transactionFound bool
-	for year, months := range transactions {
-
-		// months
-		for month := range months {
-
-			for i, tx := range transactions[year][month][txType] {
-				if tx.Id == req.Id {
-					tx.Amount = updatedAmount
-					tx.Description = req.Description
-					tx.Category = req.Category
+	// a transaction keeps its archived category, it just can't be moved into one
+	if req.Category != tx.Category && !isAllowedCategory(txType, req.Category) {
+		return fmt.Errorf("\n\ninvalid transaction category: %s", req.Category)
+	}
 
-					transactions[year][month][txType][i] = tx
-					transactionFound = true
-				}
-			}
-		}
+	updatedTags, err := parseTags(req.Tags)
+	if err != nil {
+		return fmt.Errorf("invalid transaction tags: %w", err)
 	}
 
-	if !transactionFound {
-		return fmt.Errorf("transaction with id %s not found", req.Id)
+	tx.Amount = updatedAmount
+	tx.Description = req.Description
+	tx.Category = req.Category
+	tx.Tags = updatedTags
+	// a date in another month moves the transaction over to that month
+	if !updatedDate.IsZero() {
+		tx.Date = updatedDate
 	}
 
-	if saveTransactionErr := SaveTransactions(transactions); saveTransactionErr != nil {
+	if saveTransactionErr := UpdateTransaction(txType, tx); saveTransactionErr != nil {
 		return fmt.Errorf("error saving transaction: %w", saveTransactionErr)
 	}
 
@@ -174,3 +174,50 @@ func TestHandleUpdateTransaction(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleUpdateTransactionDate(t *testing.T) {
+	setupTestStorage(t, StorageSQLite)
+
+	testTransactions := TransactionHistory{
+		"2024": {
+			"march": {
+				"expense": {
+					{Id: "12345678", Amount: 50.00, Category: "food", Description: "groceries", Date: time.Date(2024, time.March, 3, 0, 0, 0, 0, time.UTC)},
+					{Id: "87654321", Amount: 20.00, Category: "food", Description: "coffee", Date: time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)},
+				},
+			},
+		},
+	}
+	if err := saveTransactionsToTestStorage(testTransactions); err != nil {
+		t.Fatalf("Failed to initialize test storage: %v", err)
+	}
+
+	// a date within the same month only changes the date
+	req := UpdateTransactionRequest{Type: "expense", Id: "12345678", Amount: "50", Category: "food", Description: "groceries", Date: "2024-03-20"}
+	if err := handleUpdateTransaction(req); err != nil {
+		t.Fatalf("Expected no error updating date, got %v", err)
+	}
+	transactions, _ := loadTransactionsFromTestStorage()
+	tx, err := getTransactionById("12345678")
+	if err != nil || tx.Date.Day() != 20 || len(transactions["2024"]["march"]["expense"]) != 2 {
+		t.Errorf("Expected date to change to the 20th, got %+v (err %v)", tx, err)
+	}
+
+	// a date in another month moves the transaction over
+	req.Date = "2024-04-02"
+	if err := handleUpdateTransaction(req); err != nil {
+		t.Fatalf("Expected no error moving transaction, got %v", err)
+	}
+	transactions, _ = loadTransactionsFromTestStorage()
+	if len(transactions["2024"]["march"]["expense"]) != 1 || len(transactions["2024"]["april"]["expense"]) != 1 {
+		t.Errorf("Expected transaction to move from march to april, got %+v", transactions)
+	}
+	if moved := transactions["2024"]["april"]["expense"][0]; moved.Id != "12345678" || moved.Date.Format(TransactionDateFormat) != "2024-04-02" {
+		t.Errorf("Unexpected moved transaction %+v", moved)
+	}
+
+	req.Date = "2024-13-01"
+	if err := handleUpdateTransaction(req); err == nil {
+		t.Errorf("Expected error for invalid date")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 )
 
@@ -57,14 +58,18 @@ func capitalize(word string) string {
 	return string(runes)
 }
 
-// helper to provide a list of allowed transaction categories
+// helper to provide a sorted list of the categories new transactions can use, archived categories are left out
 func listOfAllowedCategories(transactionType string) (categories []string, err error) {
-	for c := range allowedTransactionCategories[transactionType] {
-		categories = append(categories, c)
+	all, err := listCategories(transactionType, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range all {
+		categories = append(categories, c.Name)
 	}
 
 	if len(categories) <= 0 {
-		return categories, fmt.Errorf("something went wrong with getting list of allowed categories for transaction type %s", transactionType)
+		return categories, fmt.Errorf("no categories to choose from for transaction type %s, add one or unarchive one in the settings", transactionType)
 	}
 
 	return categories, nil
@@ -151,6 +156,56 @@ func getTransactionById(id string) (*Transaction, error) {
 	return nil, fmt.Errorf("transaction with ID %s not found", id)
 }
 
+// helper to get the first day of a month, used for transactions that were stored before they had a date
+func firstOfMonth(month, year string) (time.Time, error) {
+	m, ok := monthOrder[strings.ToLower(month)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid month %s", month)
+	}
+	y, err := strconv.Atoi(year)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid year %s: %w", year, err)
+	}
+	return time.Date(y, time.Month(m), 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+// helper to pick the date a new transaction is prefilled with - today when adding to the current month, otherwise the first of the month
+func defaultTransactionDate(month, year string) time.Time {
+	now := time.Now()
+	if strings.EqualFold(month, now.Month().String()) && year == strconv.Itoa(now.Year()) {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	date, err := firstOfMonth(month, year)
+	if err != nil {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return date
+}
+
+// helper to parse a transaction date entered in a form or on the command line
+func parseTransactionDate(raw string) (time.Time, error) {
+	date, err := time.Parse(TransactionDateFormat, strings.TrimSpace(raw))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected format YYYY-MM-DD", raw)
+	}
+	return date, nil
+}
+
+// helper to get the month and year a date belongs to as used in the transaction history, e.g. "march", "2024"
+func periodOfDate(date time.Time) (month, year string) {
+	return strings.ToLower(date.Month().String()), strconv.Itoa(date.Year())
+}
+
+// helper to sort transactions chronologically, transactions on the same day keep their order
+func sortTransactionsByDate(transactions []Transaction) []Transaction {
+	sorted := make([]Transaction, len(transactions))
+	copy(sorted, transactions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+	return sorted
+}
+
 // helper to enforce the character limit of the description field
 func enforceCharLimit(textToCheck string, lastChar rune) bool {
 	return len(textToCheck) <= DescriptionMaxCharLength
@@ -3,6 +3,7 @@ package main
 import (
 	"slices"
 	"testing"
+	"time"
 )
 
 func TestNormalizeTransactionType(t *testing.T) {
@@ -412,3 +413,36 @@ func TestDetermineLatestMonthAndYear(t *testing.T) {
 		})
 	}
 }
+
+func TestSortTransactionsByDate(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC) }
+	unsorted := []Transaction{
+		{Id: "c", Date: day(20)},
+		{Id: "a", Date: day(2)},
+		{Id: "d", Date: day(20)},
+		{Id: "b", Date: day(5)},
+	}
+
+	sorted := sortTransactionsByDate(unsorted)
+	var ids string
+	for _, tx := range sorted {
+		ids += tx.Id
+	}
+	if ids != "abcd" {
+		t.Errorf("Expected chronological order keeping same day order, got %s", ids)
+	}
+	if unsorted[0].Id != "c" {
+		t.Errorf("Expected input slice to be left untouched")
+	}
+}
+
+func TestDefaultTransactionDate(t *testing.T) {
+	now := time.Now()
+	month, year := periodOfDate(now)
+	if got := defaultTransactionDate(month, year); got.Day() != now.Day() || got.Month() != now.Month() {
+		t.Errorf("Expected today for the current month, got %s", got)
+	}
+	if got := defaultTransactionDate("february", "2020").Format(TransactionDateFormat); got != "2020-02-01" {
+		t.Errorf("Expected first of the month for a past month, got %s", got)
+	}
+}
@@ -180,7 +180,8 @@ func gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType strin
 		}
 	}
 
-	transactions, err := LoadTransactions()
+	// only the displayed month is needed for the tables
+	transactions, err := LoadTransactionsForPeriod(displayYear, displayMonth, "")
 	if err != nil {
 		return nil, fmt.Errorf("unable to load transactions file: %w", err)
 	}
@@ -189,6 +190,9 @@ func gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType strin
 	if displayMonth != "" && displayYear != "" {
 		headerText = fmt.Sprintf("%s %s", capitalize(displayMonth), displayYear)
 	}
+	if readOnlySession {
+		headerText += " (read-only)"
+	}
 
 	var calculatedPnl PnLResult
 	var footerText string
@@ -233,16 +237,29 @@ func gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType strin
 		AddItem(helpCenterFooter, 0, 1, 1, 1, 0, 0, false).
 		AddItem(helpRightFooter, 0, 2, 1, 1, 0, 0, false))
 
+	// spent vs limit of each budget between the tables and the totals, only shown once there are budgets
+	budgetPanel, budgetPanelHeight, err := createBudgetPanel(displayMonth, displayYear)
+	if err != nil {
+		return nil, fmt.Errorf("unable to calculate budgets: %w", err)
+	}
+
 	grid := styleGrid(tview.NewGrid().
-		SetRows(3, 0, 3, 2).
 		SetColumns(0, 0, 0).
 		SetBorders(true).
 		AddItem(header, 0, 0, 1, 3, 0, 0, false).
 		AddItem(incomeTable, 1, 0, 1, 1, 0, 0, false).
 		AddItem(expenseTable, 1, 1, 1, 1, 0, 0, false).
-		AddItem(investmentTable, 1, 2, 1, 1, 0, 0, false).
-		AddItem(pnlFooter, 2, 0, 1, 3, 0, 0, false).
-		AddItem(footerGrid, 3, 0, 1, 3, 0, 0, false))
+		AddItem(investmentTable, 1, 2, 1, 1, 0, 0, false))
+	if budgetPanel != nil {
+		grid.SetRows(3, 0, budgetPanelHeight, 3, 2).
+			AddItem(budgetPanel, 2, 0, 1, 3, 0, 0, false).
+			AddItem(pnlFooter, 3, 0, 1, 3, 0, 0, false).
+			AddItem(footerGrid, 4, 0, 1, 3, 0, 0, false)
+	} else {
+		grid.SetRows(3, 0, 3, 2).
+			AddItem(pnlFooter, 2, 0, 1, 3, 0, 0, false).
+			AddItem(footerGrid, 3, 0, 1, 3, 0, 0, false)
+	}
 	grid.SetBorder(false).SetTitle("Expense Tracking Tool").SetTitleAlign(tview.AlignCenter)
 
 	// keep a list of tables for focus switching in the TUI
@@ -266,6 +283,7 @@ func gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType strin
 	}
 	pages.AddPage(pageName, grid, true, true)
 	tui.SetFocus(tables[currentTable])
+	rememberView(displayMonth, displayYear, currentTable)
 
 	// handle input capture for navigation,
 	grid.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -283,10 +301,12 @@ func gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType strin
 		case tcell.KeyTAB, tcell.KeyRight:
 			currentTable = (currentTable + 1) % len(tables) // % len(tables) wraps back to 0 when we reach the end of list of tables to prevenet out of bounds errors
 			tui.SetFocus(tables[currentTable])
+			rememberView(displayMonth, displayYear, currentTable)
 			return nil
 		case tcell.KeyBacktab, tcell.KeyLeft: // Shift + Tab
 			currentTable = (currentTable - 1 + len(tables)) % len(tables) // add +len(tables to prevent out of bounds when on first index and trying pressing to go back, if we don't do this we get index -1, when we do this we get 0-1+len(tables) which takes us to the last elemet of the table list instead of to -1
 			tui.SetFocus(tables[currentTable])
+			rememberView(displayMonth, displayYear, currentTable)
 			return nil
 		}
 
@@ -370,6 +390,75 @@ func gridVisualizeTransactions(selectedMonth, selectedYear, focusTableType strin
 			return nil // key event consumed
 		}
 
+		if event.Key() == tcell.KeyRune && event.Rune() == 'p' {
+			currentTableType := ""
+			switch currentTable {
+			case 0:
+				currentTableType = "income"
+			case 1:
+				currentTableType = "expense"
+			case 2:
+				currentTableType = "investment"
+			}
+			if err := formChangePassword(displayMonth, displayYear, currentTableType); err != nil {
+				showErrorModal(fmt.Sprintf("change password error:\n\n%s", err), grid)
+				return nil
+			}
+			return nil // key event consumed
+		}
+
+		if event.Key() == tcell.KeyRune && event.Rune() == 'b' {
+			currentTableType := ""
+			switch currentTable {
+			case 0:
+				currentTableType = "income"
+			case 1:
+				currentTableType = "expense"
+			case 2:
+				currentTableType = "investment"
+			}
+			if err := showBudgets(displayMonth, displayYear, currentTableType); err != nil {
+				showErrorModal(fmt.Sprintf("budgets error:\n\n%s", err), grid)
+				return nil
+			}
+			return nil // key event consumed
+		}
+
+		// totals of the month per category with a drill-down into the subcategories
+		if event.Key() == tcell.KeyRune && event.Rune() == 'c' {
+			if err := showCategoryTotals(displayMonth, displayYear, func() {
+				pages.SwitchToPage(pageName)
+				tui.SetFocus(tables[currentTable])
+			}); err != nil {
+				showErrorModal(fmt.Sprintf("error showing category totals:\n\n%s", err), grid)
+				return nil
+			}
+			return nil // key event consumed
+		}
+
+		// lock the session right away instead of waiting for the idle timeout
+		if event.Key() == tcell.KeyRune && event.Rune() == 'L' {
+			lockSession()
+			return nil // key event consumed
+		}
+
+		if event.Key() == tcell.KeyRune && event.Rune() == 's' {
+			currentTableType := ""
+			switch currentTable {
+			case 0:
+				currentTableType = "income"
+			case 1:
+				currentTableType = "expense"
+			case 2:
+				currentTableType = "investment"
+			}
+			if err := showSettings(displayMonth, displayYear, currentTableType); err != nil {
+				showErrorModal(fmt.Sprintf("settings error:\n\n%s", err), grid)
+				return nil
+			}
+			return nil // key event consumed
+		}
+
 		// enter search mode
 		if event.Key() == tcell.KeyRune && event.Rune() == '/' {
 			var currentSearch string
@@ -98,6 +98,11 @@ func showYearResults(year string) error {
 		return fmt.Errorf("unable to calculate year pnl: %w", err)
 	}
 
+	categoryTotals, err := calculateCategoryTotals("", year)
+	if err != nil {
+		return fmt.Errorf("unable to calculate category totals: %w", err)
+	}
+
 	months, err := getMonthsForYear(year)
 	if err != nil {
 		return fmt.Errorf("unable to get months for year: %w", err)
@@ -131,6 +136,12 @@ func showYearResults(year string) error {
 	leftContent.WriteString(fmt.Sprintf("  Investments: €%.2f\n", yearPnL.investmentTotal))
 	leftContent.WriteString(fmt.Sprintf("  Savings: €%.2f (%.1f%% of income)\n", yearPnL.pnlAmount, yearPnL.pnlPercent))
 
+	// subcategories are rolled up into their parent here, the drill-down is in the category totals window
+	if len(categoryTotals["expense"]) > 0 {
+		leftContent.WriteString("\nExpenses by Category:\n")
+		leftContent.WriteString(formatCategoryTotals(categoryTotals["expense"]))
+	}
+
 	leftText.SetText(leftContent.String())
 
 	// right panel: ASCII pie chart
@@ -150,7 +161,8 @@ func showYearResults(year string) error {
 
 	// frame with navigation
 	frame := tview.NewFrame(flex).
-		AddText(generateCombinedControlsFooter(), false, tview.AlignCenter, theme.FieldTextColor)
+		AddText(generateCombinedControlsFooter(), false, tview.AlignCenter, theme.FieldTextColor).
+		AddText(Yellow+"c"+Reset+": totals by category", false, tview.AlignCenter, theme.FieldTextColor)
 
 	// input capture for navigation
 	flex.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -159,6 +171,16 @@ func showYearResults(year string) error {
 			pages.SwitchToPage("yearSelector")
 			return nil
 		}
+		// drill down from the parent categories into their subcategories
+		if event.Key() == tcell.KeyRune && event.Rune() == 'c' {
+			if err := showCategoryTotals("", year, func() {
+				pages.SwitchToPage("yearResults")
+				tui.SetFocus(flex)
+			}); err != nil {
+				showErrorModal(fmt.Sprintf("error showing category totals:\n\n%s", err), flex)
+			}
+			return nil // key event consumed
+		}
 		return event
 	})
 